@@ -0,0 +1,147 @@
+/*
+Copyright © 2025 Thomas von Dein
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package calc
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// Calculate() and  its options evaluate  expressions without  any user
+// interaction, so that other programs can embed the calculator:
+//
+//	import "rpn/calc"
+//
+//	result, err := calc.Calculate("2 3 +")
+
+// configures the temporary calculator used by Calculate()
+type Option func(*Calc) error
+
+// the lua interpreter and the functions registered by the script are
+// global, so calculations must not overlap and have to leave them as
+// they were
+var calculateLock sync.Mutex
+
+func WithPrecision(precision int) Option {
+	return func(c *Calc) error {
+		if precision < 0 || precision > MaxPrecision {
+			return fmt.Errorf("invalid precision %d, use 0-%d", precision, MaxPrecision)
+		}
+
+		c.precision = precision
+
+		return nil
+	}
+}
+
+// apply operators to the whole stack, e.g. "1 2 3 +" yields 6
+func WithBatch() Option {
+	return func(c *Calc) error {
+		c.batch = true
+
+		return nil
+	}
+}
+
+// predefined variables, which can be retrieved with <NAME
+func WithVars(vars map[string]float64) Option {
+	return func(c *Calc) error {
+		for name, value := range vars {
+			c.Vars[name] = value
+		}
+
+		return nil
+	}
+}
+
+// load lua functions from script, same as the --config flag. The
+// interpreter only lives as long as the calculation.
+func WithLuaScript(script string) Option {
+	return func(c *Calc) (err error) {
+		if _, err := os.Stat(script); err != nil {
+			return fmt.Errorf("failed to load lua script: %w", err)
+		}
+
+		// InitLua() panics on invalid scripts, which is fine for the
+		// commandline tool, but not here
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("failed to load lua script %s: %v", script, r)
+			}
+		}()
+
+		LuaInterpreter = lua.NewState(lua.Options{SkipOpenLibs: true})
+
+		luarunner := NewInterpreter(script, c.debug)
+		luarunner.InitLua()
+		c.SetInt(luarunner)
+
+		return nil
+	}
+}
+
+// evaluate  an expression and return the last  item of the stack.
+// Nothing is printed, it's safe to be called concurrently.
+func Calculate(expr string, opts ...Option) (float64, error) {
+	calculateLock.Lock()
+	defer calculateLock.Unlock()
+
+	state, funcs, conversions := LuaInterpreter, LuaFuncs, LuaConversions
+
+	defer func() {
+		if LuaInterpreter != state {
+			LuaInterpreter.Close()
+		}
+
+		LuaInterpreter, LuaFuncs, LuaConversions = state, funcs, conversions
+	}()
+
+	calc := NewCalcWriter(io.Discard)
+	calc.quiet = true
+
+	for _, opt := range opts {
+		if err := opt(calc); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := calc.Eval(expr); err != nil {
+		return 0, err
+	}
+
+	if calc.stack.Len() == 0 {
+		return 0, errors.New("stack is empty")
+	}
+
+	return calc.stack.Last()[0], nil
+}
+
+// same as Calculate(), but panics on errors, useful in tests and examples
+func MustCalculate(expr string, opts ...Option) float64 {
+	result, err := Calculate(expr, opts...)
+	if err != nil {
+		panic(err)
+	}
+
+	return result
+}
@@ -0,0 +1,163 @@
+/*
+Copyright © 2025 Thomas von Dein
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package calc_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"rpn/calc"
+)
+
+func ExampleCalculate() {
+	result, err := calc.Calculate("2 3 + 4 *")
+	if err != nil {
+		fmt.Println(err)
+
+		return
+	}
+
+	fmt.Println(result)
+
+	fmt.Println(calc.MustCalculate("1 2 3 4 +", calc.WithBatch()))
+	fmt.Println(calc.MustCalculate("<X 2 ^", calc.WithVars(map[string]float64{"X": 3})))
+	// Output:
+	// 20
+	// 10
+	// 9
+}
+
+func TestCalculate(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "api.lua")
+	code := `
+function double(x)
+    return x * 2
+end
+
+function bad(x)
+    error("no way")
+end
+
+function init()
+    register("double", 1, "double")
+    register("bad", 1, "always fails")
+end
+`
+	if err := os.WriteFile(script, []byte(code), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		name string
+		expr string
+		opts []calc.Option
+		exp  float64
+		err  bool
+	}{
+		{name: "plain", expr: "10 4 -", exp: 6},
+		{name: "precision", expr: "1 3 /", opts: []calc.Option{calc.WithPrecision(6)}, exp: 1.0 / 3},
+		{name: "invalid-precision", expr: "1", opts: []calc.Option{calc.WithPrecision(16)}, err: true},
+		{name: "batch", expr: "2 3 4 max", opts: []calc.Option{calc.WithBatch()}, exp: 4},
+		{name: "vars", expr: "<A <B +",
+			opts: []calc.Option{calc.WithVars(map[string]float64{"A": 1, "B": 2})}, exp: 3},
+		{name: "lua", expr: "21 double", opts: []calc.Option{calc.WithLuaScript(script)}, exp: 42},
+		{name: "lua-failure", expr: "21 bad", opts: []calc.Option{calc.WithLuaScript(script)}, err: true},
+		{name: "lua-missing", expr: "1", opts: []calc.Option{calc.WithLuaScript(script + ".no")}, err: true},
+		{name: "empty", expr: "", err: true},
+		{name: "unknown-var", expr: "1 2 <X +", err: true},
+		{name: "store-empty", expr: ">X", err: true},
+		{name: "swap-short", expr: "1 swap 2 +", err: true},
+		{name: "dup-empty", expr: "dup 2 +", err: true},
+		{name: "invalid", expr: "1 0 /", err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := calc.Calculate(test.expr, test.opts...)
+
+			if test.err {
+				if err == nil {
+					t.Errorf("%s accepted, expected error", test.expr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != test.exp {
+				t.Errorf("calculate failed:\n+++  got: %f\n--- want: %f", got, test.exp)
+			}
+		})
+	}
+}
+
+func TestCalculateConcurrently(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "api.lua")
+	code := `
+function triple(x)
+    return x * 3
+end
+
+function init()
+    register("triple", 1, "triple")
+end
+`
+	if err := os.WriteFile(script, []byte(code), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	state, funcs := calc.LuaInterpreter, len(calc.LuaFuncs)
+
+	var wait sync.WaitGroup
+
+	for number := range 20 {
+		wait.Add(1)
+
+		go func() {
+			defer wait.Done()
+
+			expr, opts, exp := fmt.Sprintf("%d 1 +", number), []calc.Option{}, float64(number+1)
+			if number%2 == 0 {
+				expr, opts, exp = fmt.Sprintf("%d triple", number), []calc.Option{calc.WithLuaScript(script)}, float64(number*3)
+			}
+
+			got, err := calc.Calculate(expr, opts...)
+			if err != nil {
+				t.Errorf("%s failed: %s", expr, err)
+
+				return
+			}
+
+			if got != exp {
+				t.Errorf("%s failed:\n+++  got: %f\n--- want: %f", expr, got, exp)
+			}
+		}()
+	}
+
+	wait.Wait()
+
+	if calc.LuaInterpreter != state || len(calc.LuaFuncs) != funcs {
+		t.Errorf("calculate didn't restore the lua interpreter")
+	}
+}
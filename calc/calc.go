@@ -0,0 +1,2014 @@
+/*
+Copyright © 2023-2024 Thomas von Dein
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package calc
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/chzyer/readline"
+)
+
+type Calc struct {
+	debug        bool
+	batch        bool
+	stdin        bool
+	showstack    bool
+	showstacklen int // number of items shown by showstack
+	intermediate bool
+	notdone      bool // set to true as long as there are items left in the eval loop
+	usagestats   bool
+	money        bool   // round every result to cents, see Round()
+	groupdigits  bool   // print thousands separators, see FormatNumber()
+	decimalcomma bool   // print numbers european style: 1.234,56
+	notation     string // fix (default), sci or eng
+	showfull     bool   // print results with %g instead of the precision
+	teach        bool   // print operations and the stack in one line, see Teach()
+	color        bool   // colorize results, errors and the prompt
+	hints        bool   // show hints about functions while typing, see Hint()
+	hexupper     bool   // print hex digits in uppercase
+	progmode     bool   // print integer results in hex and binary as well
+	allownan     bool   // accept NaN and Inf results
+	quiet        bool   // don't print results, e.g. when stdout carries the stack
+	quietstack   bool   // don't confirm stack commands, see StackChange()
+	printfinal   bool   // print the last stack item on exit, unless already done
+	printed      bool   // set to true if the last item evaluated printed a result
+	finalonly    bool   // stdin: print the result of the last operation on exit only
+	haslast      bool   // the last item evaluated produced lastresult
+	nohistory    bool   // don't record history entries, e.g. during repeat
+	lenientmoney bool   // guess the decimal separator of amounts, see parseMoney()
+	paragraph    bool   // only print results at the end of a paragraph
+	linemode     bool   // every input line is a paragraph of its own, see EndLine()
+	droppartial  bool   // discard the last window if it isn't full, see EndWindow()
+	windowing    bool   // the operation of a window is being evaluated
+	dirty        bool   // something has been evaluated in the current paragraph
+	failed       bool   // an error occurred in the current paragraph
+	loadnext     bool   // the next line is a json stack, see CommandJSONLoad()
+	persist      bool   // restore the session on startup and save it on exit
+	quit         bool   // exit has been called, end the session after the current line
+	paragraphop  string
+	summary      string // output format of --summary, empty if disabled
+	every        int    // window size, every N numbers are a paragraph, see EndWindow()
+	window       int    // numbers pushed in the current window
+	precision    int
+	historylimit int
+	locale       string // en (default) or de, see delocalize()
+	prompt       string // template, see Prompt()
+	promptfailed bool   // the lua prompt function failed, see LuaPrompt()
+	byteunits    string // iec (default) or si, see formatBytes()
+	relaxedcase  string // off, constants (default) or all, see RelaxedCase()
+	tolerance    float64
+	lastresult   float64 // printed on exit, see PrintLastResult()
+	maxiter      int
+	maxexpand    int            // items evaluated by nested expansions per line, see CountExpansion()
+	expansions   int            // items evaluated by nested expansions for the current line
+	expanding    []string       // the chain of repeats currently being evaluated
+	out          io.Writer      // results are printed here, os.Stdout by default
+	errout       io.Writer      // errors are printed here, os.Stdout by default
+	clipboard    Clipboard      // used by copy, see SystemClipboard
+	tee          io.WriteCloser // results are recorded here, see Tee()
+	line         string         // the input line being evaluated
+	origin       string         // file:line: of the input line in file mode, see EvalFile()
+
+	stack        *Stack
+	history      []HistoryEntry
+	notes        []Note
+	lastop       *HistoryEntry // the last math operation
+	lastx        Numbers       // its operands, see SetLastX()
+	completer    readline.AutoCompleter
+	interpreter  *Interpreter
+	Space        *regexp.Regexp
+	Comment      *regexp.Regexp
+	Register     *regexp.Regexp
+	Numeric      *regexp.Regexp
+	Hex          *regexp.Regexp
+	Constants    []string
+	LuaFunctions []string
+
+	Funcalls      Funcalls
+	BatchFuncalls Funcalls
+
+	// different kinds of commands, displays nicer in help output
+	StackCommands    Commands
+	SettingsCommands Commands
+	ShowCommands     Commands
+	Commands         Commands
+
+	// items left on  the current input line, commands with arguments
+	// consume them
+	pending []string
+
+	Vars  map[string]float64
+	Usage map[string]int
+}
+
+// the history contains math operations, stack manipulations and notes
+const (
+	HistoryMath  string = "math"
+	HistoryStack string = "stack"
+	HistoryNote  string = "note"
+)
+
+// A note  taken during the  session, see the note  command. Notes are
+// never evaluated.
+type Note struct {
+	Time time.Time `json:"time"`
+	Text string    `json:"text"`
+}
+
+func (note Note) String() string {
+	return note.Time.Format(time.DateTime) + " " + note.Text
+}
+
+// Math entries keep  their exact result, so that recalling it doesn't
+// introduce rounding errors. It is only formatted when being viewed.
+type HistoryEntry struct {
+	Kind      string  `json:"kind"`
+	Text      string  `json:"text"` // the operation with its operands, without the result
+	Result    float64 `json:"result"`
+	HasResult bool    `json:"hasresult"`
+}
+
+// format the entry, the result is formatted using the given function
+func (entry HistoryEntry) Format(format func(float64) string) string {
+	if !entry.HasResult {
+		return entry.Text
+	}
+
+	return entry.Text + " -> " + format(entry.Result)
+}
+
+// the view format of the history command
+func (entry HistoryEntry) String() string {
+	return entry.Format(func(result float64) string {
+		return fmt.Sprintf("%f", result)
+	})
+}
+
+// help for lua functions will be added dynamically
+const Help string = `
+Operators:
+basic operators: + - x * / ^  (* is an alias of x)
+
+Bitwise operators: and or xor < (left shift) > (right shift)
+extract (value offset length) deposit (value field offset length)
+bitsof (IEEE-754 bit pattern of a double)
+
+Percent functions:
+%                    percent
+%-                   subtract percent
+%+                   add percent
+
+Math functions (see https://pkg.go.dev/math):
+mod sqrt abs acos acosh asin asinh atan atan2 atanh cbrt ceil cos cosh
+erf erfc  erfcinv erfinv exp  exp2 expm1 floor  gamma ilogb j0  j1 log
+log10 log1p log2 logb pow round roundtoeven sin sinh tan tanh trunc y0
+y1 copysign dim hypot ncr npr multichoose ceildiv floordiv (alias: //)
+roundmult factorial (alias: !)
+
+Time functions:
+now                  current time as unix timestamp
+
+Constants (case insensitive):
+E Pi Phi Sqrt2 SqrtE SqrtPi SqrtPhi Ln2 Log2E Ln10 Log10E
+
+Batch functions:
+sum                  sum of all values (alias: +)
+max                  max of all values
+min                  min of all values
+mean                 mean of all values (alias: avg)
+median               median of all values
+madev                median absolute deviation of all values
+Append ! to keep the values on the stack, e.g. sum! (works without batch mode)
+
+Register variables:
+>NAME                Put last stack element into variable NAME
+>>NAME               Move last stack element into variable NAME
+<NAME                Retrieve variable NAME and put onto stack`
+
+// commands, constants and operators,  defined here to feed completion
+// and our mode switch in Eval() dynamically
+const (
+	Constants      string = `E Pi Phi Sqrt2 SqrtE SqrtPi SqrtPhi Ln2 Log2E Ln10 Log10E`
+	Precision      int    = 2
+	MaxPrecision   int    = 15
+	MaxHexWidth    int    = 16 // hex digits of a 64 bit number
+	WordSize       int    = 64 // bits of the words used by extract and deposit
+	MaxDenominator int    = 10000
+	ShowStackLen   int    = 5     // default number of items shown by showstack
+	HistoryLimit   int    = 10000 // max number of history entries kept
+	HistorySample  int    = 3     // operands shown at each end of long entries
+	MoneyPrecision int    = 2
+	MaxRepeat      int    = 1000000
+	MaxExpansions  int    = 10000 // items evaluated by nested expansions per input line
+)
+
+// supported number formats, en: 1,234.56 and de: 1.234,56
+const (
+	LocaleEN string = "en"
+	LocaleDE string = "de"
+)
+
+// registers containing the operands of the last math operation
+const (
+	LastX string = "LASTX"
+	LastY string = "LASTY"
+)
+
+// appended to a batch function it keeps the operands, e.g. sum!
+const KeepSuffix string = "!"
+
+// A kind of token, see TokenKinds
+type TokenKind struct {
+	Name  string
+	Match func(c *Calc, item string) bool
+	Eval  func(c *Calc, item string) error
+}
+
+// The kinds of tokens in the order they are tried by EvalItem(), the
+// first one matching an item determines its meaning. So lua functions
+// shadow builtin functions and commands,  but not numbers.  Changing
+// the order changes the meaning of existing input, so don't, unless
+// you really have to, see TestTokenPrecedence.
+var TokenKinds = []TokenKind{
+	{
+		Name:  "number",
+		Match: (*Calc).IsLiteral,
+		Eval: func(c *Calc, item string) error {
+			_, err := c.EvalLiteral(item)
+
+			return err
+		},
+	},
+	{
+		Name: "lua function",
+		Match: func(c *Calc, item string) bool {
+			return contains(c.LuaFunctions, item)
+		},
+		Eval: (*Calc).EvalLuaFunction,
+	},
+	{
+		Name: "constant",
+		Match: func(c *Calc, item string) bool {
+			_, ok := c.FindConstant(item)
+
+			return ok
+		},
+		Eval: func(c *Calc, item string) error {
+			constant, _ := c.FindConstant(item)
+			if constant != item {
+				c.Debug(fmt.Sprintf("%s interpreted as %s", item, constant))
+			}
+
+			c.stack.Backup()
+			c.stack.Push(const2num(constant))
+
+			return nil
+		},
+	},
+	{
+		Name: "function",
+		Match: func(c *Calc, item string) bool {
+			return exists(c.Funcalls, item)
+		},
+		Eval: (*Calc).EvalFuncall,
+	},
+	{
+		Name: "batch function",
+		Match: func(c *Calc, item string) bool {
+			return exists(c.BatchFuncalls, item)
+		},
+		Eval: (*Calc).EvalFuncall,
+	},
+	{
+		Name: "keep function",
+		Match: func(c *Calc, item string) bool {
+			name, ok := strings.CutSuffix(item, KeepSuffix)
+
+			return ok && exists(c.BatchFuncalls, name)
+		},
+		Eval: (*Calc).EvalFuncall,
+	},
+	{
+		Name: "register",
+		Match: func(c *Calc, item string) bool {
+			return c.Register.MatchString(item)
+		},
+		Eval: (*Calc).EvalRegister,
+	},
+	commandKind("command", func(c *Calc) Commands { return c.Commands }),
+	commandKind("show command", func(c *Calc) Commands { return c.ShowCommands }),
+	{
+		Name: "stack command",
+		Match: func(c *Calc, item string) bool {
+			return exists(c.StackCommands, item)
+		},
+		Eval: (*Calc).EvalStackCommand,
+	},
+	commandKind("setting", func(c *Calc) Commands { return c.SettingsCommands }),
+	{
+		Name: "help",
+		Match: func(_ *Calc, item string) bool {
+			return item == "?" || item == "help"
+		},
+		Eval: func(c *Calc, _ string) error {
+			c.PrintHelp()
+
+			return nil
+		},
+	},
+}
+
+// all kinds of internal commands work the same way
+func commandKind(name string, commands func(c *Calc) Commands) TokenKind {
+	return TokenKind{
+		Name: name,
+		Match: func(c *Calc, item string) bool {
+			return exists(commands(c), item)
+		},
+		Eval: func(c *Calc, item string) error {
+			return c.RunCommand(item, commands(c)[item])
+		},
+	}
+}
+
+// run a stack command and confirm what it changed
+func (c *Calc) EvalStackCommand(item string) error {
+	before := c.stack.All()
+
+	if err := c.RunCommand(item, c.StackCommands[item]); err != nil {
+		return err
+	}
+
+	c.StackChange(item, before)
+
+	return nil
+}
+
+// print a  one line confirmation  of a stack command,  showing the
+// region of the stack it changed, e.g. swap: ... 5 3 -> ... 3 5. Only
+// in interactive mode and if the stack isn't displayed anyway.
+func (c *Calc) StackChange(name string, before []float64) {
+	if c.quiet || c.quietstack || c.stdin || c.showstack || c.teach {
+		return
+	}
+
+	if change, ok := ChangedRegion(before, c.stack.All(), c.FormatItem); ok {
+		fmt.Fprintf(c.out, "%s: %s\n", name, change)
+	}
+}
+
+// number display modes: fixed point, scientific and engineering notation
+const (
+	NotationFix string = "fix"
+	NotationSci string = "sci"
+	NotationEng string = "eng"
+)
+
+// units of the human command: powers of 1024 (KiB) or 1000 (kB)
+const (
+	ByteUnitsIEC string = "iec"
+	ByteUnitsSI  string = "si"
+)
+
+// which failed lookups are retried case insensitively, see RelaxedCase()
+const (
+	RelaxedCaseOff       string = "off"
+	RelaxedCaseConstants string = "constants"
+	RelaxedCaseAll       string = "all"
+)
+
+// currency symbols are ignored, so amounts can be pasted from invoices
+var Currencies = []string{"$", "€", "£"}
+
+var ColorSequence = regexp.MustCompile("\033\\[[0-9;]*m")
+
+// That way we can add custom functions to completion
+func GetCompleteCustomFunctions() func(string) []string {
+	return func(line string) []string {
+		completions := []string{}
+
+		for luafunc := range LuaFuncs {
+			completions = append(completions, luafunc)
+		}
+
+		// constants are case insensitive, offer both spellings
+		for _, constant := range strings.Split(Constants, " ") {
+			completions = append(completions, constant, strings.ToLower(constant))
+		}
+
+		return completions
+	}
+}
+
+func (c *Calc) GetCompleteCustomFuncalls() func(string) []string {
+	return func(line string) []string {
+		completions := []string{}
+
+		for function := range c.Funcalls {
+			completions = append(completions, function)
+		}
+
+		for function := range c.BatchFuncalls {
+			completions = append(completions, function)
+		}
+
+		for command := range c.SettingsCommands {
+			if len(command) > 1 {
+				completions = append(completions, command)
+			}
+		}
+
+		for command := range c.ShowCommands {
+			if len(command) > 1 {
+				completions = append(completions, command)
+			}
+		}
+
+		for command := range c.StackCommands {
+			if len(command) > 1 {
+				completions = append(completions, command)
+			}
+		}
+
+		for command := range c.Commands {
+			if len(command) > 1 {
+				completions = append(completions, command)
+			}
+		}
+
+		return completions
+	}
+}
+
+func NewCalc() *Calc {
+	calc := Calc{stack: NewStack(), debug: false, precision: Precision,
+		historylimit: HistoryLimit, locale: LocaleEN, notation: NotationFix,
+		byteunits: ByteUnitsIEC, relaxedcase: RelaxedCaseConstants, showstacklen: ShowStackLen, maxexpand: MaxExpansions,
+		tolerance: Tolerance, maxiter: MaxIterations, out: os.Stdout, errout: os.Stdout,
+		clipboard: SystemClipboard{}}
+
+	calc.Funcalls = DefineFunctions()
+	calc.BatchFuncalls = DefineBatchFunctions()
+	calc.Vars = map[string]float64{}
+	calc.Usage = map[string]int{}
+
+	calc.completer = readline.NewPrefixCompleter(
+		// custom lua functions
+		readline.PcItemDynamic(GetCompleteCustomFunctions()),
+		readline.PcItemDynamic(calc.GetCompleteCustomFuncalls()),
+	)
+
+	calc.Space = regexp.MustCompile(`\s+`)
+	calc.Comment = regexp.MustCompile(`#.*`) // ignore everything after #
+	calc.Register = regexp.MustCompile(`^(>>|[<>])([A-Z][A-Z0-9]*)`)
+	calc.Numeric = regexp.MustCompile(`^[-+]?\.?[0-9]`) // candidates for digit separators
+	calc.Hex = regexp.MustCompile(`^-?0[xX]`)
+
+	// pre-calculate mode switching arrays
+	calc.Constants = strings.Split(Constants, " ")
+
+	calc.SetCommands()
+
+	return &calc
+}
+
+// a calculator printing everything to out instead of stdout, e.g. to
+// embed it or to capture the output in tests
+func NewCalcWriter(out io.Writer) *Calc {
+	calc := NewCalc()
+	calc.out = out
+	calc.errout = out
+	calc.stack.out = out
+
+	return calc
+}
+
+// setup the interpreter, called from Main(), import lua functions
+func (c *Calc) SetInt(interpreter *Interpreter) {
+	c.interpreter = interpreter
+	c.interpreter.out = c.out
+
+	for name := range LuaFuncs {
+		c.LuaFunctions = append(c.LuaFunctions, name)
+	}
+
+	// conversions are regular functions, lua isn't involved anymore
+	for _, conversion := range LuaConversions {
+		for name, function := range conversion.Funcalls() {
+			c.Funcalls[name] = function
+		}
+	}
+
+	// persist = true in the config is the same as --persist
+	if interpreter.PersistSetting() {
+		c.persist = true
+	}
+
+	if template, ok := interpreter.PromptTemplate(); ok {
+		if err := c.CheckPrompt(template); err != nil {
+			fmt.Fprintf(c.out, "ignoring prompt from config: %s\n", err)
+		} else {
+			c.prompt = template
+		}
+	}
+}
+
+func (c *Calc) ToggleDebug() {
+	c.debug = !c.debug
+	c.stack.ToggleDebug()
+	fmt.Fprintf(c.out, "debugging set to %t\n", c.debug)
+}
+
+func (c *Calc) ToggleBatch() {
+	c.batch = !c.batch
+	fmt.Fprintf(c.out, "batchmode set to %t\n", c.batch)
+}
+
+func (c *Calc) ToggleStdin() {
+	c.stdin = !c.stdin
+}
+
+func (c *Calc) ToggleUsageStats() {
+	c.usagestats = !c.usagestats
+	fmt.Fprintf(c.out, "usage statistics set to %t\n", c.usagestats)
+}
+
+func (c *Calc) ToggleMoney() {
+	c.money = !c.money
+	fmt.Fprintf(c.out, "money mode set to %t\n", c.money)
+}
+
+func (c *Calc) ToggleAllowNaN() {
+	c.allownan = !c.allownan
+	fmt.Fprintf(c.out, "allow NaN and Inf set to %t\n", c.allownan)
+}
+
+func (c *Calc) ToggleGroupDigits() {
+	c.groupdigits = !c.groupdigits
+	fmt.Fprintf(c.out, "group digits set to %t\n", c.groupdigits)
+}
+
+func (c *Calc) ToggleDecimalComma() {
+	c.decimalcomma = !c.decimalcomma
+	fmt.Fprintf(c.out, "decimal comma set to %t\n", c.decimalcomma)
+}
+
+func (c *Calc) ToggleShowFull() {
+	c.showfull = !c.showfull
+	fmt.Fprintf(c.out, "show full precision set to %t\n", c.showfull)
+}
+
+func (c *Calc) ToggleProgmode() {
+	c.progmode = !c.progmode
+	fmt.Fprintf(c.out, "programmer mode set to %t\n", c.progmode)
+}
+
+func (c *Calc) ToggleLinemode() {
+	c.linemode = !c.linemode
+	fmt.Fprintf(c.out, "line mode set to %t\n", c.linemode)
+}
+
+func (c *Calc) ToggleLenientMoney() {
+	c.lenientmoney = !c.lenientmoney
+	fmt.Fprintf(c.out, "lenient money input set to %t\n", c.lenientmoney)
+}
+
+func (c *Calc) ToggleHints() {
+	c.hints = !c.hints
+	fmt.Fprintf(c.out, "hints set to %t\n", c.hints)
+}
+
+func (c *Calc) ToggleColor() {
+	c.color = !c.color
+	fmt.Fprintf(c.out, "color set to %t\n", c.color)
+}
+
+func (c *Calc) ToggleTeach() {
+	c.teach = !c.teach
+	fmt.Fprintf(c.out, "teach mode set to %t\n", c.teach)
+}
+
+func (c *Calc) ToggleHexUpper() {
+	c.hexupper = !c.hexupper
+	fmt.Fprintf(c.out, "uppercase hex set to %t\n", c.hexupper)
+}
+
+func (c *Calc) SetNotation(notation string) {
+	c.notation = notation
+	fmt.Fprintf(c.out, "notation set to %s\n", c.notation)
+}
+
+func (c *Calc) ToggleShow() {
+	c.showstack = !c.showstack
+}
+
+// the template of the prompt, the default one if none has been set
+func (c *Calc) PromptTemplate() string {
+	if c.prompt == "" {
+		return DefaultPrompt
+	}
+
+	return c.prompt
+}
+
+// The  prompt is rendered from  a template, where  placeholders like
+// {stack} are replaced by their current values, see PromptValues().
+const DefaultPrompt string = "rpn{modes} [{stack}{checkpoint}{revision}]{arrow} "
+
+var PromptPlaceholder = regexp.MustCompile(`\{([a-z]+)\}`)
+
+// the values of the placeholders of the prompt template
+func (c *Calc) PromptValues() map[string]string {
+	values := map[string]string{
+		"stack":      strconv.Itoa(c.stack.Len()),
+		"top":        "",
+		"rev":        strconv.Itoa(c.stack.rev),
+		"revision":   "",
+		"checkpoint": "",
+		"modes":      "",
+		"batch":      "",
+		"money":      "",
+		"debug":      "",
+		"prog":       "",
+		"arrow":      c.Colorize(ColorError, "»"),
+	}
+
+	if c.stack.Len() > 0 {
+		values["top"] = c.FormatItem(c.stack.Last()[0])
+	}
+
+	for _, mode := range []struct {
+		name    string
+		enabled bool
+	}{{"batch", c.batch}, {"money", c.money}, {"debug", c.debug}, {"prog", c.progmode}} {
+		if mode.enabled {
+			values[mode.name] = mode.name
+			values["modes"] += "->" + mode.name
+		}
+	}
+
+	if c.debug {
+		values["revision"] = "/rev" + values["rev"]
+	}
+
+	if c.stack.HasCheckpoint() {
+		values["checkpoint"] = "*"
+	}
+
+	return values
+}
+
+// a template is valid if it only contains known placeholders and no
+// control characters
+func (c *Calc) CheckPrompt(template string) error {
+	values := c.PromptValues()
+
+	for _, match := range PromptPlaceholder.FindAllStringSubmatch(template, -1) {
+		if !exists(values, match[1]) {
+			return fmt.Errorf("unknown placeholder %s in prompt", match[0])
+		}
+	}
+
+	if strings.ContainsAny(PromptPlaceholder.ReplaceAllString(template, ""), "{}") {
+		return errors.New("unbalanced braces in prompt")
+	}
+
+	if strings.ContainsFunc(template, unicode.IsControl) {
+		return errors.New("prompt must not contain control characters")
+	}
+
+	return nil
+}
+
+// The prompt must only contain SGR color sequences (\033[...m), which
+// readline strips when it computes the visible prompt width. Any other
+// escape sequence would be counted and  mess up line editing once the
+// input wraps. readline doesn't know  about the \001 and \002 markers
+// of GNU readline, it would print them verbatim. That's why control
+// characters are not allowed in templates. An invalid template, e.g.
+// set from lua, is ignored in favor of the default.
+func (c *Calc) Prompt() string {
+	if prompt, ok := c.LuaPrompt(); ok {
+		return prompt
+	}
+
+	template := c.prompt
+
+	if template == "" || c.CheckPrompt(template) != nil {
+		template = DefaultPrompt
+	}
+
+	values := c.PromptValues()
+
+	return PromptPlaceholder.ReplaceAllStringFunc(template, func(placeholder string) string {
+		return values[strings.Trim(placeholder, "{}")]
+	})
+}
+
+// the prompt returned by the lua function prompt(state), if there is
+// one. If it fails, the template is used instead and the error is only
+// reported the first time, so that it doesn't flood the screen.
+func (c *Calc) LuaPrompt() (string, bool) {
+	if c.interpreter == nil {
+		return "", false
+	}
+
+	prompt, ok, err := c.interpreter.Prompt(PromptState{
+		Stack:     c.stack.All(),
+		Batch:     c.batch,
+		Debug:     c.debug,
+		Precision: c.precision,
+	})
+
+	if err != nil {
+		if !c.promptfailed {
+			c.PrintError(fmt.Errorf("%w, using the default prompt", err))
+			c.promptfailed = true
+		}
+
+		return "", false
+	}
+
+	return prompt, ok
+}
+
+// SGR colors of the different kinds of output
+const (
+	ColorError  int = 31 // red
+	ColorResult int = 32 // green
+	ColorDim    int = 2
+)
+
+// colorize text if colors are enabled
+func (c *Calc) Colorize(color int, text string) string {
+	if !c.color {
+		return text
+	}
+
+	return colorize(color, text)
+}
+
+// print an error, in red if colors are enabled
+func (c *Calc) PrintError(err error) {
+	fmt.Fprintln(c.errout, c.origin+c.Colorize(ColorError, err.Error()))
+}
+
+// wrap text into an SGR color sequence
+func colorize(color int, text string) string {
+	return fmt.Sprintf("\033[%dm%s\033[0m", color, text)
+}
+
+// the visible width of the prompt, color sequences don't count
+func promptWidth(prompt string) int {
+	return utf8.RuneCountInString(ColorSequence.ReplaceAllString(prompt, ""))
+}
+
+// the actual work horse, evaluate a line of calc command[s]
+func (c *Calc) Eval(line string) error {
+	// jsonload without a file wants this line
+	if c.loadnext {
+		c.loadnext = false
+
+		if err := c.LoadJSON([]byte(line)); err != nil {
+			return Error(err.Error())
+		}
+
+		return nil
+	}
+
+	if c.paragraph || c.linemode {
+		// an empty line ends the current paragraph
+		if strings.TrimSpace(line) == "" {
+			return c.EndParagraph()
+		}
+
+		c.dirty = true
+	}
+
+	// remove comments and expand groups like [1, 2, 3]. In german
+	// notation the comma is the decimal point, so only ; separates
+	// items there
+	separators := ",;"
+	if c.locale == LocaleDE {
+		separators = ";"
+	}
+
+	line, err := expandGroups(c.Comment.ReplaceAllString(line, ""), separators)
+	if err != nil {
+		return Error(err.Error())
+	}
+
+	line = strings.TrimSpace(line)
+
+	if line == "" {
+		return nil
+	}
+
+	items := c.Space.Split(line, -1)
+
+	// the budget is shared by all expansions of an input line
+	if len(c.expanding) == 0 {
+		c.expansions = 0
+		c.line = line
+	}
+
+	var before []float64
+
+	if c.teach {
+		before = c.stack.All()
+		c.lastop = nil
+	}
+
+	// after a -- separator every item is treated as data, so that
+	// numbers can't be mistaken for commands and vice versa
+	data := false
+
+	for len(items) > 0 {
+		item := items[0]
+		c.pending = items[1:]
+		c.notdone = len(c.pending) > 0
+		c.printed = false
+		c.haslast = false
+
+		err := c.CountExpansion()
+
+		switch {
+		case err != nil:
+			err = Error(err.Error())
+		case item == "--" && !data:
+			data = true
+		case data:
+			err = c.EvalData(item)
+		default:
+			err = c.EvalItem(item)
+		}
+
+		if err != nil {
+			c.pending = nil
+			c.failed = true
+
+			return err
+		}
+
+		// the item might have consumed some of the pending ones
+		items = c.pending
+
+		if c.quit {
+			c.pending = nil
+
+			return nil
+		}
+
+		if c.every > 0 && c.window >= c.every {
+			if err := c.EndWindow(); err != nil {
+				c.pending = nil
+
+				return err
+			}
+		}
+	}
+
+	if c.teach {
+		c.Teach(line, before)
+	} else if c.showstack && !c.stdin {
+		fmt.Fprintln(c.out, c.Colorize(ColorDim, "stack: "+c.StackLine()))
+	}
+
+	return nil
+}
+
+// Constructs evaluating lines on their own, like repeat, register
+// themselves here, so that nested or recursive expansions can't run
+// forever.
+func (c *Calc) BeginExpansion(name string) {
+	c.expanding = append(c.expanding, name)
+}
+
+func (c *Calc) EndExpansion() {
+	c.expanding = c.expanding[:len(c.expanding)-1]
+}
+
+// count an item evaluated by a nested or recursive expansion, fails
+// once the budget of the current input line has been used up. A single
+// repeat is limited by MaxRepeat instead, so it isn't charged.
+func (c *Calc) CountExpansion() error {
+	if len(c.expanding) < 2 {
+		return nil
+	}
+
+	c.expansions++
+
+	if c.expansions > c.maxexpand {
+		return fmt.Errorf("expansion budget of %d items exceeded in %s",
+			c.maxexpand, strings.Join(c.expanding, " > "))
+	}
+
+	return nil
+}
+
+// the last items of the stack, as shown by showstack
+func (c *Calc) StackLine() string {
+	dots := ""
+
+	if c.stack.Len() > c.showstacklen {
+		dots = "... "
+	}
+
+	last := []string{}
+	for _, item := range c.stack.Last(c.showstacklen) {
+		last = append(last, c.FormatItem(item))
+	}
+
+	return dots + strings.Join(last, " ")
+}
+
+// print the last operation of the line  with its operands along with
+// the resulting stack, if the line changed it, e.g. 80 20 + | stack: 100
+func (c *Calc) Teach(line string, before []float64) {
+	if list2str(before) == list2str(c.stack.All()) && c.lastop == nil {
+		return
+	}
+
+	operation := line
+	if c.lastop != nil {
+		operation = c.lastop.Text
+	}
+
+	fmt.Fprintf(c.out, "%s | stack: %s\n", operation, c.StackLine())
+}
+
+// evaluate an item after the -- separator, only numbers are allowed
+func (c *Calc) EvalData(item string) error {
+	if done, err := c.EvalLiteral(item); done {
+		return err
+	}
+
+	return Error(fmt.Sprintf("%s is not a number", item))
+}
+
+// push  the item onto  the stack if it  is a number  literal (decimal,
+// hex, time, duration etc).  Returns true if the item has been handled,
+// in which case err tells if the literal was valid.
+func (c *Calc) EvalLiteral(item string) (bool, error) {
+	// a currency symbol separated by whitespace, like in 1.234,56 €
+	if contains(Currencies, item) {
+		return true, nil
+	}
+
+	// percent literal like 5%, relative to the last stack item
+	if c.Numeric.MatchString(item) && strings.HasSuffix(item, "%") {
+		return true, c.PushPercent(item)
+	}
+
+	num, isnumber, err := c.ParseNumber(item)
+	if !isnumber {
+		return false, nil
+	}
+
+	// the separators are guessed, show how they have been interpreted
+	if c.lenientmoney && err == nil && moneyLiteral.MatchString(item) &&
+		item != strconv.FormatFloat(num, 'f', -1, 64) {
+		c.Debug(fmt.Sprintf("amount %s interpreted as %s", item, strconv.FormatFloat(num, 'f', -1, 64)))
+	}
+
+	if err == nil {
+		err = c.CheckNumber(num)
+	}
+
+	if err != nil {
+		return true, Error(err.Error())
+	}
+
+	c.stack.Backup()
+	c.stack.Push(num)
+
+	if c.every > 0 && !c.windowing {
+		c.window++
+		c.dirty = true
+	}
+
+	return true, nil
+}
+
+// ParseNumber  tries all kinds of  number literals in  a defined order.
+// isnumber is false if the item doesn't look like a number at all, if
+// it does, but can't be parsed, err describes the problem.
+func (c *Calc) ParseNumber(item string) (num float64, isnumber bool, err error) {
+	item = stripCurrency(item)
+
+	switch {
+	case c.locale != LocaleDE && ipLiteral.MatchString(item):
+		// 192.168.1.1, checked early, because the dots would be taken
+		// as separators otherwise. In german notation they are
+		// separators, so 1.000.000.000 is a billion there.
+		num, err = parseIP(item)
+
+		return num, true, err
+	case c.lenientmoney && moneyLiteral.MatchString(item):
+		// 1.234,56 or (1,234.56), the decimal separator is guessed
+		num, err = parseMoney(item)
+		if err != nil {
+			return 0, true, fmt.Errorf("malformed amount: %s (%w)", item, err)
+		}
+
+		return num, true, nil
+	case romanLiteral.MatchString(item) && !contains(c.LuaFunctions, item):
+		// MCMLXXXIV, at least two letters or the r: prefix are
+		// required, so they don't collide with other items
+		num, err = parseRoman(item)
+
+		return num, true, err
+	case dateLiteral.MatchString(item):
+		// iso dates like 2024-06-01, as unix timestamp
+		num, err = parseDate(item)
+
+		return num, true, err
+	case !c.Numeric.MatchString(item):
+		// NaN and Inf are the only numbers not starting with a digit
+		if num, err := strconv.ParseFloat(item, 64); err == nil {
+			return num, true, nil
+		}
+
+		// numbers with more than one sign, they are no operators
+		if multiSign.MatchString(item) {
+			return 0, true, fmt.Errorf("malformed number: %s", item)
+		}
+
+		return 0, false, nil
+	}
+
+	// remove digit separators like in 1_000_000 or 1,000,000
+	literal, err := stripSeparators(delocalize(item, c.locale))
+	if err != nil {
+		return 0, true, fmt.Errorf("malformed number: %s (%w)", item, err)
+	}
+
+	num, err = strconv.ParseFloat(literal, 64)
+
+	switch {
+	case err == nil:
+		return num, true, nil
+	case errors.Is(err, strconv.ErrRange):
+		return 0, true, fmt.Errorf("number out of range: %s", item)
+	case fractionLiteral.MatchString(literal):
+		// 3/4, only integers, so that dates don't match
+		num, err = parseFraction(literal)
+	case strings.Contains(literal, ":"):
+		// time like 7:30
+		num, err = parseTime(literal)
+	case c.Hex.MatchString(literal):
+		num, err = parseHex(literal)
+	case dmsLiteral.MatchString(literal):
+		// degrees, minutes and seconds like 52d31m12s
+		num, err = parseDMS(literal)
+	default:
+		// duration like 1h30m, as seconds
+		duration, durerr := time.ParseDuration(literal)
+		if durerr != nil {
+			return 0, true, fmt.Errorf("malformed number: %s", item)
+		}
+
+		num, err = duration.Seconds(), nil
+	}
+
+	return num, true, err
+}
+
+// evaluate a single item according to the first kind of token matching
+func (c *Calc) EvalItem(item string) error {
+	kind, ok := c.Classify(item)
+	if !ok {
+		canonical, err := c.RelaxedCase(item)
+		if err != nil {
+			return Error(err.Error())
+		}
+
+		if canonical != "" {
+			c.Debug(fmt.Sprintf("%s interpreted as %s", item, canonical))
+
+			return c.EvalItem(canonical)
+		}
+
+		if suggestion, ok := c.Suggest(item); ok {
+			return Error(fmt.Sprintf("unknown command or operator, did you mean %s?", suggestion))
+		}
+
+		return Error("unknown command or operator")
+	}
+
+	return kind.Eval(c, item)
+}
+
+// find out how an item would be interpreted
+func (c *Calc) Classify(item string) (TokenKind, bool) {
+	for _, kind := range TokenKinds {
+		if kind.Match(c, item) {
+			return kind, true
+		}
+	}
+
+	return TokenKind{}, false
+}
+
+// is the item any kind of number literal, see EvalLiteral()
+func (c *Calc) IsLiteral(item string) bool {
+	if contains(Currencies, item) {
+		return true
+	}
+
+	if c.Numeric.MatchString(item) && strings.HasSuffix(item, "%") {
+		return true
+	}
+
+	_, isnumber, _ := c.ParseNumber(item)
+
+	return isnumber
+}
+
+// run a builtin function, batch functions only in batch mode
+func (c *Calc) EvalFuncall(item string) error {
+	funcname, keep := item, false
+
+	// sum! and the like keep their operands, in any mode
+	if name, ok := strings.CutSuffix(item, KeepSuffix); ok && exists(c.BatchFuncalls, name) {
+		funcname, keep = name, true
+	}
+
+	if !c.batch && !keep && !exists(c.Funcalls, item) {
+		return Error("only supported in batch mode")
+	}
+
+	if err := c.DoFuncall(funcname, keep); err != nil {
+		return Error(err.Error())
+	}
+
+	c.Result()
+
+	return nil
+}
+
+// >NAME stores the last stack item in a variable, >>NAME removes it
+// from the stack as well, <NAME retrieves it
+func (c *Calc) EvalRegister(item string) error {
+	regmatches := c.Register.FindStringSubmatch(item)
+
+	if regmatches[1] != "<" && (regmatches[2] == LastX || regmatches[2] == LastY) {
+		return Error(regmatches[2] + " is read-only")
+	}
+
+	var err error
+
+	switch regmatches[1] {
+	case ">>":
+		err = c.PopVar(regmatches[2])
+	case ">":
+		err = c.PutVar(regmatches[2])
+	case "<":
+		err = c.GetVar(regmatches[2])
+	}
+
+	if err != nil {
+		return Error(err.Error())
+	}
+
+	return nil
+}
+
+// unless relaxedcase is off, constants are matched case insensitive,
+// so pi works as well as Pi, returns the canonical name
+func (c *Calc) FindConstant(item string) (string, bool) {
+	if contains(c.Constants, item) {
+		return item, true
+	}
+
+	if c.relaxedcase == RelaxedCaseOff {
+		return "", false
+	}
+
+	if matches := matchFold(item, c.Constants); len(matches) == 1 {
+		return matches[0], true
+	}
+
+	return "", false
+}
+
+// retry the lookup of an unknown item case insensitively: constants
+// unless relaxedcase is off, functions as well if it is all. Returns
+// the canonical name if exactly one of them matches, an error listing
+// the candidates if more than one do, and an empty string otherwise.
+func (c *Calc) RelaxedCase(item string) (string, error) {
+	names := []string{}
+
+	if c.relaxedcase != RelaxedCaseOff {
+		names = append(names, c.Constants...)
+	}
+
+	// off by default, because sum and SUM might be different lua
+	// functions
+	if c.relaxedcase == RelaxedCaseAll {
+		for name := range c.Funcalls {
+			names = append(names, name)
+		}
+
+		for name := range c.BatchFuncalls {
+			names = append(names, name)
+		}
+
+		names = append(names, c.LuaFunctions...)
+	}
+
+	matches := matchFold(item, names)
+
+	switch len(matches) {
+	case 0:
+		return "", nil
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("ambiguous %s, candidates: %s", item, strings.Join(matches, ", "))
+	}
+}
+
+// the names equal to item ignoring case, sorted and without duplicates
+func matchFold(item string, names []string) []string {
+	matches := []string{}
+
+	for _, name := range names {
+		if strings.EqualFold(name, item) && !contains(matches, name) {
+			matches = append(matches, name)
+		}
+	}
+
+	sort.Strings(matches)
+
+	return matches
+}
+
+// push the given percentage of the last stack item, e.g. with 400 on
+// the stack 5% pushes 20
+func (c *Calc) PushPercent(item string) error {
+	percent, _, err := c.ParseNumber(strings.TrimSuffix(item, "%"))
+	if err != nil {
+		return Error("malformed percent literal " + item)
+	}
+
+	if c.stack.Len() == 0 {
+		return Error("percent literal needs a value on the stack")
+	}
+
+	c.stack.Backup()
+	c.stack.Push(c.Round(c.stack.Last()[0] / 100 * percent))
+
+	return nil
+}
+
+// consume up to count items following the current one from the input
+// line, -1 means all of them
+func (c *Calc) ConsumeArgs(count int) []string {
+	if count < 0 || count > len(c.pending) {
+		count = len(c.pending)
+	}
+
+	args := c.pending[:count]
+	c.pending = c.pending[count:]
+	c.notdone = len(c.pending) > 0
+
+	return args
+}
+
+// execute an internal command, feed it its arguments if it wants some
+func (c *Calc) RunCommand(name string, command *Command) error {
+	c.CountUsage(name)
+
+	if command.ArgFunc == nil {
+		// FIXME: propagate errors
+		command.Func(c)
+
+		return nil
+	}
+
+	count := command.Expectargs
+	if command.Accepts != nil {
+		candidates := c.pending
+		if count >= 0 && count < len(candidates) {
+			candidates = candidates[:count]
+		}
+
+		count = command.Accepts(c, candidates)
+	}
+
+	if err := command.ArgFunc(c, c.ConsumeArgs(count)); err != nil {
+		return Error(err.Error())
+	}
+
+	return nil
+}
+
+// Execute a math function, check if it is defined just in case
+func (c *Calc) DoFuncall(funcname string, keep bool) error {
+	var function *Funcall
+	if c.batch || keep {
+		function = c.BatchFuncalls[funcname]
+	} else {
+		function = c.Funcalls[funcname]
+	}
+
+	if function == nil {
+		return Error("function not defined but in completion list")
+	}
+
+	var args Numbers
+
+	batch := false
+
+	if function.Expectargs == -1 {
+		// batch mode, but always < stack len, so check first
+		if c.stack.Len() == 0 {
+			return errors.New("stack doesn't provide enough arguments")
+		}
+
+		args = c.stack.All()
+		batch = true
+	} else {
+		//  this is way better behavior than just using 0 in place of
+		// non-existing stack items
+		if c.stack.Len() < function.Expectargs {
+			return errors.New("stack doesn't provide enough arguments")
+		}
+
+		args = c.stack.Last(function.Expectargs)
+	}
+
+	c.Debug(fmt.Sprintf("calling %s with args: %v", funcname, args))
+
+	// the  actual lambda call, so  to say. We provide  a slice of
+	// the requested size, fetched  from the stack (but not popped
+	// yet!)
+	funcresult := function.Func(args)
+
+	if funcresult.Err != nil {
+		// leave the stack untouched in case of any error
+		return funcresult.Err
+	}
+
+	// NaN or Inf would poison every subsequent calculation
+	if err := c.CheckNumber(funcresult.Res); err != nil {
+		if function.Overflow != nil {
+			if reason := function.Overflow(args); reason != nil {
+				return reason
+			}
+		}
+
+		return err
+	}
+
+	// don't forget to backup!
+	c.stack.Backup()
+
+	// "pop"
+	switch {
+	case keep:
+		// the result is pushed on top of the operands
+		funcname += KeepSuffix
+	case batch:
+		// get rid of stack
+		c.stack.Clear()
+	default:
+		// remove operands
+		c.stack.Shift(function.Expectargs)
+	}
+
+	// save result
+	result := c.Round(funcresult.Res)
+	c.stack.Push(result)
+
+	// thanks a lot
+	c.SetHistory(funcname, args, result)
+	c.SetLastX(args)
+	c.CountUsage(funcname)
+
+	return nil
+}
+
+// we need to add a history entry for each operation. Batch functions
+// might get millions of operands from stdin, so we only keep a sample
+// of them, otherwise the history would contain a copy of the stack.
+func (c *Calc) SetHistory(op string, args Numbers, res float64) {
+	operands := sample2str(args, HistorySample)
+
+	// in programmer mode the operands of bitwise operations are easier
+	// to read in hex
+	if function, ok := c.Funcalls[op]; ok && c.progmode && function.Category == "bitwise" {
+		operands = c.HexList(args)
+	}
+
+	c.ResultHistory(res, "%s %s", operands, op)
+}
+
+// integers in hex, other numbers as usual
+func (c *Calc) HexList(items Numbers) string {
+	parts := make([]string, len(items))
+
+	for pos, item := range items {
+		parts[pos] = fmt.Sprint(item)
+
+		if item == math.Trunc(item) {
+			if hex, err := formatHex(item, 0, c.hexupper); err == nil {
+				parts[pos] = hex
+			}
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// like HP calculators we keep the operands of the last math operation
+// in the  registers LASTX (the last  stack item) and  LASTY (the one
+// before), so that they can be retrieved after a mistake. They are no
+// variables, so they are neither listed nor saved and can't be
+// overwritten.
+func (c *Calc) SetLastX(args Numbers) {
+	if len(args) == 0 {
+		return
+	}
+
+	c.lastx = slices.Clone(args[max(len(args)-2, 0):])
+}
+
+// the registers refer to the operands on the stack, so they are
+// meaningless after clearing it
+func (c *Calc) ClearLastX() {
+	c.lastx = nil
+}
+
+// the value of the register LASTX or LASTY, if set
+func (c *Calc) LastRegister(name string) (float64, bool) {
+	position := map[string]int{LastX: 1, LastY: 2}[name]
+
+	if position == 0 || len(c.lastx) < position {
+		return 0, false
+	}
+
+	return c.lastx[len(c.lastx)-position], true
+}
+
+// just a textual representation of math operations, viewable with the
+// history command
+func (c *Calc) History(format string, args ...any) {
+	c.AddHistory(HistoryMath, format, args...)
+}
+
+// a math operation with its result, which is stored exactly
+func (c *Calc) ResultHistory(result float64, format string, args ...any) {
+	entry := HistoryEntry{
+		Kind:      HistoryMath,
+		Text:      fmt.Sprintf(format, args...),
+		Result:    result,
+		HasResult: true,
+	}
+
+	// also needed if the history is disabled
+	c.lastop = &entry
+
+	c.appendHistory(entry)
+}
+
+// same for stack manipulations, so that the user can see when the stack
+// has been cleared, swapped etc
+func (c *Calc) StackHistory(format string, args ...any) {
+	c.AddHistory(HistoryStack, format, args...)
+}
+
+// add an entry, the oldest entries will be dropped once the history
+// limit has been reached
+func (c *Calc) AddHistory(kind string, format string, args ...any) {
+	c.appendHistory(HistoryEntry{
+		Kind: kind,
+		Text: fmt.Sprintf(format, args...),
+	})
+}
+
+func (c *Calc) appendHistory(entry HistoryEntry) {
+	if c.nohistory {
+		return
+	}
+
+	if c.historylimit > 0 && len(c.history) >= c.historylimit {
+		c.history = c.history[len(c.history)-c.historylimit+1:]
+	}
+
+	c.history = append(c.history, entry)
+}
+
+// attach a note to the session, it's part of the history as well
+func (c *Calc) AddNote(text string) {
+	c.notes = append(c.notes, Note{Time: time.Now(), Text: text})
+	c.AddHistory(HistoryNote, "note: %s", text)
+}
+
+// return the exact result of history entry n, counting from 1
+func (c *Calc) RecallHistory(n int) (float64, error) {
+	if n < 1 || n > len(c.history) {
+		return 0, fmt.Errorf("no history entry %d", n)
+	}
+
+	entry := c.history[n-1]
+	if !entry.HasResult {
+		return 0, fmt.Errorf("history entry %d has no result", n)
+	}
+
+	return entry.Result, nil
+}
+
+// write the history to out, results are  formatted using the given
+// function, nil means exact, so that they survive a round trip
+func (c *Calc) ExportHistory(out io.Writer, format func(float64) string) error {
+	if format == nil {
+		format = func(result float64) string {
+			return strconv.FormatFloat(result, 'g', -1, 64)
+		}
+	}
+
+	for _, entry := range c.history {
+		if _, err := fmt.Fprintln(out, entry.Format(format)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// print the history, optionally only entries of the given kind
+func (c *Calc) PrintHistory(kind string) {
+	for _, entry := range c.history {
+		if kind == "" || entry.Kind == kind {
+			fmt.Fprintln(c.out, entry)
+		}
+	}
+}
+
+// print the result
+func (c *Calc) Result() float64 {
+	if c.finalonly && !c.intermediate {
+		// no matter if the input comes as one line or many
+		c.lastresult = c.stack.Last()[0]
+		c.haslast = true
+
+		return c.lastresult
+	}
+
+	// we only  print the result if it's either  a final result or
+	// (if it is intermediate) if -i has been given
+	if !c.quiet && !c.paragraph && !c.linemode && c.every == 0 && !c.teach && (c.intermediate || !c.notdone) {
+		// only needed in repl
+		if !c.stdin {
+			fmt.Fprint(c.out, "= ")
+		}
+
+		c.PrintNumber(c.stack.Last()[0])
+		c.Tee(c.stack.Last()[0])
+	}
+
+	return c.stack.Last()[0]
+}
+
+// unless enabled with allownan, NaN and Inf are not allowed onto the
+// stack
+func (c *Calc) CheckNumber(result float64) error {
+	if !c.allownan && (math.IsNaN(result) || math.IsInf(result, 0)) {
+		return errors.New("result is not a number")
+	}
+
+	return nil
+}
+
+// in money mode every result is rounded to cents, half away from zero,
+// just like invoices are computed line by line
+func (c *Calc) Round(result float64) float64 {
+	if !c.money {
+		return result
+	}
+
+	return roundMoney(result)
+}
+
+func (c *Calc) PrintNumber(result float64) {
+	c.printed = true
+
+	text := c.FormatResult(result)
+
+	if c.progmode {
+		if forms, ok := c.ProgrammerForms(result); ok {
+			text += " " + forms
+		}
+	}
+
+	fmt.Fprintln(c.out, c.origin+c.Colorize(ColorResult, text))
+}
+
+// the hex and binary representation of an integer, as printed in
+// programmer mode, e.g. (0xff, 0b11111111). Returns false for other
+// numbers.
+func (c *Calc) ProgrammerForms(value float64) (string, bool) {
+	hex, err := formatBase(value, 16)
+	if err != nil {
+		return "", false
+	}
+
+	binary, _ := formatBase(value, 2)
+	sign := ""
+
+	if value < 0 {
+		sign = "-"
+		hex = hex[1:]
+		binary = binary[1:]
+	}
+
+	if c.hexupper {
+		hex = strings.ToUpper(hex)
+	}
+
+	return fmt.Sprintf("(%s0x%s, %s0b%s)", sign, hex, sign, binary), true
+}
+
+// format a result the way it is printed, using the precision and the
+// display settings
+func (c *Calc) FormatResult(result float64) string {
+	// showfull is meant to see the exact value, which a custom format
+	// would hide
+	if c.showfull {
+		return fmt.Sprintf("%g", result)
+	}
+
+	// the user might have defined a custom format in lua
+	if c.interpreter != nil {
+		if text, ok := c.interpreter.FormatResult(result); ok {
+			return text
+		}
+	}
+
+	truncated := math.Trunc(result)
+	precision := c.precision
+
+	switch {
+	case c.money:
+		precision = MoneyPrecision
+	case result == truncated && c.notation == NotationFix:
+		precision = 0
+	}
+
+	return c.FormatNumber(result, precision)
+}
+
+// format  a number  with  the given  precision (-1:  as  many digits  as
+// needed), using thousands separators and decimal comma if enabled. In
+// sci and eng notation the precision is the number of digits after the
+// decimal point of the normalized mantissa.
+func (c *Calc) FormatNumber(value float64, precision int) string {
+	var number string
+
+	switch c.notation {
+	case NotationSci:
+		number = strconv.FormatFloat(value, 'e', precision, 64)
+	case NotationEng:
+		number = formatEngineering(value, precision)
+	default:
+		number = strconv.FormatFloat(value, 'f', precision, 64)
+	}
+
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return number
+	}
+
+	separator, decimal := "", "."
+
+	if c.groupdigits {
+		separator = ","
+	}
+
+	if c.decimalcomma {
+		decimal = ","
+
+		if c.groupdigits {
+			separator = "."
+		}
+	}
+
+	return groupDigits(number, separator, decimal)
+}
+
+// format a stack item for dump and the like, which show full precision
+func (c *Calc) FormatItem(value float64) string {
+	if !c.groupdigits && !c.decimalcomma && c.notation == NotationFix {
+		return fmt.Sprint(value)
+	}
+
+	return c.FormatNumber(value, -1)
+}
+
+// finish a paragraph: apply the operator given on the commandline (if
+// any), print the result and start over with an empty stack
+func (c *Calc) EndParagraph() error {
+	if !c.dirty {
+		return nil
+	}
+
+	var err error
+
+	// the error has already been reported, the result would be bogus
+	if !c.failed {
+		if c.paragraphop != "" && c.stack.Len() > 0 {
+			err = c.Eval(c.paragraphop)
+		}
+
+		if err == nil && !c.quiet && c.stack.Len() > 0 {
+			c.PrintNumber(c.stack.Last()[0])
+		}
+	}
+
+	c.dirty = false
+	c.failed = false
+
+	c.stack.Backup()
+	c.stack.Clear()
+
+	return err
+}
+
+// in line mode  every input line is  calculated independently, just
+// like a paragraph: print its result and start over with an empty stack
+func (c *Calc) EndLine() error {
+	if !c.linemode {
+		return nil
+	}
+
+	return c.EndParagraph()
+}
+
+// with --every N the numbers read are split into windows of N numbers,
+// each of them is calculated like a paragraph: the operator given on the
+// commandline is applied, the result printed and the stack cleared
+func (c *Calc) EndWindow() error {
+	c.window = 0
+	c.windowing = true
+
+	defer func() { c.windowing = false }()
+
+	return c.EndParagraph()
+}
+
+// the input might end before the last window is full, it is calculated
+// anyway, unless --drop-partial has been given
+func (c *Calc) EndLastWindow() error {
+	if c.every == 0 || c.window == 0 {
+		return nil
+	}
+
+	if c.droppartial {
+		c.Debug(fmt.Sprintf("dropping partial window of %d items", c.window))
+
+		c.window = 0
+		c.dirty = false
+		c.failed = false
+		c.stack.Backup()
+		c.stack.Clear()
+
+		return nil
+	}
+
+	return c.EndWindow()
+}
+
+// called on exit if --print-final  has been given: print the last stack
+// item, so that  "echo 42 | rpn" can be used as  a number formatter. If
+// the last operation already printed its result, we don't repeat it.
+// when reading stdin only the result of the very last operation is
+// printed, once the input has been consumed. If something else followed
+// it, e.g. a number, there's nothing to print, just like on a single line.
+func (c *Calc) PrintLastResult() {
+	if !c.haslast || c.quiet || c.paragraph || c.linemode || c.every > 0 || c.teach {
+		return
+	}
+
+	c.haslast = false
+
+	c.PrintNumber(c.lastresult)
+	c.Tee(c.lastresult)
+}
+
+func (c *Calc) PrintFinal() {
+	if !c.printfinal || c.quiet || c.printed || c.stack.Len() == 0 {
+		return
+	}
+
+	c.PrintNumber(c.stack.Last()[0])
+}
+
+func (c *Calc) Debug(msg string) {
+	if c.debug {
+		fmt.Fprintf(c.out, "DEBUG(calc): %s\n", msg)
+	}
+}
+
+func (c *Calc) EvalLuaFunction(funcname string) error {
+	// called from calc loop
+	var luaresult float64
+
+	var err error
+
+	switch c.interpreter.FuncNumArgs(funcname) {
+	case 0:
+		fallthrough
+	case 1:
+		luaresult, err = c.interpreter.CallLuaFunc(funcname, c.stack.Last())
+	case 2:
+		luaresult, err = c.interpreter.CallLuaFunc(funcname, c.stack.Last(2))
+	case -1:
+		luaresult, err = c.interpreter.CallLuaFunc(funcname, c.stack.All())
+	default:
+		luaresult, err = 0, errors.New("invalid number of argument requested")
+	}
+
+	if err != nil {
+		return Error(err.Error())
+	}
+
+	if err := c.CheckNumber(luaresult); err != nil {
+		return Error(err.Error())
+	}
+
+	c.stack.Backup()
+
+	dopush := true
+
+	switch c.interpreter.FuncNumArgs(funcname) {
+	case 0:
+		a := c.stack.Last()
+
+		if len(a) == 1 {
+			c.ResultHistory(luaresult, "%s(%f)", funcname, a)
+		}
+
+		dopush = false
+	case 1:
+		a := c.stack.Pop()
+		c.ResultHistory(luaresult, "%s(%f)", funcname, a)
+		c.SetLastX(Numbers{a})
+	case 2:
+		a := c.stack.Pop()
+		b := c.stack.Pop()
+		c.ResultHistory(luaresult, "%s(%f,%f)", funcname, a, b)
+		c.SetLastX(Numbers{b, a})
+	case -1:
+		c.SetLastX(c.stack.All())
+		c.stack.Clear()
+		c.ResultHistory(luaresult, "%s(*)", funcname)
+	}
+
+	if dopush {
+		c.stack.Push(c.Round(luaresult))
+	}
+
+	c.CountUsage(funcname)
+
+	c.Result()
+
+	return nil
+}
+
+func (c *Calc) PutVar(name string) error {
+	last := c.stack.Last()
+
+	if len(last) == 0 {
+		return fmt.Errorf("stack is empty, nothing to store in %s", name)
+	}
+
+	c.Debug(fmt.Sprintf("register %.2f in %s", last[0], name))
+	c.Vars[name] = last[0]
+
+	return nil
+}
+
+func (c *Calc) PopVar(name string) error {
+	if c.stack.Len() == 0 {
+		return fmt.Errorf("stack is empty, nothing to store in %s", name)
+	}
+
+	c.stack.Backup()
+
+	value := c.stack.Pop()
+	c.Debug(fmt.Sprintf("register %.2f in %s", value, name))
+	c.Vars[name] = value
+
+	return nil
+}
+
+func (c *Calc) GetVar(name string) error {
+	value, ok := c.LastRegister(name)
+	if !ok {
+		value, ok = c.Vars[name]
+	}
+
+	if !ok {
+		return fmt.Errorf("variable %s doesn't exist", name)
+	}
+
+	c.Debug(fmt.Sprintf("retrieve %.2f from %s", value, name))
+	c.stack.Backup()
+	c.stack.Push(value)
+
+	return nil
+}
+
+func sortcommands(hash Commands) []string {
+	keys := make([]string, 0, len(hash))
+
+	for key := range hash {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// the help of a command, aliases refer to their canonical name
+func commandHelp(commands Commands, name string) string {
+	if canonical, ok := CommandAliases[name]; ok {
+		return "alias of " + canonical
+	}
+
+	return commands[name].Help
+}
+
+func (c *Calc) PrintHelp() {
+	fmt.Fprintln(c.out, "Available configuration commands:")
+
+	for _, name := range sortcommands(c.SettingsCommands) {
+		fmt.Fprintf(c.out, "%-20s %s\n", name, commandHelp(c.SettingsCommands, name))
+	}
+
+	fmt.Fprintln(c.out)
+
+	fmt.Fprintln(c.out, "Available show commands:")
+
+	for _, name := range sortcommands(c.ShowCommands) {
+		fmt.Fprintf(c.out, "%-20s %s\n", name, commandHelp(c.ShowCommands, name))
+	}
+
+	fmt.Fprintln(c.out)
+
+	fmt.Fprintln(c.out, "Available stack manipulation commands:")
+
+	for _, name := range sortcommands(c.StackCommands) {
+		fmt.Fprintf(c.out, "%-20s %s\n", name, commandHelp(c.StackCommands, name))
+	}
+
+	fmt.Fprintln(c.out)
+
+	fmt.Fprintln(c.out, "Other commands:")
+
+	for _, name := range sortcommands(c.Commands) {
+		fmt.Fprintf(c.out, "%-20s %s\n", name, commandHelp(c.Commands, name))
+	}
+
+	fmt.Fprintln(c.out)
+
+	fmt.Fprintln(c.out, Help)
+
+	// append lua functions, if any
+	if len(LuaFuncs) > 0 {
+		fmt.Fprintln(c.out, "Lua functions:")
+
+		for name, function := range LuaFuncs {
+			fmt.Fprintf(c.out, "%-20s %s\n", name, function.help)
+		}
+	}
+}
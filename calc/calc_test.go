@@ -0,0 +1,4291 @@
+/*
+Copyright © 2023 Thomas von Dein
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package calc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/chzyer/readline"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// evaluate cmd and check that it fails if and only if wantErr is
+// set. Returns whether the caller has a result to compare, which is
+// not the case if an error was expected.
+func evalCase(t *testing.T, calc *Calc, cmd string, wantErr bool) bool {
+	t.Helper()
+
+	err := calc.Eval(cmd)
+
+	if !wantErr {
+		if err != nil {
+			t.Fatalf("%s failed: %s", cmd, err)
+		}
+
+		return true
+	}
+
+	if err == nil {
+		t.Errorf("%s accepted, expected error", cmd)
+	}
+
+	return false
+}
+
+// same, but an expected error must match the message wantErr
+func evalCaseError(t *testing.T, calc *Calc, cmd string, wantErr string) bool {
+	t.Helper()
+
+	err := calc.Eval(cmd)
+
+	if wantErr == "" {
+		if err != nil {
+			t.Fatalf("%s failed: %s", cmd, err)
+		}
+
+		return true
+	}
+
+	switch {
+	case err == nil:
+		t.Errorf("%s accepted, expected error", cmd)
+	case err.Error() != wantErr:
+		t.Errorf("%s failed:\n+++  got: %s\n--- want: %s", cmd, err, wantErr)
+	}
+
+	return false
+}
+
+func TestCommentsAndWhitespace(t *testing.T) {
+	calc := NewCalc()
+
+	var tests = []struct {
+		name string
+		cmd  []string
+		exp  float64 // last element of the stack
+	}{
+		{
+			name: "whitespace prefix",
+			cmd:  []string{"  5"},
+			exp:  5.0,
+		},
+		{
+			name: "whitespace postfix",
+			cmd:  []string{"5  "},
+			exp:  5.0,
+		},
+		{
+			name: "whitespace both",
+			cmd:  []string{"  5   "},
+			exp:  5.0,
+		},
+		{
+			name: "comment line w/ spaces",
+			cmd:  []string{"5", "   #   19"},
+			exp:  5.0,
+		},
+		{
+			name: "comment line w/o spaces",
+			cmd:  []string{"5", `#19`},
+			exp:  5.0,
+		},
+		{
+			name: "inline comment w/ spaces",
+			cmd:  []string{"5   #   19"},
+			exp:  5.0,
+		},
+		{
+			name: "inline comment w/o spaces",
+			cmd:  []string{"5#19"},
+			exp:  5.0,
+		},
+	}
+
+	for _, test := range tests {
+		testname := fmt.Sprintf("%s .(expect %.2f)",
+			test.name, test.exp)
+
+		t.Run(testname, func(t *testing.T) {
+			for _, line := range test.cmd {
+				if err := calc.Eval(line); err != nil {
+					t.Error(err.Error())
+				}
+			}
+			got := calc.stack.Last()
+
+			if len(got) > 0 {
+				if got[0] != test.exp {
+					t.Errorf("parsing failed:\n+++  got: %f\n--- want: %f",
+						got, test.exp)
+				}
+			}
+
+			if calc.stack.Len() != 1 {
+				t.Errorf("invalid stack size:\n+++  got: %d\n--- want: 1",
+					calc.stack.Len())
+			}
+		})
+
+		calc.stack.Clear()
+	}
+}
+
+func TestDigitSeparators(t *testing.T) {
+	calc := NewCalc()
+
+	var tests = []struct {
+		name string
+		cmd  string
+		exp  float64
+		err  bool
+	}{
+		{
+			name: "underscore integer",
+			cmd:  `1_000_000`,
+			exp:  1000000,
+		},
+		{
+			name: "comma integer",
+			cmd:  `1,000,000`,
+			exp:  1000000,
+		},
+		{
+			name: "negative integer",
+			cmd:  `-1_000`,
+			exp:  -1000,
+		},
+		{
+			name: "underscore float",
+			cmd:  `1_234.567_8`,
+			exp:  1234.5678,
+		},
+		{
+			name: "comma float",
+			cmd:  `1,234.5`,
+			exp:  1234.5,
+		},
+		{
+			name: "hex",
+			cmd:  `0xDEAD_BEEF`,
+			exp:  3735928559,
+		},
+		{
+			name: "double separator",
+			cmd:  `1__,2`,
+			err:  true,
+		},
+		{
+			name: "trailing separator",
+			cmd:  `1_000_`,
+			err:  true,
+		},
+		{
+			name: "separator before dot",
+			cmd:  `1_.5`,
+			err:  true,
+		},
+	}
+
+	for _, test := range tests {
+		testname := fmt.Sprintf("separator-%s", test.name)
+
+		t.Run(testname, func(t *testing.T) {
+			calc.stack.Clear()
+
+			err := calc.EvalItem(test.cmd)
+
+			if test.err {
+				if err == nil {
+					t.Errorf("%s accepted, expected error", test.cmd)
+				}
+
+				if calc.stack.Len() != 0 {
+					t.Errorf("stack modified after invalid input %s", test.cmd)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Error(err.Error())
+
+				return
+			}
+
+			got := calc.stack.Last()[0]
+			if got != test.exp {
+				t.Errorf("parsing failed:\n+++  got: %f\n--- want: %f",
+					got, test.exp)
+			}
+		})
+	}
+}
+
+func TestParseNumber(t *testing.T) {
+	var tests = []struct {
+		item     string
+		exp      float64
+		isnumber bool
+		err      string
+	}{
+		{item: "2e-7", exp: 2e-7, isnumber: true},
+		{item: "1,000.5", exp: 1000.5, isnumber: true},
+		{item: "0x10", exp: 16, isnumber: true},
+		{item: "1:30", exp: 1.5, isnumber: true},
+		{item: "1m30s", exp: 90, isnumber: true},
+		{item: "XL", exp: 40, isnumber: true},
+		{item: "2e", isnumber: true, err: "malformed number: 2e"},
+		{item: "2e+", isnumber: true, err: "malformed number: 2e+"},
+		{item: "1e999", isnumber: true, err: "number out of range: 1e999"},
+		{item: "0b1010", isnumber: true, err: "malformed number: 0b1010"},
+		{item: "3/4", exp: 0.75, isnumber: true},
+		{item: "1/0", isnumber: true, err: "division by null"},
+		{item: "1.5/2", isnumber: true, err: "malformed number: 1.5/2"},
+		{item: "1/2/3", isnumber: true, err: "malformed number: 1/2/3"},
+		{item: "sqrt", isnumber: false},
+		{item: "-", isnumber: false},
+	}
+
+	calc := NewCalc()
+
+	for _, test := range tests {
+		t.Run(test.item, func(t *testing.T) {
+			got, isnumber, err := calc.ParseNumber(test.item)
+
+			if isnumber != test.isnumber {
+				t.Errorf("%s: isnumber:\n+++  got: %t\n--- want: %t",
+					test.item, isnumber, test.isnumber)
+			}
+
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Errorf("%s: error:\n+++  got: %v\n--- want: %s",
+						test.item, err, test.err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Error(err.Error())
+
+				return
+			}
+
+			if got != test.exp {
+				t.Errorf("parse number failed:\n+++  got: %f\n--- want: %f",
+					got, test.exp)
+			}
+		})
+	}
+}
+
+func TestCurrency(t *testing.T) {
+	var tests = []struct {
+		name   string
+		locale string
+		cmd    string
+		exp    float64
+		err    bool
+	}{
+		{name: "dollar", locale: LocaleEN, cmd: `$1,234.56`, exp: 1234.56},
+		{name: "negative-dollar", locale: LocaleEN, cmd: `-$5`, exp: -5},
+		{name: "pound-suffix", locale: LocaleEN, cmd: `12.5£`, exp: 12.5},
+		{name: "euro-de", locale: LocaleDE, cmd: `1.234,56€`, exp: 1234.56},
+		{name: "euro-de-space", locale: LocaleDE, cmd: `1.234,56 €`, exp: 1234.56},
+		{name: "plain-de", locale: LocaleDE, cmd: `0,5`, exp: 0.5},
+		{name: "operators-en", locale: LocaleEN, cmd: `$10 $2.5 -`, exp: 7.5},
+		{name: "operators-de", locale: LocaleDE, cmd: `10 2,5 - 2 *`, exp: 15},
+		{name: "symbol-only", locale: LocaleEN, cmd: `$ 5`, exp: 5},
+		{name: "misplaced-separator", locale: LocaleDE, cmd: `1..000€`, err: true},
+		{name: "four-groups-de", locale: LocaleDE, cmd: `1.200.100.100 1 +`, exp: 1200100101},
+		{name: "billion-de", locale: LocaleDE, cmd: `1.000.000.000`, exp: 1e9},
+		{name: "ip-en", locale: LocaleEN, cmd: `192.168.1.1`, exp: 3232235777},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			calc := NewCalc()
+
+			if err := calc.Eval("locale " + test.locale); err != nil {
+				t.Fatal(err)
+			}
+
+			err := calc.Eval(test.cmd)
+
+			if test.err {
+				if err == nil {
+					t.Errorf("%s accepted, expected error", test.cmd)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Error(err.Error())
+
+				return
+			}
+
+			got := calc.stack.Last()[0]
+			if got != test.exp {
+				t.Errorf("parsing failed:\n+++  got: %f\n--- want: %f",
+					got, test.exp)
+			}
+		})
+	}
+}
+
+func TestLenientMoney(t *testing.T) {
+	var tests = []struct {
+		name  string
+		cmd   string
+		exp   float64
+		debug string
+		err   bool
+	}{
+		{
+			name:  "eu-and-us",
+			cmd:   `1.234,56 € $1,234.56 +`,
+			exp:   2469.12,
+			debug: "amount 1.234,56 interpreted as 1234.56",
+		},
+		{
+			name:  "parentheses",
+			cmd:   `100 (25,50) +`,
+			exp:   74.5,
+			debug: "amount (25,50) interpreted as -25.5",
+		},
+		{name: "plain", cmd: `2 3.5 x`, exp: 7},
+		{name: "ambiguous", cmd: `1,234`, err: true},
+		{name: "hex-unaffected", cmd: `0x10`, exp: 16},
+		{name: "ip-unaffected", cmd: `0.0.1.0`, exp: 256},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			calc := NewCalcWriter(out)
+			calc.lenientmoney = true
+			calc.debug = true
+
+			if !evalCase(t, calc, test.cmd, test.err) {
+				return
+			}
+
+			got := calc.stack.Last()[0]
+			if math.Abs(got-test.exp) > 1e-9 {
+				t.Errorf("lenient money failed:\n+++  got: %f\n--- want: %f", got, test.exp)
+			}
+
+			if test.debug != "" && !strings.Contains(out.String(), test.debug) {
+				t.Errorf("lenient money debug failed:\n+++  got: %s\n--- want: %s",
+					out.String(), test.debug)
+			}
+		})
+	}
+
+	// off by default, since it's just a guess
+	calc := NewCalc()
+	if err := calc.Eval(`1.234,56`); err == nil {
+		t.Errorf("1.234,56 accepted, expected error")
+	}
+}
+
+func TestDataSeparator(t *testing.T) {
+	var tests = []struct {
+		name string
+		cmd  string
+		exp  []float64
+		err  bool
+	}{
+		{name: "nop", cmd: `1 nop 2 nop`, exp: []float64{1, 2}},
+		{name: "numbers", cmd: `-- 1 -2 0x10 1:30`, exp: []float64{1, -2, 16, 1.5}},
+		{name: "command-before", cmd: `1 2 batch sum -- 4`, exp: []float64{3, 4}},
+		{name: "command-after", cmd: `1 2 batch -- sum`, exp: []float64{1, 2}, err: true},
+		{name: "double-separator", cmd: `1 -- --`, exp: []float64{1}, err: true},
+		{name: "operator-after", cmd: `1 2 -- +`, exp: []float64{1, 2}, err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			calc := NewCalc()
+
+			evalCase(t, calc, test.cmd, test.err)
+
+			got := calc.stack.All()
+			if list2str(got) != list2str(test.exp) {
+				t.Errorf("eval failed:\n+++  got: %v\n--- want: %v", got, test.exp)
+			}
+		})
+	}
+}
+
+func TestRomanCollisions(t *testing.T) {
+	var tests = []struct {
+		name string
+		cmd  string
+		exp  []float64
+		err  bool
+	}{
+		{name: "numeral", cmd: `MCMLXXXIV XVI +`, exp: []float64{2000}},
+		{name: "prefix", cmd: `r:C r:I +`, exp: []float64{101}},
+		{name: "clear-shortcut", cmd: `1 2 c`, exp: []float64{}},
+		{name: "single-letter", cmd: `1 C`, exp: []float64{1}, err: true},
+		{name: "register", cmd: `5 >MM clear <MM`, exp: []float64{5}},
+		{name: "invalid", cmd: `1 IIII`, exp: []float64{1}, err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			calc := NewCalc()
+
+			evalCase(t, calc, test.cmd, test.err)
+
+			got := calc.stack.All()
+			if list2str(got) != list2str(test.exp) {
+				t.Errorf("eval failed:\n+++  got: %v\n--- want: %v", got, test.exp)
+			}
+		})
+	}
+}
+
+func TestGroups(t *testing.T) {
+	var tests = []struct {
+		name   string
+		cmd    string
+		locale string
+		exp    []float64
+		err    bool
+	}{
+		{name: "commas", cmd: `[1, 2, 3]`, exp: []float64{1, 2, 3}},
+		{name: "no-spaces", cmd: `[1,2,3]`, exp: []float64{1, 2, 3}},
+		{name: "whitespace", cmd: "[ 1 ,\t2 ,   3 ] +", exp: []float64{1, 5}},
+		{name: "semicolons", cmd: `[1; 2; 3]`, exp: []float64{1, 2, 3}},
+		{name: "mean", cmd: `batch [1, 2, 3, 6] mean`, exp: []float64{3}},
+		{name: "mixed", cmd: `10 [1, 2] 3`, exp: []float64{10, 1, 2, 3}},
+		{name: "two-groups", cmd: `[1, 2] [3, 4] x`, exp: []float64{1, 2, 12}},
+		{name: "decimal-comma", cmd: `[1,5; 2,5]`, locale: LocaleDE, exp: []float64{1.5, 2.5}},
+		{name: "unterminated", cmd: `5 [1, 2, 3`, exp: []float64{}, err: true},
+		{name: "unopened", cmd: `5 1, 2]`, exp: []float64{}, err: true},
+		{name: "nested", cmd: `5 [1, [2]]`, exp: []float64{}, err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			calc := NewCalc()
+
+			if test.locale != "" {
+				calc.locale = test.locale
+			}
+
+			evalCase(t, calc, test.cmd, test.err)
+
+			got := calc.stack.All()
+			if list2str(got) != list2str(test.exp) {
+				t.Errorf("groups failed:\n+++  got: %v\n--- want: %v", got, test.exp)
+			}
+		})
+	}
+}
+
+func TestCalc(t *testing.T) {
+	calc := NewCalc()
+
+	var tests = []struct {
+		name  string
+		cmd   string
+		exp   float64
+		batch bool
+	}{
+		// ops
+		{
+			name: "plus",
+			cmd:  `15 15 +`,
+			exp:  30,
+		},
+		{
+			name: "power",
+			cmd:  `4 2 ^`,
+			exp:  16,
+		},
+		{
+			name: "minus",
+			cmd:  `100 50 -`,
+			exp:  50,
+		},
+		{
+			name: "multi",
+			cmd:  `4 4 x`,
+			exp:  16,
+		},
+		{
+			name: "divide",
+			cmd:  `10 2 /`,
+			exp:  5,
+		},
+		{
+			name: "percent",
+			cmd:  `400 20 %`,
+			exp:  80,
+		},
+		{
+			name: "percent-minus",
+			cmd:  `400 20 %-`,
+			exp:  320,
+		},
+		{
+			name: "percent-plus",
+			cmd:  `400 20 %+`,
+			exp:  480,
+		},
+
+		{
+			name: "percent-literal-plus",
+			cmd:  `400 5% +`,
+			exp:  420,
+		},
+		{
+			name: "percent-literal-minus",
+			cmd:  `400 5% -`,
+			exp:  380,
+		},
+
+		// math tests
+		{
+			name: "mod",
+			cmd:  `9 2 mod`,
+			exp:  1,
+		},
+		{
+			name: "sqrt",
+			cmd:  `16 sqrt`,
+			exp:  4,
+		},
+		{
+			name: "ceil",
+			cmd:  `15.5 ceil`,
+			exp:  16,
+		},
+		{
+			name: "dim",
+			cmd:  `6 4 dim`,
+			exp:  2,
+		},
+
+		{
+			name: "ncr",
+			cmd:  `52 5 ncr`,
+			exp:  2598960,
+		},
+		{
+			name: "ncr-large-n",
+			cmd:  `1000 2 ncr`,
+			exp:  499500,
+		},
+		{
+			name: "ncr-k-greater-n",
+			cmd:  `2 5 ncr`,
+			exp:  0,
+		},
+		{
+			name: "ncr-above-2^53",
+			cmd:  `60 30 ncr`,
+			exp:  118264581564861424,
+		},
+		{
+			name: "npr",
+			cmd:  `10 3 npr`,
+			exp:  720,
+		},
+		{
+			name: "multichoose",
+			cmd:  `3 2 multichoose`,
+			exp:  6,
+		},
+
+		// constants tests
+		{
+			name: "pitimes2",
+			cmd:  `Pi 2 *`,
+			exp:  6.283185307179586,
+		},
+		{
+			name: "euler",
+			cmd:  `E log`,
+			exp:  1,
+		},
+		{
+			name: "euler-lowercase",
+			cmd:  `e 1 ^ log`,
+			exp:  1,
+		},
+		{
+			name: "scientific-notation",
+			cmd:  `2e3 1E1 +`,
+			exp:  2010,
+		},
+		{
+			name: "lowercase-pi",
+			cmd:  `pi 2 x`,
+			exp:  6.283185307179586,
+		},
+		{
+			name: "uppercase-sqrt2",
+			cmd:  `SQRT2 sqrt2 x`,
+			exp:  2.0000000000000004,
+		},
+		{
+			name: "pi+sqrt2",
+			cmd:  `Pi Sqrt2 +`,
+			exp:  4.555806215962888,
+		},
+
+		// batch tests
+		{
+			name:  "batch-sum",
+			cmd:   `2 2 2 2 sum`,
+			exp:   8,
+			batch: true,
+		},
+		{
+			name:  "batch-median",
+			cmd:   `1 2 3 4 5 median`,
+			exp:   3,
+			batch: true,
+		},
+		{
+			name:  "batch-median-unsorted",
+			cmd:   `5 1 4 2 3 median`,
+			exp:   3,
+			batch: true,
+		},
+		{
+			name:  "batch-median-even",
+			cmd:   `4 1 3 2 median`,
+			exp:   2.5,
+			batch: true,
+		},
+		{
+			name:  "batch-madev",
+			cmd:   `1 2 3 4 100 madev`,
+			exp:   1,
+			batch: true,
+		},
+		{
+			name:  "batch-mean",
+			cmd:   `2 2 8 2 2 mean`,
+			exp:   3.2,
+			batch: true,
+		},
+		{
+			name:  "batch-min",
+			cmd:   `1 2 3 4 5 min`,
+			exp:   1,
+			batch: true,
+		},
+		{
+			name:  "batch-max",
+			cmd:   `1 2 3 4 5 max`,
+			exp:   5,
+			batch: true,
+		},
+
+		// stack tests
+		{
+			name: "use-vars",
+			cmd:  `10 >TEN clear 5 <TEN *`,
+			exp:  50,
+		},
+		{
+			name: "reverse",
+			cmd:  `100 500 reverse -`,
+			exp:  400,
+		},
+		{
+			name: "swap",
+			cmd:  `2 16 swap /`,
+			exp:  8,
+		},
+		{
+			name:  "clear batch",
+			cmd:   "1 1 1 1 1 clear 1 1 sum",
+			exp:   2,
+			batch: true,
+		},
+		{
+			name: "undo",
+			cmd:  `4 4 + undo *`,
+			exp:  16,
+		},
+
+		// bit tests
+		{
+			name: "bit and",
+			cmd:  `1 3 and`,
+			exp:  1,
+		},
+		{
+			name: "bit or",
+			cmd:  `1 3 or`,
+			exp:  3,
+		},
+		{
+			name: "bit xor",
+			cmd:  `1 3 xor`,
+			exp:  2,
+		},
+
+		// hex tests
+		{
+			name: "hex",
+			cmd:  `-0x10 0x10 +`,
+			exp:  0,
+		},
+
+		// time tests
+		{
+			name: "time",
+			cmd:  `1:30 2:45 +`,
+			exp:  4.25,
+		},
+		{
+			name: "time-in-seconds",
+			cmd:  `1:30 2:45:30 + 3600 x round`,
+			exp:  15330,
+		},
+
+		// duration tests
+		{
+			name: "duration",
+			cmd:  `1h30m 20m +`,
+			exp:  6600,
+		},
+		{
+			name: "duration-zero",
+			cmd:  `90m 1.5h -`,
+			exp:  0,
+		},
+		{
+			name: "duration-seconds",
+			cmd:  `45s 500ms +`,
+			exp:  45.5,
+		},
+
+		// converters
+		{
+			name: "inch-to-cm",
+			cmd:  `111 inch-to-cm`,
+			exp:  281.94,
+		},
+		{
+			name: "gallons-to-liters",
+			cmd:  `111 gallons-to-liters`,
+			exp:  420.135,
+		},
+		{
+			name: "meters-to-yards",
+			cmd:  `111 meters-to-yards`,
+			exp:  1.2139107611548556,
+		},
+		{
+			name: "miles-to-kilometers",
+			cmd:  `111 miles-to-kilometers`,
+			exp:  178.599,
+		},
+		{
+			name: "fraction",
+			cmd:  `1/3 3 x`,
+			exp:  1,
+		},
+		{
+			name: "fraction-negative",
+			cmd:  `-3/4 2 x`,
+			exp:  -1.5,
+		},
+		{
+			name: "fraction-operator",
+			cmd:  `3 4 /`,
+			exp:  0.75,
+		},
+	}
+
+	for _, test := range tests {
+		testname := fmt.Sprintf("cmd-%s-expect-%.2f",
+			test.name, test.exp)
+
+		t.Run(testname, func(t *testing.T) {
+			calc.batch = test.batch
+			if err := calc.Eval(test.cmd); err != nil {
+				t.Error(err.Error())
+			}
+			got := calc.Result()
+			calc.stack.Clear()
+			if got != test.exp {
+				t.Errorf("calc failed:\n+++  got: %f\n--- want: %f",
+					got, test.exp)
+			}
+		})
+	}
+}
+
+func TestRemoveOutliers(t *testing.T) {
+	var tests = []struct {
+		name string
+		cmd  string
+		exp  []float64
+		err  bool
+	}{
+		{
+			name: "two-outliers",
+			cmd:  `10 11 9 10 12 100 -50 10 11 3 rmoutliers`,
+			exp:  []float64{10, 11, 9, 10, 12, 10, 11},
+		},
+		{
+			name: "no-outliers",
+			cmd:  `1 2 3 4 5 3 rmoutliers`,
+			exp:  []float64{1, 2, 3, 4, 5},
+		},
+		{
+			name: "empty-stack",
+			cmd:  `rmoutliers`,
+			err:  true,
+		},
+		{
+			name: "negative-factor",
+			cmd:  `1 2 3 -1 rmoutliers`,
+			exp:  []float64{1, 2, 3, -1},
+			err:  true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			calc := NewCalc()
+
+			evalCase(t, calc, test.cmd, test.err)
+
+			got := calc.stack.All()
+			if fmt.Sprint(got) != fmt.Sprint(test.exp) {
+				t.Errorf("rmoutliers failed:\n+++  got: %v\n--- want: %v",
+					got, test.exp)
+			}
+		})
+	}
+}
+
+func TestStackHistory(t *testing.T) {
+	calc := NewCalc()
+
+	if err := calc.Eval(`1 2 3 swap dup + reverse shift undo 4 clear`); err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		name string
+		kind string
+		exp  []string
+	}{
+		{
+			name: "stack",
+			kind: HistoryStack,
+			exp: []string{
+				"swap: 3 2",
+				"dup: 2",
+				"reverse: 3 items",
+				"shift: removed 1",
+				"undo: 3 items",
+				"clear: removed 4 items",
+			},
+		},
+		{
+			name: "math",
+			kind: HistoryMath,
+			exp:  []string{"2 2 + -> 4.000000"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := []string{}
+
+			for _, entry := range calc.history {
+				if entry.Kind == test.kind {
+					got = append(got, entry.String())
+				}
+			}
+
+			if strings.Join(got, "|") != strings.Join(test.exp, "|") {
+				t.Errorf("history %s failed:\n+++  got: %q\n--- want: %q",
+					test.kind, got, test.exp)
+			}
+		})
+	}
+}
+
+func TestHistoryLimit(t *testing.T) {
+	calc := NewCalc()
+	calc.historylimit = 3
+
+	if err := calc.Eval(`1 1 + 2 + 3 + 4 + 5 +`); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(calc.history) != 3 {
+		t.Fatalf("history limit failed:\n+++  got: %d\n--- want: %d",
+			len(calc.history), 3)
+	}
+
+	exp := "11 5 + -> 16.000000"
+	if got := calc.history[2].String(); got != exp {
+		t.Errorf("history eviction failed:\n+++  got: %s\n--- want: %s", got, exp)
+	}
+}
+
+// summing up lots of values must not copy them into the history. The
+// values are pushed directly,  because evaluating each of them would
+// make a stack backup every time, which is not what we measure here.
+func BenchmarkBatchSumHistory(b *testing.B) {
+	b.ReportAllocs()
+
+	for range b.N {
+		calc := NewCalc()
+		calc.batch = true
+		calc.quiet = true
+
+		for i := range 1000000 {
+			calc.stack.Push(float64(i))
+		}
+
+		if err := calc.Eval("sum"); err != nil {
+			b.Fatal(err)
+		}
+
+		if len(calc.history[0].Text) > 100 {
+			b.Fatalf("history entry not sampled: %d bytes", len(calc.history[0].Text))
+		}
+	}
+}
+
+func TestMoney(t *testing.T) {
+	var tests = []struct {
+		name  string
+		money bool
+		cmd   string
+		exp   float64
+	}{
+		// two invoice lines of 1.02 plus 19% VAT
+		{name: "vat-money", money: true, cmd: `1.02 19 %+ 1.02 19 %+ +`, exp: 2.42},
+		{name: "vat-plain", money: false, cmd: `1.02 19 %+ 1.02 19 %+ +`, exp: 2.4276},
+		{name: "half-away", money: true, cmd: `2.675 1 x`, exp: 2.68},
+		{name: "half-away-negative", money: true, cmd: `-2.675 1 x`, exp: -2.68},
+		{name: "percent-literal", money: true, cmd: `10.05 10%`, exp: 1.01},
+		{name: "batch", money: true, cmd: `0.333 0.333 0.333 batch sum`, exp: 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			calc := NewCalc()
+			calc.money = test.money
+
+			if err := calc.Eval(test.cmd); err != nil {
+				t.Fatal(err)
+			}
+
+			got := calc.stack.Last()[0]
+			if math.Abs(got-test.exp) > 1e-9 {
+				t.Errorf("money mode failed:\n+++  got: %f\n--- want: %f",
+					got, test.exp)
+			}
+		})
+	}
+}
+
+func TestNow(t *testing.T) {
+	TimeNow = func() time.Time {
+		return time.Date(2024, 6, 11, 12, 0, 0, 0, time.UTC)
+	}
+	defer func() { TimeNow = time.Now }()
+
+	var tests = []struct {
+		name string
+		cmd  string
+		exp  float64
+	}{
+		{name: "now", cmd: `now`, exp: 1718107200},
+		{name: "days-since", cmd: `2024-06-01 now swap - 86400 /`, exp: 10.5},
+		{name: "date", cmd: `1970-01-02`, exp: 86400},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			calc := NewCalc()
+
+			if err := calc.Eval(test.cmd); err != nil {
+				t.Fatal(err)
+			}
+
+			got := calc.stack.Last()[0]
+			if got != test.exp {
+				t.Errorf("time function failed:\n+++  got: %f\n--- want: %f",
+					got, test.exp)
+			}
+		})
+	}
+}
+
+func TestRejectNaN(t *testing.T) {
+	var tests = []struct {
+		name     string
+		cmd      string
+		allownan bool
+		exp      float64
+		err      bool
+	}{
+		{name: "sqrt-negative", cmd: `2 -1 sqrt`, exp: -1, err: true},
+		{name: "log-zero", cmd: `2 0 log`, exp: 0, err: true},
+		{name: "pow-overflow", cmd: `10 400 ^`, exp: 400, err: true},
+		{name: "mul-overflow", cmd: `1e308 1e308 x`, exp: 1e308, err: true},
+		{name: "nan-literal", cmd: `1 NaN`, exp: 1, err: true},
+		{name: "inf-literal", cmd: `1 Inf`, exp: 1, err: true},
+		{name: "allowed-sqrt", cmd: `-1 sqrt`, allownan: true, exp: math.NaN()},
+		{name: "allowed-log", cmd: `0 log`, allownan: true, exp: math.Inf(-1)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			calc := NewCalc()
+			calc.allownan = test.allownan
+
+			evalCase(t, calc, test.cmd, test.err)
+
+			got := calc.stack.Last()[0]
+			if got != test.exp && !(math.IsNaN(got) && math.IsNaN(test.exp)) {
+				t.Errorf("%s failed:\n+++  got: %f\n--- want: %f",
+					test.cmd, got, test.exp)
+			}
+		})
+	}
+}
+
+func TestRepeat(t *testing.T) {
+	var tests = []struct {
+		name string
+		cmd  string
+		exp  []float64
+		err  bool
+	}{
+		{name: "compound", cmd: `1000 10 repeat 1.05 x`, exp: []float64{1628.8946267774422}},
+		{name: "zero", cmd: `1000 0 repeat 1.05 x`, exp: []float64{1000}},
+		{name: "push", cmd: `3 repeat 1`, exp: []float64{1, 1, 1}},
+		{name: "rollback", cmd: `8 3 repeat 2 / 1 - 0 /`, exp: []float64{8, 3}, err: true},
+		{name: "rollback-late", cmd: `1 3 repeat 1 - 1 swap /`, exp: []float64{1, 3}, err: true},
+		{name: "fraction", cmd: `1 2.5 repeat 2 x`, exp: []float64{1, 2.5}, err: true},
+		{name: "too-large", cmd: `1 1e7 repeat 2 x`, exp: []float64{1, 1e7}, err: true},
+		{name: "nothing", cmd: `1 2 repeat`, exp: []float64{1, 2}, err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			calc := NewCalc()
+
+			evalCase(t, calc, test.cmd, test.err)
+
+			got := calc.stack.All()
+			if list2str(got) != list2str(test.exp) {
+				t.Errorf("repeat failed:\n+++  got: %v\n--- want: %v", got, test.exp)
+			}
+		})
+	}
+
+	t.Run("history-and-undo", func(t *testing.T) {
+		calc := NewCalc()
+
+		if err := calc.Eval(`1000 10 repeat 1.05 x`); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(calc.history) != 1 || calc.history[0].Text != "repeat 10x: 1.05 x" {
+			t.Errorf("repeat history failed:\n+++  got: %v", calc.history)
+		}
+
+		calc.stack.Restore()
+
+		if got := list2str(calc.stack.All()); got != "1000 10" {
+			t.Errorf("repeat undo failed:\n+++  got: %s\n--- want: 1000 10", got)
+		}
+	})
+
+	t.Run("nested", func(t *testing.T) {
+		calc := NewCalc()
+
+		if err := calc.Eval(`0 2 repeat 1 repeat 1 +`); err != nil {
+			t.Fatal(err)
+		}
+
+		if got := list2str(calc.stack.All()); got != "2" {
+			t.Errorf("nested repeat failed:\n+++  got: %s\n--- want: 2", got)
+		}
+
+		if len(calc.history) != 1 || calc.history[0].Text != "repeat 2x: 1 repeat 1 +" {
+			t.Errorf("nested repeat history failed:\n+++  got: %v", calc.history)
+		}
+
+		if calc.quiet || calc.nohistory {
+			t.Errorf("nested repeat didn't restore the settings")
+		}
+	})
+}
+
+func TestUsageStats(t *testing.T) {
+	calc := NewCalc()
+
+	// not counted, disabled by default
+	if err := calc.Eval("1 2 +"); err != nil {
+		t.Error(err.Error())
+	}
+
+	calc.usagestats = true
+
+	for _, line := range []string{"1 2 + 3 x", "dump", "4 + undo", "5 sqrt"} {
+		if err := calc.Eval(line); err != nil {
+			t.Error(err.Error())
+		}
+	}
+
+	expect := map[string]int{"+": 2, "x": 1, "dump": 1, "undo": 1, "sqrt": 1}
+
+	if len(calc.Usage) != len(expect) {
+		t.Errorf("invalid number of counters:\n+++  got: %v\n--- want: %v",
+			calc.Usage, expect)
+	}
+
+	for name, count := range expect {
+		if calc.Usage[name] != count {
+			t.Errorf("invalid count for %s:\n+++  got: %d\n--- want: %d",
+				name, calc.Usage[name], count)
+		}
+	}
+
+	if first := calc.SortedUsage()[0]; first.Name != "+" {
+		t.Errorf("usage not sorted by count, first entry: %s", first.Name)
+	}
+
+	t.Setenv("HOME", t.TempDir())
+
+	if err := calc.Eval("usage save"); err != nil {
+		t.Error(err.Error())
+	}
+
+	content, err := os.ReadFile(UsageFile())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(string(content), "+ 2\n") {
+		t.Errorf("unexpected usage file contents:\n%s", content)
+	}
+}
+
+func TestListFunctions(t *testing.T) {
+	calc := NewCalc()
+
+	list := calc.ListFunctions()
+
+	// +2: ? and help
+	expect := len(calc.Funcalls) + len(calc.BatchFuncalls) +
+		len(calc.SettingsCommands) + len(calc.ShowCommands) +
+		len(calc.StackCommands) + len(calc.Commands) +
+		len(calc.Constants) + len(LuaFuncs) + 2
+
+	if len(list) != expect {
+		t.Errorf("function list is stale:\n+++  got: %d entries\n--- want: %d entries",
+			len(list), expect)
+	}
+
+	for _, function := range list {
+		if function.Category == "" || function.Help == "" {
+			t.Errorf("incomplete metadata for %s: %+v", function.Name, function)
+		}
+	}
+}
+
+func TestPromptWidth(t *testing.T) {
+	var tests = []struct {
+		name  string
+		batch bool
+		debug bool
+		stack []float64
+		exp   string // the prompt without colors
+	}{
+		{
+			name: "plain",
+			exp:  "rpn [0]» ",
+		},
+		{
+			name:  "stack",
+			stack: []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10},
+			exp:   "rpn [10]» ",
+		},
+		{
+			name:  "batch",
+			batch: true,
+			stack: []float64{1},
+			exp:   "rpn->batch [1]» ",
+		},
+		{
+			name:  "batch-debug",
+			batch: true,
+			debug: true,
+			stack: []float64{1, 2},
+			exp:   "rpn->batch->debug [2/rev2]» ",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			calc := NewCalc()
+			calc.batch = test.batch
+			calc.debug = test.debug
+			calc.color = true
+
+			for _, item := range test.stack {
+				calc.stack.Push(item)
+			}
+
+			prompt := calc.Prompt()
+			want := utf8.RuneCountInString(test.exp)
+
+			if got := promptWidth(prompt); got != want {
+				t.Errorf("invalid prompt width for %q:\n+++  got: %d\n--- want: %d",
+					prompt, got, want)
+			}
+
+			// make sure readline computes the same width
+			runes := readline.Runes{}
+			if got := runes.WidthAll(runes.ColorFilter([]rune(prompt))); got != want {
+				t.Errorf("invalid readline prompt width for %q:\n+++  got: %d\n--- want: %d",
+					prompt, got, want)
+			}
+		})
+	}
+}
+
+func TestCalcLua(t *testing.T) {
+	var tests = []struct {
+		function string
+		stack    []float64
+		exp      float64
+	}{
+		{
+			function: "lower",
+			stack:    []float64{5, 6},
+			exp:      5.0,
+		},
+		{
+			function: "parallelresistance",
+			stack:    []float64{100, 200, 300},
+			exp:      54.54545454545455,
+		},
+	}
+
+	calc := NewCalc()
+
+	LuaInterpreter = lua.NewState(lua.Options{SkipOpenLibs: true})
+	defer LuaInterpreter.Close()
+
+	luarunner := NewInterpreter(filepath.Join("..", "example.lua"), false)
+	luarunner.InitLua()
+	calc.SetInt(luarunner)
+
+	for _, test := range tests {
+		testname := fmt.Sprintf("lua-%s", test.function)
+
+		t.Run(testname, func(t *testing.T) {
+			calc.stack.Clear()
+			for _, item := range test.stack {
+				calc.stack.Push(item)
+			}
+
+			if err := calc.EvalLuaFunction(test.function); err != nil {
+				t.Errorf("lua function %s failed: %s", test.function, err)
+			}
+
+			got := calc.stack.Last()
+
+			if calc.stack.Len() != 1 {
+				t.Errorf("invalid stack size:\n+++  got: %d\n--- want: 1",
+					calc.stack.Len())
+			}
+
+			if got[0] != test.exp {
+				t.Errorf("lua function %s failed:\n+++  got: %f\n--- want: %f",
+					test.function, got, test.exp)
+			}
+		})
+	}
+}
+
+func TestLuaNumerics(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "solve.lua")
+	code := `
+function poly(x)
+    return (x - 3) * (x + 1)
+end
+
+function nozero(x)
+    return x * x + 1
+end
+
+function lower(a, b)
+    return math.min(a, b)
+end
+
+function sin(x)
+    return math.sin(x)
+end
+
+function inverse(x)
+    return 1 / x
+end
+
+function root(x)
+    return math.sqrt(x)
+end
+
+function sqrtwo(x)
+    return x * x - 2
+end
+
+function init()
+    register("sqrtwo", 1, "sqrtwo")
+    register("poly", 1, "poly")
+    register("nozero", 1, "nozero")
+    register("lower", 2, "lower")
+    register("sin", 1, "sin")
+    register("inverse", 1, "inverse")
+    register("root", 1, "root")
+end
+`
+	if err := os.WriteFile(script, []byte(code), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		name string
+		cmd  string
+		exp  float64
+		size int // expected stack size, if not 1
+		err  bool
+	}{
+		{name: "positive-root", cmd: `5 solve poly`, exp: 3},
+		{name: "negative-root", cmd: `-4 solve poly`, exp: -1},
+		{name: "no-root", cmd: `5 solve nozero`, exp: 5, err: true},
+		{name: "too-few-iterations", cmd: `5 maxiter 2 solve poly`, exp: 5, err: true},
+		{name: "two-args", cmd: `5 solve lower`, exp: 5, err: true},
+		{name: "unknown", cmd: `5 solve nothing`, exp: 5, err: true},
+		{name: "integrate-poly", cmd: `0 3 integrate poly`, exp: -9},
+		{name: "integrate-sin", cmd: `0 Pi integrate sin`, exp: 2},
+		{name: "integrate-reverse", cmd: `Pi 0 integrate sin`, exp: -2},
+		{name: "integrate-ln", cmd: `1 E integrate inverse`, exp: 1},
+		{name: "integrate-pole", cmd: `-1 1 integrate inverse`, exp: 1, size: 2, err: true},
+		{name: "integrate-one-bound", cmd: `1 integrate sin`, exp: 1, err: true},
+		{name: "integrate-budget", cmd: `0 Pi tolerance 1e-300 maxiter 1 integrate sin`, exp: math.Pi, size: 2, err: true},
+		{name: "deriv-poly", cmd: `3 deriv poly`, exp: 4},
+		{name: "deriv-sin", cmd: `0 deriv sin`, exp: 1},
+		{name: "deriv-inverse", cmd: `2 deriv inverse`, exp: -0.25},
+		{name: "deriv-undefined", cmd: `-1 deriv root`, exp: -1, err: true},
+		{name: "solve-money", cmd: `money 1 solve sqrtwo`, exp: 1.41},
+		{name: "integrate-money", cmd: `money 1 4 integrate inverse`, exp: 1.39},
+		{name: "deriv-money", cmd: `money 1 deriv sin`, exp: 0.54},
+		{name: "lastx", cmd: `5 poly lastx`, exp: 5, size: 2},
+		{name: "lasty", cmd: `4 2 lower <LASTY`, exp: 4, size: 2},
+	}
+
+	LuaInterpreter = lua.NewState(lua.Options{SkipOpenLibs: true})
+	defer LuaInterpreter.Close()
+
+	luarunner := NewInterpreter(script, false)
+	luarunner.InitLua()
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			calc := NewCalc()
+			calc.SetInt(luarunner)
+
+			evalCase(t, calc, test.cmd, test.err)
+
+			size := test.size
+			if size == 0 {
+				size = 1
+			}
+
+			if calc.stack.Len() != size {
+				t.Fatalf("invalid stack size:\n+++  got: %d\n--- want: %d",
+					calc.stack.Len(), size)
+			}
+
+			got := calc.stack.Last()[0]
+			if math.Abs(got-test.exp) > 1e-6 {
+				t.Errorf("%s failed:\n+++  got: %f\n--- want: %f",
+					test.cmd, got, test.exp)
+			}
+		})
+	}
+}
+
+func FuzzEval(f *testing.F) {
+	legal := []string{
+		"dump",
+		"showstack",
+		"help",
+		"Pi 31 *",
+		"SqrtE Pi /",
+		"55.5 yards-to-meters",
+		"2 4 +",
+		"7 8 batch sum",
+		"7 8 %-",
+		"7 8 clear",
+		"7 8 /",
+		"b",
+		"#444",
+		"<X",
+	}
+
+	for _, item := range legal {
+		f.Add(item)
+	}
+
+	calc := NewCalc()
+
+	f.Fuzz(func(t *testing.T, line string) {
+		t.Logf("Stack:\n%v\n", calc.stack.All())
+		if err := calc.EvalItem(line); err == nil {
+			t.Logf("given: <%s>", line)
+			// not corpus and empty?
+			if !contains(legal, line) && len(line) > 0 {
+				item := strings.TrimSpace(calc.Comment.ReplaceAllString(line, ""))
+				if contains(Currencies, item) {
+					return
+				}
+				if calc.Numeric.MatchString(item) {
+					item = strings.TrimSuffix(item, "%")
+				}
+				_, isnumber, numerr := calc.ParseNumber(item)
+				_, isconstant := calc.FindConstant(item)
+				relaxed, _ := calc.RelaxedCase(item)
+				// no comment, no number, no known command or function?
+				if len(item) > 0 &&
+					(!isnumber || numerr != nil) &&
+					!isconstant && relaxed == "" &&
+					!exists(calc.Funcalls, item) &&
+					!exists(calc.BatchFuncalls, item) &&
+					!contains(calc.LuaFunctions, item) &&
+					!exists(calc.Commands, item) &&
+					!exists(calc.ShowCommands, item) &&
+					!exists(calc.SettingsCommands, item) &&
+					!exists(calc.StackCommands, item) &&
+					!calc.Register.MatchString(item) &&
+					item != "?" && item != "help" {
+					t.Errorf("Fuzzy input accepted: <%s>", line)
+				}
+			}
+		}
+	})
+}
+
+func TestGroupedOutput(t *testing.T) {
+	var tests = []struct {
+		name   string
+		cmd    string
+		groups bool
+		comma  bool
+		exp    string
+	}{
+		{
+			name: "default",
+			cmd:  `1234567.891`,
+			exp:  "1234567.89\n",
+		},
+		{
+			name:   "groupdigits",
+			cmd:    `1234567.891`,
+			groups: true,
+			exp:    "1,234,567.89\n",
+		},
+		{
+			name:  "decimalcomma",
+			cmd:   `1234567.891`,
+			comma: true,
+			exp:   "1234567,89\n",
+		},
+		{
+			name:   "both",
+			cmd:    `1234567.891`,
+			groups: true,
+			comma:  true,
+			exp:    "1.234.567,89\n",
+		},
+		{
+			name:   "integer",
+			cmd:    `-1000000`,
+			groups: true,
+			exp:    "-1,000,000\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			calc := NewCalc()
+			calc.out = out
+			calc.stdin = true
+			calc.groupdigits = test.groups
+			calc.decimalcomma = test.comma
+
+			if err := calc.Eval(test.cmd); err != nil {
+				t.Fatal(err)
+			}
+
+			calc.Result()
+
+			if !strings.HasPrefix(out.String(), test.exp) {
+				t.Errorf("output failed:\n+++  got: %q\n--- want: %q",
+					out.String(), test.exp)
+			}
+		})
+	}
+
+	t.Run("dump items", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		calc := NewCalc()
+		calc.out = out
+		calc.stdin = true
+		calc.groupdigits = true
+		calc.decimalcomma = true
+
+		if err := calc.Eval(`1234.5 10000 dump`); err != nil {
+			t.Fatal(err)
+		}
+
+		exp := "#2: 1.234,50\n#1:   10.000 <- top\n"
+		if !strings.Contains(out.String(), exp) {
+			t.Errorf("dump failed:\n+++  got: %q\n--- want: %q", out.String(), exp)
+		}
+	})
+}
+
+func TestPrecisionCommand(t *testing.T) {
+	var tests = []struct {
+		name string
+		cmd  string
+		exp  string
+		err  bool
+	}{
+		{
+			name: "precision",
+			cmd:  `1 3 / precision 6 dup +`,
+			exp:  "0.666667\n",
+		},
+		{
+			name: "prec",
+			cmd:  `prec 0 2 3 /`,
+			exp:  "1\n",
+		},
+		{
+			name: "too large",
+			cmd:  `precision 16`,
+			err:  true,
+		},
+		{
+			name: "negative",
+			cmd:  `precision -1`,
+			err:  true,
+		},
+		{
+			name: "not a number",
+			cmd:  `precision foo`,
+			err:  true,
+		},
+		{
+			name: "no integer",
+			cmd:  `precision 2.5 2 *`,
+			exp:  "precision: 2\n5\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			calc := NewCalc()
+			calc.out = out
+			calc.stdin = true
+			calc.quietstack = true
+
+			if !evalCase(t, calc, test.cmd, test.err) {
+				if calc.precision != Precision {
+					t.Errorf("precision changed to %d", calc.precision)
+				}
+
+				return
+			}
+
+			if out.String() != test.exp {
+				t.Errorf("precision failed:\n+++  got: %q\n--- want: %q",
+					out.String(), test.exp)
+			}
+		})
+	}
+}
+
+func TestHistoryRecall(t *testing.T) {
+	calc := NewCalc()
+	calc.quiet = true
+
+	if err := calc.Eval(`1 3 /`); err != nil {
+		t.Fatal(err)
+	}
+
+	// the precision only affects the display
+	if got := calc.history[0].String(); got != "1 3 / -> 0.333333" {
+		t.Errorf("history view failed:\n+++  got: %s", got)
+	}
+
+	result, err := calc.RecallHistory(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := result * 3; math.Abs(got-1) > 1e-15 {
+		t.Errorf("history recall failed:\n+++  got: %.17f\n--- want: %f", got, 1.0)
+	}
+
+	for _, n := range []int{0, 2} {
+		if _, err := calc.RecallHistory(n); err == nil {
+			t.Errorf("history entry %d accepted, expected error", n)
+		}
+	}
+
+	if err := calc.Eval(`dup`); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := calc.RecallHistory(2); err == nil {
+		t.Errorf("stack history entry accepted, expected error")
+	}
+
+	out := &bytes.Buffer{}
+	if err := calc.ExportHistory(out, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := "1 3 / -> 0.3333333333333333\ndup: 0.3333333333333333\n"
+	if out.String() != exp {
+		t.Errorf("history export failed:\n+++  got: %q\n--- want: %q", out.String(), exp)
+	}
+}
+
+func TestNotation(t *testing.T) {
+	var tests = []struct {
+		notation string
+		value    string
+		exp      string
+	}{
+		{notation: NotationFix, value: "0.000123", exp: "0.00"},
+		{notation: NotationFix, value: "1.23e9", exp: "1230000000"},
+		{notation: NotationSci, value: "0.000123", exp: "1.23e-04"},
+		{notation: NotationSci, value: "1.23e9", exp: "1.23e+09"},
+		{notation: NotationEng, value: "0.000123", exp: "123e-6"},
+		{notation: NotationEng, value: "1.23e9", exp: "1.23e9"},
+		{notation: NotationEng, value: "-12500", exp: "-12.5e3"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.notation+"-"+test.value, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			calc := NewCalc()
+			calc.out = out
+			calc.stdin = true
+			calc.notation = test.notation
+
+			if err := calc.Eval(test.value); err != nil {
+				t.Fatal(err)
+			}
+
+			calc.Result()
+
+			if got := strings.TrimSpace(out.String()); got != test.exp {
+				t.Errorf("notation %s failed:\n+++  got: %s\n--- want: %s",
+					test.notation, got, test.exp)
+			}
+		})
+	}
+
+	t.Run("dump", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		calc := NewCalc()
+		calc.out = out
+		calc.stdin = true
+		calc.notation = NotationEng
+		calc.decimalcomma = true
+
+		if err := calc.Eval(`0.000123 1.23e9 dump`); err != nil {
+			t.Fatal(err)
+		}
+
+		exp := "#2: 123e-6\n#1: 1,23e9 <- top\n"
+		if !strings.HasSuffix(out.String(), exp) {
+			t.Errorf("dump failed:\n+++  got: %q\n--- want: %q", out.String(), exp)
+		}
+	})
+}
+
+func TestShowFull(t *testing.T) {
+	var tests = []struct {
+		name     string
+		cmd      string
+		showfull bool
+		exp      string
+		err      bool
+	}{
+		{name: "rounded", cmd: `0.1 0.2 +`, exp: "0.30\n"},
+		{name: "full", cmd: `0.1 0.2 + full`, exp: "0.30\n0.30000000000000004\n"},
+		{name: "showfull", cmd: `0.1 0.2 +`, showfull: true, exp: "0.30000000000000004\n"},
+		{name: "empty", cmd: `full`, err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			calc := NewCalc()
+			calc.out = out
+			calc.stdin = true
+			calc.intermediate = true
+			calc.showfull = test.showfull
+
+			if !evalCase(t, calc, test.cmd, test.err) {
+				return
+			}
+
+			if out.String() != test.exp {
+				t.Errorf("full failed:\n+++  got: %q\n--- want: %q", out.String(), test.exp)
+			}
+		})
+	}
+}
+
+func TestLuaConversions(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "conversions.lua")
+	code := `
+function init()
+    register_conversion("furlong", "meters", 201.168, "furlongs to meters")
+    register_conversion("celsius", "fahrenheit", 1.8, "celsius to fahrenheit", 32)
+    register_conversion("ells", "inches", 45)
+end
+`
+	if err := os.WriteFile(script, []byte(code), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		cmd string
+		exp float64
+	}{
+		{cmd: `2 furlong-to-meters`, exp: 402.336},
+		{cmd: `402.336 meters-to-furlong`, exp: 2},
+		{cmd: `100 celsius-to-fahrenheit`, exp: 212},
+		{cmd: `-40 celsius-to-fahrenheit`, exp: -40},
+		{cmd: `212 fahrenheit-to-celsius`, exp: 100},
+		{cmd: `90 inches-to-ells`, exp: 2},
+	}
+
+	LuaInterpreter = lua.NewState(lua.Options{SkipOpenLibs: true})
+	defer LuaInterpreter.Close()
+
+	luarunner := NewInterpreter(script, false)
+	luarunner.InitLua()
+
+	for _, test := range tests {
+		t.Run(test.cmd, func(t *testing.T) {
+			calc := NewCalc()
+			calc.SetInt(luarunner)
+
+			if err := calc.Eval(test.cmd); err != nil {
+				t.Fatal(err)
+			}
+
+			got := calc.stack.Last()[0]
+			if math.Abs(got-test.exp) > 1e-9 {
+				t.Errorf("conversion failed:\n+++  got: %f\n--- want: %f", got, test.exp)
+			}
+		})
+	}
+
+	calc := NewCalc()
+	calc.SetInt(luarunner)
+
+	for name, exp := range map[string]string{
+		"furlong-to-meters": "convert furlongs to meters",
+		"meters-to-furlong": "convert meters to furlong",
+		"ells-to-inches":    "convert ells to inches",
+	} {
+		function, ok := calc.Funcalls[name]
+		if !ok {
+			t.Fatalf("conversion %s not registered", name)
+		}
+
+		if function.Help != exp || function.Category != "converter" {
+			t.Errorf("conversion %s help failed:\n+++  got: %s\n--- want: %s",
+				name, function.Help, exp)
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "feet.lua")
+	code := `
+function init()
+    register_conversion("feet", "inch", 12)
+end
+`
+	if err := os.WriteFile(script, []byte(code), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		name  string
+		cmd   string
+		exp   float64
+		chain string
+		err   bool
+	}{
+		{
+			name:  "direct",
+			cmd:   `2 convert inch cm`,
+			exp:   5.08,
+			chain: "inch-to-cm",
+		},
+		{
+			name:  "two-hops",
+			cmd:   `2 convert feet cm`,
+			exp:   60.96,
+			chain: "feet-to-inch, inch-to-cm",
+		},
+		{
+			name:  "two-hops-back",
+			cmd:   `60.96 convert cm feet`,
+			exp:   2,
+			chain: "cm-to-inch, inch-to-feet",
+		},
+		{
+			name: "no-path",
+			cmd:  `2 convert inch liters`,
+			err:  true,
+		},
+		{
+			name: "unknown-unit",
+			cmd:  `2 convert inch furlong`,
+			err:  true,
+		},
+		{
+			name: "same-unit",
+			cmd:  `2 convert inch inch`,
+			err:  true,
+		},
+		{
+			name: "empty-stack",
+			cmd:  `convert inch cm`,
+			err:  true,
+		},
+	}
+
+	LuaInterpreter = lua.NewState(lua.Options{SkipOpenLibs: true})
+	defer LuaInterpreter.Close()
+
+	luarunner := NewInterpreter(script, false)
+	luarunner.InitLua()
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			calc := NewCalcWriter(out)
+			calc.SetInt(luarunner)
+			calc.debug = true
+
+			if !evalCase(t, calc, test.cmd, test.err) {
+				return
+			}
+
+			got := calc.stack.Last()[0]
+			if math.Abs(got-test.exp) > 1e-9 {
+				t.Errorf("convert failed:\n+++  got: %f\n--- want: %f", got, test.exp)
+			}
+
+			if !strings.Contains(out.String(), "conversion chain: "+test.chain+"\n") {
+				t.Errorf("convert chain failed:\n+++  got: %s\n--- want: %s",
+					out.String(), test.chain)
+			}
+		})
+	}
+
+	calc := NewCalc()
+	calc.SetInt(luarunner)
+
+	err := calc.Eval(`2 convert inch liters`)
+	exp := "Error: no conversion from inch to liters"
+
+	if err == nil || err.Error() != exp {
+		t.Errorf("convert error failed:\n+++  got: %v\n--- want: %s", err, exp)
+	}
+}
+
+func TestHexCommand(t *testing.T) {
+	var tests = []struct {
+		name  string
+		cmd   string
+		upper bool
+		exp   string
+		err   bool
+	}{
+		{name: "plain", cmd: `48879 hex`, exp: "0xbeef\n"},
+		{name: "padded", cmd: `48879 hex 8`, exp: "0x0000beef\n"},
+		{name: "upper", cmd: `48879 hex 8`, upper: true, exp: "0x0000BEEF\n"},
+		{name: "negative", cmd: `-1 hex 4`, exp: "0xffff\n"},
+		{name: "no-width", cmd: `255 hex dup`, exp: "0xff\n"},
+		{name: "too-wide", cmd: `1 hex 17`, err: true},
+		{name: "too-small", cmd: `256 hex 2`, err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			calc := NewCalc()
+			calc.out = out
+			calc.quiet = true
+			calc.hexupper = test.upper
+
+			if !evalCase(t, calc, test.cmd, test.err) {
+				return
+			}
+
+			if out.String() != test.exp {
+				t.Errorf("hex failed:\n+++  got: %q\n--- want: %q", out.String(), test.exp)
+			}
+		})
+	}
+}
+
+func TestLastX(t *testing.T) {
+	var tests = []struct {
+		name string
+		cmd  string
+		exp  Numbers
+		err  bool
+	}{
+		{name: "correct-operand", cmd: `100 7 + lastx -`, exp: Numbers{100}},
+		{name: "register", cmd: `100 7 + <LASTX <LASTY`, exp: Numbers{107, 7, 100}},
+		{name: "function", cmd: `16 sqrt lastx`, exp: Numbers{4, 16}},
+		{name: "batch", cmd: `batch 1 2 3 sum lastx <LASTY`, exp: Numbers{6, 3, 2}},
+		{name: "stack-commands", cmd: `1 2 + 5 dup swap lastx`, exp: Numbers{3, 5, 5, 2}},
+		{name: "undefined", cmd: `1 lastx`, err: true},
+		{name: "compound", cmd: `100 7 %+ lastx %+`, exp: Numbers{114.49}},
+		{name: "cleared", cmd: `100 7 + clear lastx`, err: true},
+		{name: "read-only", cmd: `100 7 + 5 >LASTX`, err: true},
+		{name: "read-only-pop", cmd: `100 7 + 5 >>LASTY`, err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			calc := NewCalc()
+			calc.quiet = true
+
+			if !evalCase(t, calc, test.cmd, test.err) {
+				return
+			}
+
+			if got := calc.stack.All(); list2str(got) != list2str(test.exp) {
+				t.Errorf("lastx failed:\n+++  got: %s\n--- want: %s",
+					list2str(got), list2str(test.exp))
+			}
+
+			// the registers are no variables
+			if len(calc.Vars) != 0 {
+				t.Errorf("lastx registers stored as variables: %v", calc.Vars)
+			}
+		})
+	}
+}
+
+func TestShowEmptyStack(t *testing.T) {
+	commands := []string{
+		"hex", "base 2", "human", "fraction", "to-time", "to-duration",
+		"to-dms", "to-date", "to-ip", "full", "roman", "asint", "asuint",
+		"asfloatbits",
+	}
+
+	for _, command := range commands {
+		t.Run(command, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			calc := NewCalcWriter(out)
+
+			err := calc.Eval(command)
+			if err == nil || !strings.Contains(err.Error(), "stack is empty") {
+				t.Errorf("%s on an empty stack:\n+++  got: %v\n--- want: stack is empty", command, err)
+			}
+
+			if out.Len() != 0 {
+				t.Errorf("%s printed %q on an empty stack", command, out.String())
+			}
+		})
+	}
+}
+
+func TestBaseCommand(t *testing.T) {
+	var tests = []struct {
+		name string
+		cmd  string
+		exp  string
+		err  bool
+	}{
+		{name: "binary", cmd: `10 base 2`, exp: "1010\n"},
+		{name: "base36", cmd: `1295 base 36`, exp: "zz\n"},
+		{name: "continue", cmd: `49 base 7 dup`, exp: "100\n"},
+		{name: "fraction", cmd: `1.5 base 2`, err: true},
+		{name: "invalid-base", cmd: `10 base 37`, err: true},
+		{name: "not-a-base", cmd: `10 base x`, err: true},
+		{name: "no-base", cmd: `10 base`, err: true},
+		{name: "empty", cmd: `base 2`, err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			calc := NewCalc()
+			calc.out = out
+			calc.quiet = true
+
+			if !evalCase(t, calc, test.cmd, test.err) {
+				return
+			}
+
+			if out.String() != test.exp {
+				t.Errorf("base failed:\n+++  got: %q\n--- want: %q", out.String(), test.exp)
+			}
+		})
+	}
+}
+
+func TestHumanCommand(t *testing.T) {
+	var tests = []struct {
+		name string
+		cmd  string
+		exp  string
+		err  bool
+	}{
+		{name: "iec", cmd: `1500000000 human`, exp: "1.40 GiB\n"},
+		{name: "si", cmd: `byteunits si 1500000000 human`, exp: "1.50 GB\n"},
+		{name: "precision", cmd: `precision 1 byteunits iec 1536 human`, exp: "1.5 KiB\n"},
+		{name: "bytes", cmd: `byteunits si 999 human`, exp: "999 B\n"},
+		{name: "exabytes", cmd: `2 60 ^ human`, exp: "1.00 EiB\n"},
+		{name: "invalid", cmd: `byteunits metric`, err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			calc := NewCalc()
+			calc.out = out
+			calc.quiet = true
+
+			if !evalCase(t, calc, test.cmd, test.err) {
+				return
+			}
+
+			if out.String() != test.exp {
+				t.Errorf("human failed:\n+++  got: %q\n--- want: %q", out.String(), test.exp)
+			}
+		})
+	}
+}
+
+// the meaning of existing input must not change silently
+func TestTokenPrecedence(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "shadow.lua")
+	code := `
+function sqrt(x)
+    return x
+end
+
+function mix(x)
+    return x
+end
+
+function init()
+    register("sqrt", 1, "shadows builtin")
+    register("MIX", 1, "looks like a roman numeral")
+    register("dump", 1, "shadows command")
+end
+`
+	if err := os.WriteFile(script, []byte(code), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		item string
+		lua  bool
+		exp  string
+	}{
+		{item: "1", exp: "number"},
+		{item: "-0x10", exp: "number"},
+		{item: "5%", exp: "number"},
+		{item: "€", exp: "number"},
+		{item: "inf", exp: "number"},
+		{item: "2e", exp: "number"}, // malformed, but still a number
+		{item: "MCM", exp: "number"},
+		{item: "Pi", exp: "constant"},
+		{item: "pi", exp: "constant"},
+		{item: "sqrt", exp: "function"},
+		{item: "+", exp: "function"},
+		{item: "median", exp: "batch function"},
+		{item: "median!", exp: "keep function"},
+		{item: "!", exp: "function (alias of factorial)"},
+		{item: "*", exp: "function (alias of x)"},
+		{item: "avg", exp: "batch function (alias of mean)"},
+		{item: "avg!", exp: "keep function (alias of mean!)"},
+		{item: "quit", exp: "command (alias of exit)"},
+		{item: "u", exp: "stack command (alias of undo)"},
+		{item: "prec", exp: "setting (alias of precision)"},
+		{item: ">X", exp: "register"},
+		{item: ">>X", exp: "register"},
+		{item: "<X", exp: "register"},
+		{item: "repeat", exp: "command"},
+		{item: "dump", exp: "show command"},
+		{item: "undo", exp: "stack command"},
+		{item: "debug", exp: "setting"},
+		{item: "?", exp: "help"},
+		{item: "foo", exp: "unknown"},
+		{item: "sqrt", lua: true, exp: "lua function"},
+		{item: "dump", lua: true, exp: "lua function"},
+		{item: "MIX", lua: true, exp: "lua function"},
+		{item: "MCM", lua: true, exp: "number"},
+		{item: "1", lua: true, exp: "number"},
+	}
+
+	LuaInterpreter = lua.NewState(lua.Options{SkipOpenLibs: true})
+	defer LuaInterpreter.Close()
+
+	luarunner := NewInterpreter(script, false)
+	luarunner.InitLua()
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%s-%t", test.item, test.lua), func(t *testing.T) {
+			out := &bytes.Buffer{}
+			calc := NewCalc()
+			calc.out = out
+
+			if test.lua {
+				calc.SetInt(luarunner)
+			}
+
+			if err := calc.Eval("which " + test.item); err != nil {
+				t.Fatal(err)
+			}
+
+			exp := test.item + ": " + test.exp + "\n"
+			if out.String() != exp {
+				t.Errorf("precedence failed:\n+++  got: %q\n--- want: %q", out.String(), exp)
+			}
+		})
+	}
+}
+
+func TestFractionCommand(t *testing.T) {
+	var tests = []struct {
+		name string
+		cmd  string
+		exp  string
+		err  bool
+	}{
+		{name: "half", cmd: `0.5 fraction`, exp: "1/2\n"},
+		{name: "third", cmd: `0.333333 fraction`, exp: "1/3 (error 3.3e-07)\n"},
+		{name: "pi", cmd: `Pi fraction`, exp: "355/113 (error 2.7e-07)\n"},
+		{name: "pi-bound", cmd: `Pi fraction 10`, exp: "22/7 (error 1.3e-03)\n"},
+		{name: "negative", cmd: `-1.25 fraction`, exp: "-5/4\n"},
+		{name: "integer", cmd: `42 fraction dup`, exp: "42\n"},
+		{name: "zero-bound", cmd: `0.5 fraction 0`, err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			calc := NewCalc()
+			calc.out = out
+			calc.quiet = true
+
+			if !evalCase(t, calc, test.cmd, test.err) {
+				return
+			}
+
+			if out.String() != test.exp {
+				t.Errorf("fraction failed:\n+++  got: %q\n--- want: %q", out.String(), test.exp)
+			}
+		})
+	}
+}
+
+func TestTeach(t *testing.T) {
+	out := &bytes.Buffer{}
+	calc := NewCalc()
+	calc.out = out
+	calc.teach = true
+
+	for _, line := range []string{`80 20 +`, `5`, `x`, `dup`, `2 sqrt 2 ^`} {
+		if err := calc.Eval(line); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	exp := `80 20 + | stack: 100
+5 | stack: 100 5
+100 5 x | stack: 500
+dup | stack: 500 500
+1.4142135623730951 2 ^ | stack: 500 500 2.0000000000000004
+`
+	if out.String() != exp {
+		t.Errorf("teach failed:\n+++  got: %s\n--- want: %s", out.String(), exp)
+	}
+}
+
+func TestJSONDumpLoad(t *testing.T) {
+	out := &bytes.Buffer{}
+	calc := NewCalc()
+	calc.out = out
+
+	if err := calc.Eval(`1 2.5 -3 >X precision 4 jsondump`); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := `{"stack":[1,2.5,-3],"vars":{"X":-3},"precision":4}` + "\n"
+	if out.String() != exp {
+		t.Fatalf("jsondump failed:\n+++  got: %s\n--- want: %s", out.String(), exp)
+	}
+
+	file := filepath.Join(t.TempDir(), "stack.json")
+	if err := os.WriteFile(file, out.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := NewCalc()
+	if err := loaded.Eval(`42 jsonload ` + file); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := loaded.stack.All(); list2str(got) != "1 2.5 -3" {
+		t.Errorf("jsonload failed:\n+++  got: %s\n--- want: %s", list2str(got), "1 2.5 -3")
+	}
+
+	if loaded.Vars["X"] != -3 || loaded.precision != 4 {
+		t.Errorf("jsonload didn't restore vars and precision: %v %d", loaded.Vars, loaded.precision)
+	}
+
+	t.Run("undo", func(t *testing.T) {
+		undone := NewCalc()
+		if err := undone.Eval(`42 jsonload ` + file + ` undo`); err != nil {
+			t.Fatal(err)
+		}
+
+		if got := list2str(undone.stack.All()); got != "42" || len(undone.Vars) != 0 || undone.precision != Precision {
+			t.Errorf("undo after jsonload failed:\n+++  got: %s %v %d\n--- want: 42 map[] %d",
+				got, undone.Vars, undone.precision, Precision)
+		}
+
+		// only the directly following undo reverts the settings
+		if err := undone.Eval(`jsonload ` + file + ` 1 + undo`); err != nil {
+			t.Fatal(err)
+		}
+
+		if undone.Vars["X"] != -3 || undone.precision != 4 {
+			t.Errorf("undo of a later operation reverted jsonload: %v %d", undone.Vars, undone.precision)
+		}
+	})
+
+	// the next line contains a plain array
+	if err := loaded.Eval(`jsonload`); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := loaded.Eval(`[7, 8]`); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := loaded.stack.All(); list2str(got) != "7 8" {
+		t.Errorf("jsonload from next line failed:\n+++  got: %s\n--- want: %s", list2str(got), "7 8")
+	}
+
+	// invalid input must not clobber the stack
+	for _, input := range []string{`[7, "x"]`, `{"stack": [1}`, `{"precision": 99}`} {
+		if err := loaded.Eval(`jsonload`); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := loaded.Eval(input); err == nil {
+			t.Errorf("%s accepted, expected error", input)
+		}
+
+		if got := loaded.stack.All(); list2str(got) != "7 8" {
+			t.Errorf("invalid json %s modified the stack: %s", input, list2str(got))
+		}
+	}
+
+	if err := loaded.Eval(`jsonload ` + file + `.missing`); err == nil {
+		t.Errorf("missing file accepted, expected error")
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_DATA_HOME", "")
+
+	out := &bytes.Buffer{}
+	calc := NewCalcWriter(out)
+
+	if err := calc.Eval(`stacks`); err != nil {
+		t.Fatal(err)
+	}
+
+	if out.String() != "no saved stacks\n" {
+		t.Errorf("stacks failed:\n+++  got: %s\n--- want: %s", out.String(), "no saved stacks")
+	}
+
+	if err := calc.Eval(`1 2.5 -3 >X save budget clear 42 save answer`); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(filepath.Join(home, ".rpn", "stacks", "budget.json")); err != nil {
+		t.Fatalf("stack not saved in ~/.rpn/stacks: %s", err)
+	}
+
+	out.Reset()
+
+	if err := calc.Eval(`stacks`); err != nil {
+		t.Fatal(err)
+	}
+
+	if out.String() != "answer\nbudget\n" {
+		t.Errorf("stacks failed:\n+++  got: %s\n--- want: %s", out.String(), "answer budget")
+	}
+
+	loaded := NewCalcWriter(&bytes.Buffer{})
+
+	if err := loaded.Eval(`7 load budget`); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := loaded.stack.All(); list2str(got) != "1 2.5 -3" || loaded.Vars["X"] != -3 {
+		t.Errorf("load failed:\n+++  got: %s %v\n--- want: %s", list2str(got), loaded.Vars, "1 2.5 -3")
+	}
+
+	if err := loaded.Eval(`undo`); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := loaded.stack.All(); list2str(got) != "7" || len(loaded.Vars) != 0 {
+		t.Errorf("undo after load failed:\n+++  got: %s %v\n--- want: %s", list2str(got), loaded.Vars, "7")
+	}
+
+	// corrupt files, unknown and invalid names must not clobber the stack
+	corrupt := filepath.Join(home, ".rpn", "stacks", "corrupt.json")
+	if err := os.WriteFile(corrupt, []byte(`{"stack": [1`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"corrupt", "missing", "../budget", ".hidden"} {
+		if err := loaded.Eval(`load ` + name); err == nil {
+			t.Errorf("load %s accepted, expected error", name)
+		}
+
+		if got := loaded.stack.All(); list2str(got) != "7" {
+			t.Errorf("load %s modified the stack: %s", name, list2str(got))
+		}
+	}
+
+	t.Run("xdg", func(t *testing.T) {
+		datadir := t.TempDir()
+		t.Setenv("XDG_DATA_HOME", datadir)
+
+		if err := NewCalcWriter(&bytes.Buffer{}).Eval(`5 save five`); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := os.Stat(filepath.Join(datadir, "rpn", "stacks", "five.json")); err != nil {
+			t.Errorf("stack not saved in XDG_DATA_HOME: %s", err)
+		}
+	})
+}
+
+func TestColor(t *testing.T) {
+	var tests = []struct {
+		name  string
+		color bool
+		cmd   string
+		exp   string
+	}{
+		{name: "result", color: true, cmd: `2 3 +`, exp: "= \033[32m5\033[0m\n"},
+		{name: "result-nocolor", cmd: `2 3 +`, exp: "= 5\n"},
+		{name: "stack", color: true, cmd: `2 3 showstack`, exp: "\033[2mstack: 2 3\033[0m\n"},
+		{name: "stack-nocolor", cmd: `2 3 showstack`, exp: "stack: 2 3\n"},
+		{name: "toggle", color: true, cmd: `nocolor 2 3 +`, exp: "= 5\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			calc := NewCalc()
+			calc.out = out
+			calc.color = test.color
+
+			if err := calc.Eval(test.cmd); err != nil {
+				t.Fatal(err)
+			}
+
+			if out.String() != test.exp {
+				t.Errorf("color failed:\n+++  got: %q\n--- want: %q", out.String(), test.exp)
+			}
+		})
+	}
+
+	t.Run("error", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		calc := NewCalcWriter(out)
+		calc.color = true
+
+		calc.PrintError(calc.Eval(`1 0 /`))
+
+		exp := "\033[31mError: division by null\033[0m\n"
+		if out.String() != exp {
+			t.Errorf("error color failed:\n+++  got: %q\n--- want: %q", out.String(), exp)
+		}
+	})
+}
+
+func TestHints(t *testing.T) {
+	calc := NewCalc()
+
+	if err := calc.Eval(`1`); err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		line string
+		hint string
+		ok   bool
+	}{
+		{line: "atan2", hint: "atan2(y, x) — needs 2 args, stack has 1", ok: true},
+		{line: "sqrt", hint: "sqrt(x) — needs 1 arg, stack has 1", ok: true},
+		{line: "median", hint: "median(...) — uses all 1 items of the stack", ok: true},
+		{line: "dup", hint: "dup — " + calc.StackCommands["dup"].Help, ok: true},
+		{line: "42", ok: false},
+		{line: "nonexistent", ok: false},
+		// operands typed before the token count as well
+		{line: "2 atan2", hint: "atan2(y, x) — needs 2 args, stack has 2", ok: true},
+		{line: "2 3 + sqrt", hint: "sqrt(x) — needs 1 arg, stack has 2", ok: true},
+		{line: "2 Pi + 3 atan2", hint: "atan2(y, x) — needs 2 args, stack has 3", ok: true},
+	}
+
+	for _, test := range tests {
+		testname := fmt.Sprintf("hint-%s", test.line)
+
+		t.Run(testname, func(t *testing.T) {
+			hint, ok := calc.Hint(strings.Fields(test.line))
+			if ok != test.ok {
+				t.Fatalf("hint for %s returned %t, expected %t", test.line, ok, test.ok)
+			}
+
+			if hint != test.hint {
+				t.Errorf("hint failed:\n+++  got: %s\n--- want: %s", hint, test.hint)
+			}
+		})
+	}
+
+	t.Run("empty-stack", func(t *testing.T) {
+		hint, _ := NewCalc().Hint([]string{"1", "2", "atan2"})
+		if exp := "atan2(y, x) — needs 2 args, stack has 2"; hint != exp {
+			t.Errorf("hint failed:\n+++  got: %s\n--- want: %s", hint, exp)
+		}
+	})
+}
+
+func TestFinishedToken(t *testing.T) {
+	var tests = []struct {
+		line   string
+		key    rune
+		tokens string
+		ok     bool
+	}{
+		{line: "1 2 atan2 ", key: ' ', tokens: "1 2 atan2", ok: true},
+		{line: "sqrt ", key: ' ', tokens: "sqrt", ok: true},
+		{line: "sqrt  ", key: ' ', ok: false},
+		{line: "sqr", key: 'r', ok: false},
+		{line: " ", key: ' ', ok: false},
+	}
+
+	for _, test := range tests {
+		testname := fmt.Sprintf("finished-%q", test.line)
+
+		t.Run(testname, func(t *testing.T) {
+			line := []rune(test.line)
+
+			tokens, ok := finishedToken(line, len(line), test.key)
+			if ok != test.ok || strings.Join(tokens, " ") != test.tokens {
+				t.Errorf("finishedToken failed:\n+++  got: %v %t\n--- want: %s %t",
+					tokens, ok, test.tokens, test.ok)
+			}
+		})
+	}
+}
+
+func TestNotes(t *testing.T) {
+	out := &bytes.Buffer{}
+	calc := NewCalc()
+	calc.out = out
+
+	for _, line := range []string{`1 2 +`, `note result 3 is the Q3 figure`, `note 42 dup`} {
+		if err := calc.Eval(line); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := list2str(calc.stack.All()); got != "3" {
+		t.Errorf("note modified the stack:\n+++  got: %s\n--- want: 3", got)
+	}
+
+	out.Reset()
+
+	if err := calc.Eval(`notes`); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	want := []string{"result 3 is the Q3 figure", "42 dup"}
+
+	if len(lines) != len(want) {
+		t.Fatalf("notes failed:\n+++  got: %s\n--- want: %d notes", out.String(), len(want))
+	}
+
+	for idx, line := range lines {
+		// 1 2026-01-02 15:04:05 text
+		if !strings.HasPrefix(line, fmt.Sprintf("%d ", idx+1)) || !strings.HasSuffix(line, " "+want[idx]) {
+			t.Errorf("note failed:\n+++  got: %s\n--- want: %s", line, want[idx])
+		}
+	}
+
+	export := &bytes.Buffer{}
+	if err := calc.ExportHistory(export, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(export.String(), "note: 42 dup\n") {
+		t.Errorf("note missing in history export:\n%s", export.String())
+	}
+
+	if err := calc.Eval(`note`); err == nil {
+		t.Errorf("note without text accepted, expected error")
+	}
+}
+
+func TestStackChange(t *testing.T) {
+	var tests = []struct {
+		name string
+		cmd  string
+		exp  string
+	}{
+		{name: "swap", cmd: `1 5 3 swap`, exp: "swap: ... 5 3 -> ... 3 5\n"},
+		{name: "reverse", cmd: `1 2 3 reverse`, exp: "reverse: 1 2 3 -> 3 2 1\n"},
+		{name: "clear", cmd: `1 2 clear`, exp: "clear: 1 2 -> (empty)\n"},
+		{name: "unchanged", cmd: `1 reverse`, exp: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			calc := NewCalc()
+			calc.out = out
+
+			if err := calc.Eval(test.cmd); err != nil {
+				t.Fatal(err)
+			}
+
+			if out.String() != test.exp {
+				t.Errorf("stack change failed:\n+++  got: %q\n--- want: %q", out.String(), test.exp)
+			}
+		})
+	}
+
+	t.Run("quiet", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		calc := NewCalc()
+		calc.out = out
+		calc.quietstack = true
+
+		if err := calc.Eval(`1 2 swap`); err != nil {
+			t.Fatal(err)
+		}
+
+		if out.Len() > 0 {
+			t.Errorf("quiet mode printed %q", out.String())
+		}
+	})
+}
+
+func TestPick(t *testing.T) {
+	var tests = []struct {
+		name string
+		cmd  string
+		exp  []float64
+		err  bool
+	}{
+		{name: "top", cmd: `1 2 3 pick 1`, exp: []float64{1, 2, 3, 3}},
+		{name: "bottom", cmd: `1 2 3 pick 3`, exp: []float64{1, 2, 3, 1}},
+		{name: "then-add", cmd: `1 2 3 pick 2 +`, exp: []float64{1, 2, 5}},
+		{name: "out-of-range", cmd: `1 2 3 pick 4`, err: true},
+		{name: "zero", cmd: `1 pick 0`, err: true},
+		{name: "no-number", cmd: `1 pick x`, err: true},
+		{name: "no-argument", cmd: `1 pick`, err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			calc := NewCalcWriter(&bytes.Buffer{})
+
+			if !evalCase(t, calc, test.cmd, test.err) {
+				return
+			}
+
+			got := calc.stack.All()
+			if list2str(got) != list2str(test.exp) {
+				t.Errorf("pick failed:\n+++  got: %s\n--- want: %s",
+					list2str(got), list2str(test.exp))
+			}
+		})
+	}
+
+	t.Run("undo", func(t *testing.T) {
+		calc := NewCalcWriter(&bytes.Buffer{})
+
+		if err := calc.Eval(`1 2 pick 2 undo`); err != nil {
+			t.Fatal(err)
+		}
+
+		if got := list2str(calc.stack.All()); got != "1 2" {
+			t.Errorf("pick undo failed:\n+++  got: %s\n--- want: %s", got, "1 2")
+		}
+	})
+}
+
+func TestRollCommands(t *testing.T) {
+	var tests = []struct {
+		name string
+		cmd  string
+		exp  string
+		err  bool
+	}{
+		{name: "roll", cmd: `1 2 3 4 5 roll 4`, exp: "1 3 4 5 2"},
+		{name: "roll-top", cmd: `1 2 3 4 5 roll 1`, exp: "1 2 3 4 5"},
+		{name: "roll-bottom", cmd: `1 2 3 4 5 roll 5`, exp: "2 3 4 5 1"},
+		{name: "rot", cmd: `1 2 3 4 5 rot`, exp: "1 2 4 5 3"},
+		{name: "rot-twice", cmd: `1 2 3 4 5 rot rot`, exp: "1 2 5 3 4"},
+		{name: "rot-thrice", cmd: `1 2 3 4 5 rot rot rot`, exp: "1 2 3 4 5"},
+		{name: "roll-undo", cmd: `1 2 3 4 5 roll 5 undo`, exp: "1 2 3 4 5"},
+		{name: "roll-too-large", cmd: `1 2 3 4 5 roll 6`, exp: "1 2 3 4 5", err: true},
+		{name: "roll-invalid", cmd: `1 2 3 4 5 roll x`, exp: "1 2 3 4 5", err: true},
+		{name: "rot-too-small", cmd: `1 2 rot`, exp: "1 2", err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			calc := NewCalcWriter(&bytes.Buffer{})
+
+			evalCase(t, calc, test.cmd, test.err)
+
+			if got := list2str(calc.stack.All()); got != test.exp {
+				t.Errorf("roll failed:\n+++  got: %s\n--- want: %s", got, test.exp)
+			}
+		})
+	}
+}
+
+func TestDupN(t *testing.T) {
+	var tests = []struct {
+		name string
+		cmd  string
+		exp  string
+		err  bool
+	}{
+		{name: "dupn", cmd: `1 2 3 dupn 2`, exp: "1 2 3 2 3"},
+		{name: "dupn-one", cmd: `1 2 3 dupn 1`, exp: "1 2 3 3"},
+		{name: "dupn-one-is-dup", cmd: `1 2 3 dup`, exp: "1 2 3 3"},
+		{name: "dupn-all", cmd: `1 2 3 dupn 3`, exp: "1 2 3 1 2 3"},
+		{name: "dupn-undo", cmd: `1 2 3 dupn 3 undo`, exp: "1 2 3"},
+		{name: "dupn-too-large", cmd: `1 2 3 dupn 4`, exp: "1 2 3", err: true},
+		{name: "dupn-zero", cmd: `1 2 3 dupn 0`, exp: "1 2 3", err: true},
+		{name: "dupn-invalid", cmd: `1 2 3 dupn x`, exp: "1 2 3", err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			calc := NewCalcWriter(&bytes.Buffer{})
+
+			evalCase(t, calc, test.cmd, test.err)
+
+			if got := list2str(calc.stack.All()); got != test.exp {
+				t.Errorf("dupn failed:\n+++  got: %s\n--- want: %s", got, test.exp)
+			}
+		})
+	}
+}
+
+func TestDrop(t *testing.T) {
+	var tests = []struct {
+		name string
+		cmd  string
+		exp  string
+		err  bool
+	}{
+		{name: "drop", cmd: `1 2 3 4 drop 2`, exp: "1 2"},
+		{name: "drop-one", cmd: `1 2 3 4 drop 1`, exp: "1 2 3"},
+		{name: "drop-all", cmd: `1 2 3 4 drop 4`, exp: ""},
+		{name: "drop-zero", cmd: `1 2 3 4 drop 0`, exp: "1 2 3 4"},
+		{name: "drop-zero-keeps-undo", cmd: `1 2 3 4 drop 2 drop 0 undo`, exp: "1 2 3 4"},
+		{name: "drop-undo", cmd: `1 2 3 4 drop 3 undo`, exp: "1 2 3 4"},
+		{name: "shiftn", cmd: `1 2 3 4 shiftn 3`, exp: "1"},
+		{name: "too-big", cmd: `1 2 3 4 drop 5`, exp: "1 2 3 4", err: true},
+		{name: "negative", cmd: `1 2 3 4 drop -1`, exp: "1 2 3 4", err: true},
+		{name: "no-number", cmd: `1 2 3 4 drop x`, exp: "1 2 3 4", err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			calc := NewCalcWriter(&bytes.Buffer{})
+
+			evalCase(t, calc, test.cmd, test.err)
+
+			if got := list2str(calc.stack.All()); got != test.exp {
+				t.Errorf("drop failed:\n+++  got: %s\n--- want: %s", got, test.exp)
+			}
+		})
+	}
+}
+
+func TestDepth(t *testing.T) {
+	var tests = []struct {
+		name  string
+		cmd   string
+		batch bool
+		exp   string
+	}{
+		{name: "depth", cmd: `1 2 3 depth`, exp: "1 2 3 3"},
+		{name: "empty", cmd: `depth`, exp: "0"},
+		{name: "twice", cmd: `1 depth depth`, exp: "1 1 2"},
+		{name: "batch", cmd: `1 2 3 depth`, batch: true, exp: "1 2 3 3"},
+		{name: "batch-sum", cmd: `1 2 3 depth sum`, batch: true, exp: "9"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			calc := NewCalcWriter(&bytes.Buffer{})
+			calc.batch = test.batch
+
+			if err := calc.Eval(test.cmd); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := list2str(calc.stack.All()); got != test.exp {
+				t.Errorf("depth failed:\n+++  got: %s\n--- want: %s", got, test.exp)
+			}
+		})
+	}
+}
+
+func TestOverNip(t *testing.T) {
+	var tests = []struct {
+		name string
+		cmd  string
+		exp  string
+		err  bool
+	}{
+		{name: "over", cmd: `1 2 3 over`, exp: "1 2 3 2"},
+		{name: "over-two", cmd: `1 2 over`, exp: "1 2 1"},
+		{name: "over-twice", cmd: `1 2 over over`, exp: "1 2 1 2"},
+		{name: "over-undo", cmd: `1 2 over undo`, exp: "1 2"},
+		{name: "over-too-small", cmd: `1 over`, exp: "1", err: true},
+		{name: "nip", cmd: `1 2 3 nip`, exp: "1 3"},
+		{name: "nip-two", cmd: `1 2 nip`, exp: "2"},
+		{name: "nip-undo", cmd: `1 2 3 nip undo`, exp: "1 2 3"},
+		{name: "nip-too-small", cmd: `1 nip`, exp: "1", err: true},
+		{name: "over-nip", cmd: `1 2 over nip`, exp: "1 1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			calc := NewCalcWriter(&bytes.Buffer{})
+
+			evalCase(t, calc, test.cmd, test.err)
+
+			if got := list2str(calc.stack.All()); got != test.exp {
+				t.Errorf("%s failed:\n+++  got: %s\n--- want: %s", test.name, got, test.exp)
+			}
+		})
+	}
+}
+
+func TestCheckpoint(t *testing.T) {
+	var tests = []struct {
+		name string
+		cmd  string
+		exp  []float64
+		out  string
+		err  bool
+	}{
+		{
+			name: "rollback",
+			cmd:  `1 2 checkpoint + 3 x rollback`,
+			exp:  []float64{1, 2},
+			out:  "rolled back, 2 items changed\n",
+		},
+		{
+			name: "rollback-twice",
+			cmd:  `1 checkpoint 2 + rollback 5 + rollback`,
+			exp:  []float64{1},
+			out:  "rolled back, 1 items changed\nrolled back, 1 items changed\n",
+		},
+		{
+			name: "not-overwritten-by-operations",
+			cmd:  `4 checkpoint sqrt undo 9 + dup x rollback`,
+			exp:  []float64{4},
+			out:  "rolled back, 1 items changed\n",
+		},
+		{
+			name: "new-checkpoint",
+			cmd:  `1 checkpoint 2 checkpoint 3 rollback`,
+			exp:  []float64{1, 2},
+			out:  "rolled back, 1 items changed\n",
+		},
+		{
+			name: "undo-rollback",
+			cmd:  `1 checkpoint 2 3 rollback undo`,
+			exp:  []float64{1, 2, 3},
+			out:  "rolled back, 2 items changed\n",
+		},
+		{
+			name: "empty-checkpoint",
+			cmd:  `checkpoint 1 2 rollback`,
+			exp:  []float64{},
+			out:  "rolled back, 2 items changed\n",
+		},
+		{
+			name: "no-checkpoint",
+			cmd:  `1 2 rollback`,
+			err:  true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			calc := NewCalcWriter(out)
+			calc.stdin = true
+
+			if !evalCase(t, calc, test.cmd, test.err) {
+				return
+			}
+
+			got := calc.stack.All()
+			if list2str(got) != list2str(test.exp) {
+				t.Errorf("checkpoint failed:\n+++  got: %s\n--- want: %s",
+					list2str(got), list2str(test.exp))
+			}
+
+			if out.String() != test.out {
+				t.Errorf("checkpoint output failed:\n+++  got: %q\n--- want: %q",
+					out.String(), test.out)
+			}
+		})
+	}
+
+	t.Run("prompt", func(t *testing.T) {
+		calc := NewCalcWriter(&bytes.Buffer{})
+
+		if err := calc.Eval(`1 2 checkpoint`); err != nil {
+			t.Fatal(err)
+		}
+
+		if got, exp := calc.Prompt(), "rpn [2*]» "; got != exp {
+			t.Errorf("checkpoint prompt failed:\n+++  got: %q\n--- want: %q", got, exp)
+		}
+	})
+}
+
+func TestReadEditedStack(t *testing.T) {
+	var tests = []struct {
+		name    string
+		content string
+		exp     string
+		skipped int
+		err     bool
+	}{
+		{
+			name:    "unchanged",
+			content: "# comment\n1\n2.5\n0.30000000000000004\n",
+			exp:     "1 2.5 0.30000000000000004",
+		},
+		{
+			name:    "invalid-lines",
+			content: "1\nfoo\n2 # two\n\n3x\n3\n",
+			exp:     "1 2 3",
+			skipped: 2,
+		},
+		{
+			name:    "empty",
+			content: "",
+			err:     true,
+		},
+		{
+			name:    "only-comments",
+			content: "# add or remove numbers as you wish.\n\n",
+			err:     true,
+		},
+		{
+			name:    "all-invalid",
+			content: "# comment\nfoo\nbar\n",
+			err:     true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			calc := NewCalcWriter(&bytes.Buffer{})
+
+			items, skipped, err := calc.ReadEditedStack(strings.NewReader(test.content))
+
+			if test.err {
+				if err == nil {
+					t.Errorf("%q accepted, expected error", test.content)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := list2str(items); got != test.exp || skipped != test.skipped {
+				t.Errorf("read edited stack failed:\n+++  got: %s (%d skipped)\n--- want: %s (%d skipped)",
+					got, skipped, test.exp, test.skipped)
+			}
+		})
+	}
+}
+
+func TestEditAbort(t *testing.T) {
+	// an editor exiting non-zero, like vi after :cq
+	t.Setenv("EDITOR", "false")
+
+	calc := NewCalcWriter(&bytes.Buffer{})
+
+	if err := calc.Eval(`1 2 3`); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := calc.Eval(`edit`); err == nil {
+		t.Errorf("edit accepted, expected error")
+	}
+
+	if got := list2str(calc.stack.All()); got != "1 2 3" {
+		t.Errorf("edit abort failed:\n+++  got: %s\n--- want: %s", got, "1 2 3")
+	}
+}
+
+type testClipboard struct {
+	text string
+	err  error
+}
+
+func (clipboard *testClipboard) Copy(text string) error {
+	clipboard.text = text
+
+	return clipboard.err
+}
+
+func TestCopy(t *testing.T) {
+	var tests = []struct {
+		name      string
+		cmd       string
+		exp       string
+		stdin     bool
+		clipboard error // returned by the clipboard
+		err       bool
+	}{
+		{name: "precision", cmd: `1 3 / copy`, exp: "0.33"},
+		{name: "integer", cmd: `2 3 x copy`, exp: "6"},
+		{name: "sci", cmd: `sci 12345 copy`, exp: "1.23e+04"},
+		{name: "stdin", cmd: `1 copy`, stdin: true, err: true},
+		{name: "empty", cmd: `copy`, err: true},
+		{name: "unavailable", cmd: `1 copy`, clipboard: errors.New("no clipboard available"), err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clipboard := &testClipboard{err: test.clipboard}
+			calc := NewCalc()
+			calc.out = &bytes.Buffer{}
+			calc.stdin = test.stdin
+			calc.clipboard = clipboard
+
+			if !evalCase(t, calc, test.cmd, test.err) {
+				return
+			}
+
+			if clipboard.text != test.exp {
+				t.Errorf("copy failed:\n+++  got: %s\n--- want: %s", clipboard.text, test.exp)
+			}
+		})
+	}
+}
+
+// The tokenizer spec: how single items are classified and which value
+// (or error) they produce, with the default locale
+func TestTokenizer(t *testing.T) {
+	var tests = []struct {
+		item string
+		kind string
+		exp  float64
+		err  string
+	}{
+		{item: "5", kind: "number", exp: 5},
+		{item: "+5", kind: "number", exp: 5},
+		{item: "-5", kind: "number", exp: -5},
+		{item: "5.", kind: "number", exp: 5},
+		{item: ".5", kind: "number", exp: 0.5},
+		{item: "-.5", kind: "number", exp: -0.5},
+		{item: "+.5", kind: "number", exp: 0.5},
+		{item: "5.e2", kind: "number", exp: 500},
+		{item: ".5e2", kind: "number", exp: 50},
+		{item: "1e3", kind: "number", exp: 1000},
+		{item: "1_000", kind: "number", exp: 1000},
+		{item: "1,000", kind: "number", exp: 1000},
+		{item: "1,000,000.5", kind: "number", exp: 1000000.5},
+		{item: "0xDEAD_BEEF", kind: "number", exp: 0xDEADBEEF},
+		{item: "5e", kind: "number", err: "malformed number: 5e"},
+		{item: "5e+", kind: "number", err: "malformed number: 5e+"},
+		{item: "5..", kind: "number", err: "malformed number: 5.."},
+		{item: "1.2.3", kind: "number", err: "malformed number: 1.2.3"},
+		{item: "5x", kind: "number", err: "malformed number: 5x"},
+		{item: "5,5", kind: "number", err: "malformed number: 5,5 (misplaced thousands separator)"},
+		{item: "1,0000", kind: "number", err: "malformed number: 1,0000 (misplaced thousands separator)"},
+		{item: "5_", kind: "number", err: "malformed number: 5_ (misplaced digit separator)"},
+		{item: "0b1,0", kind: "number", err: "malformed number: 0b1,0 (misplaced thousands separator)"},
+		{item: "--5", kind: "number", err: "malformed number: --5"},
+		{item: "+-5", kind: "number", err: "malformed number: +-5"},
+		{item: "-+.5", kind: "number", err: "malformed number: -+.5"},
+		{item: "inf", kind: "number", err: "result is not a number"},
+		{item: "+", kind: "function"},
+		{item: "-", kind: "function"},
+		{item: "e", kind: "constant", exp: math.E},
+		{item: "E", kind: "constant", exp: math.E},
+		{item: ".", kind: "", err: "unknown command or operator"},
+		{item: "_5", kind: "", err: "unknown command or operator"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.item, func(t *testing.T) {
+			calc := NewCalc()
+			calc.out = &bytes.Buffer{}
+
+			kind, _ := calc.Classify(test.item)
+			if kind.Name != test.kind {
+				t.Errorf("%s classified as %q, expected %q", test.item, kind.Name, test.kind)
+			}
+
+			if test.kind == "function" {
+				return
+			}
+
+			err := calc.Eval(test.item)
+
+			if test.err != "" {
+				if err == nil || !strings.HasSuffix(err.Error(), test.err) {
+					t.Errorf("%s: error:\n+++  got: %v\n--- want: %s", test.item, err, test.err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := calc.stack.Last()[0]; got != test.exp {
+				t.Errorf("%s: value:\n+++  got: %g\n--- want: %g", test.item, got, test.exp)
+			}
+		})
+	}
+
+	t.Run("comment", func(t *testing.T) {
+		// the comment is removed before the line is split into items
+		calc := NewCalc()
+
+		if err := calc.Eval(`.5#x`); err != nil {
+			t.Fatal(err)
+		}
+
+		if got := list2str(calc.stack.All()); got != "0.5" {
+			t.Errorf(".5#x failed:\n+++  got: %s\n--- want: 0.5", got)
+		}
+	})
+}
+
+func TestShowStackSize(t *testing.T) {
+	var tests = []struct {
+		name string
+		cmd  string
+		exp  string
+		err  bool
+	}{
+		// a number directly following showstack is the size
+		{name: "default", cmd: `showstack`, exp: "stack: ... 2 3 4 5 6\n"},
+		{name: "two", cmd: `showstack 2`, exp: "stack: ... 5 6\n"},
+		{name: "ten", cmd: `showstack 10`, exp: "stack: 1 2 3 4 5 6\n"},
+		{name: "exact", cmd: `showstack 6`, exp: "stack: 1 2 3 4 5 6\n"},
+		{name: "zero", cmd: `showstack 0`, err: true},
+		{name: "negative", cmd: `showstack -1`, err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			calc := NewCalc()
+			calc.out = out
+
+			if !evalCase(t, calc, test.cmd, test.err) {
+				if calc.showstacklen != ShowStackLen {
+					t.Errorf("stack size changed to %d", calc.showstacklen)
+				}
+
+				return
+			}
+
+			out.Reset()
+
+			if err := calc.Eval(`1 2 3 4 5 6`); err != nil {
+				t.Fatal(err)
+			}
+
+			if out.String() != test.exp {
+				t.Errorf("showstack failed:\n+++  got: %q\n--- want: %q", out.String(), test.exp)
+			}
+		})
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	var tests = []struct {
+		item string
+		exp  string
+	}{
+		{item: "mile-to-kilometers", exp: "miles-to-kilometers"},
+		{item: "miles-to-kilometer", exp: "miles-to-kilometers"},
+		{item: "mile-to-kilometer", exp: "miles-to-kilometers"},
+		{item: "kilometer-to-mile", exp: "kilometers-to-miles"},
+		{item: "gallon-to-liter", exp: "gallons-to-liters"},
+		{item: "inches-to-cm", exp: "inch-to-cm"},
+		{item: "metres-to-yards", exp: "meters-to-yards"},
+		{item: "parsecs-to-lightyears", exp: ""},
+		{item: "miles-to-parsecs", exp: ""},
+		{item: "miles-to-yards", exp: ""}, // both units exist, the conversion doesn't
+		{item: "sqr", exp: "sqrt"},
+		{item: "dumb", exp: "dump"},
+		{item: "xyzzy", exp: ""},
+	}
+
+	calc := NewCalc()
+
+	for _, test := range tests {
+		t.Run(test.item, func(t *testing.T) {
+			got, ok := calc.Suggest(test.item)
+			if ok != (test.exp != "") || got != test.exp {
+				t.Errorf("suggest failed:\n+++  got: %s\n--- want: %s", got, test.exp)
+			}
+		})
+	}
+
+	t.Run("error", func(t *testing.T) {
+		err := calc.Eval(`10 mile-to-kilometers`)
+
+		exp := "did you mean miles-to-kilometers?"
+		if err == nil || !strings.HasSuffix(err.Error(), exp) {
+			t.Errorf("error failed:\n+++  got: %v\n--- want: %s", err, exp)
+		}
+	})
+}
+
+func TestPromptTemplate(t *testing.T) {
+	var tests = []struct {
+		name     string
+		template string
+		batch    bool
+		debug    bool
+		stack    []float64
+		exp      string
+	}{
+		{name: "default", exp: "rpn [0]» "},
+		{name: "top", template: "{stack} {top} » ", stack: []float64{1, 2.5}, exp: "2 2.5 » "},
+		{name: "empty", template: "{stack} {top} » ", exp: "0  » "},
+		{name: "flags", template: "[{batch}|{debug}|{rev}] ", batch: true, debug: true,
+			stack: []float64{1}, exp: "[batch|debug|1] "},
+		{name: "modes", template: "calc{modes}{arrow} ", batch: true, exp: "calc->batch» "},
+		{name: "invalid", template: "{nonsense} ", stack: []float64{1}, exp: "rpn [1]» "},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			calc := NewCalc()
+			calc.prompt = test.template
+			calc.batch = test.batch
+			calc.debug = test.debug
+
+			for _, item := range test.stack {
+				calc.stack.Backup()
+				calc.stack.Push(item)
+			}
+
+			if got := calc.Prompt(); got != test.exp {
+				t.Errorf("prompt failed:\n+++  got: %q\n--- want: %q", got, test.exp)
+			}
+		})
+	}
+
+	t.Run("command", func(t *testing.T) {
+		calc := NewCalc()
+
+		if err := calc.Eval(`prompt "{stack} {top} » "`); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := calc.Eval(`3`); err != nil {
+			t.Fatal(err)
+		}
+
+		exp := "1 3 » "
+		if got := calc.Prompt(); got != exp {
+			t.Errorf("prompt failed:\n+++  got: %q\n--- want: %q", got, exp)
+		}
+
+		for _, template := range []string{`prompt {foo}`, `prompt {stack`, "prompt \033[1m"} {
+			if err := calc.Eval(template); err == nil {
+				t.Errorf("%s accepted, expected error", template)
+			}
+		}
+
+		if err := calc.Eval(`prompt default`); err != nil {
+			t.Fatal(err)
+		}
+
+		exp = "rpn [1]» "
+		if got := calc.Prompt(); got != exp {
+			t.Errorf("prompt failed:\n+++  got: %q\n--- want: %q", got, exp)
+		}
+	})
+
+	t.Run("lua", func(t *testing.T) {
+		script := filepath.Join(t.TempDir(), "prompt.lua")
+		code := `
+prompt = "[{stack}] "
+
+function init()
+end
+`
+		if err := os.WriteFile(script, []byte(code), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		LuaInterpreter = lua.NewState(lua.Options{SkipOpenLibs: true})
+		defer LuaInterpreter.Close()
+
+		luarunner := NewInterpreter(script, false)
+		luarunner.InitLua()
+
+		calc := NewCalc()
+		calc.SetInt(luarunner)
+
+		exp := "[0] "
+		if got := calc.Prompt(); got != exp {
+			t.Errorf("prompt failed:\n+++  got: %q\n--- want: %q", got, exp)
+		}
+	})
+
+	t.Run("lua-function", func(t *testing.T) {
+		script := filepath.Join(t.TempDir(), "prompt.lua")
+		code := `
+function prompt(state)
+  if state.depth > 2 then
+    return "deep " .. state.depth .. " " .. state.stack[1] .. ".." .. state.top .. "> "
+  end
+
+  if state.batch then
+    error("boom")
+  end
+
+  if state.debug then
+    return 42
+  end
+
+  return state.depth .. "> "
+end
+
+function init()
+end
+`
+		if err := os.WriteFile(script, []byte(code), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		LuaInterpreter = lua.NewState(lua.Options{SkipOpenLibs: true})
+		defer LuaInterpreter.Close()
+
+		luarunner := NewInterpreter(script, false)
+		luarunner.InitLua()
+
+		errout := &bytes.Buffer{}
+		calc := NewCalcWriter(&bytes.Buffer{})
+		calc.errout = errout
+		calc.SetInt(luarunner)
+
+		for _, step := range []struct {
+			cmd string
+			exp string
+		}{
+			{cmd: ``, exp: "0> "},
+			{cmd: `1 2`, exp: "2> "},
+			{cmd: `3`, exp: "deep 3 1..3> "},
+			// failing functions fall back to the template
+			{cmd: `clear batch`, exp: "rpn->batch [0]» "},
+			{cmd: `batch debug`, exp: "rpn->debug [0/rev3]» "},
+		} {
+			if err := calc.Eval(step.cmd); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := ColorSequence.ReplaceAllString(calc.Prompt(), ""); got != step.exp {
+				t.Errorf("prompt after %s failed:\n+++  got: %q\n--- want: %q", step.cmd, got, step.exp)
+			}
+		}
+
+		// reported only once
+		if got := strings.Count(errout.String(), "using the default prompt"); got != 1 {
+			t.Errorf("lua prompt errors reported %d times:\n%s", got, errout.String())
+		}
+
+		if !strings.Contains(errout.String(), "boom") {
+			t.Errorf("lua prompt error not reported:\n%s", errout.String())
+		}
+	})
+}
+
+func TestOutput(t *testing.T) {
+	var tests = []struct {
+		name string
+		cmd  string
+		exp  []string
+	}{
+		{name: "result", cmd: `1 2 +`, exp: []string{"= 3\n"}},
+		{name: "vars", cmd: `5 >X vars`, exp: []string{"VARIABLE", "X", "-> 5.00"}},
+		{name: "novars", cmd: `vars`, exp: []string{"no vars registered"}},
+		{name: "history", cmd: `1 2 + history`, exp: []string{"1 2 + -> 3.000000"}},
+		{name: "help", cmd: `help`, exp: []string{"Available configuration commands:", "Batch functions:"}},
+		{name: "debug", cmd: `debug 1 >X`, exp: []string{"DEBUG(calc): register 1.00 in X"}},
+		{name: "dump", cmd: `1 2 dump`, exp: []string{"#1: 2 <- top"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			calc := NewCalcWriter(out)
+
+			if err := calc.Eval(test.cmd); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, exp := range test.exp {
+				if !strings.Contains(out.String(), exp) {
+					t.Errorf("output failed:\n+++  got: %s\n--- want: %s", out.String(), exp)
+				}
+			}
+		})
+	}
+
+	t.Run("error", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		calc := NewCalcWriter(out)
+
+		calc.PrintError(calc.Eval(`foo`))
+
+		exp := "Error: unknown command or operator\n"
+		if out.String() != exp {
+			t.Errorf("error failed:\n+++  got: %q\n--- want: %q", out.String(), exp)
+		}
+	})
+}
+
+func TestExpansionBudget(t *testing.T) {
+	var tests = []struct {
+		name  string
+		cmd   string
+		err   string
+		stack string // restored to the state before repeat
+	}{
+		{name: "within", cmd: `0 10 repeat 200 repeat 1 +`},
+		{name: "single", cmd: `0 20000 repeat 1 +`},
+		{name: "nested", cmd: `0 100 repeat 200 repeat 1 +`,
+			err: "expansion budget of 10000 items exceeded in repeat > repeat", stack: "0 100"},
+		{name: "raised", cmd: `maxexpand 100000 0 100 repeat 200 repeat 1 +`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			calc := NewCalcWriter(&bytes.Buffer{})
+
+			err := calc.Eval(test.cmd)
+
+			if test.err == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				return
+			}
+
+			if err == nil || !strings.HasSuffix(err.Error(), test.err) {
+				t.Errorf("budget failed:\n+++  got: %v\n--- want: %s", err, test.err)
+			}
+
+			if got := list2str(calc.stack.All()); got != test.stack {
+				t.Errorf("stack not restored:\n+++  got: %s\n--- want: %s", got, test.stack)
+			}
+
+			// the budget is per line
+			if err := calc.Eval(`10 repeat 1 +`); err != nil {
+				t.Errorf("budget not reset: %s", err)
+			}
+		})
+	}
+
+	calc := NewCalc()
+	if err := calc.Eval(`maxexpand 0`); err == nil {
+		t.Errorf("maxexpand 0 accepted, expected error")
+	}
+}
+
+// expansions evaluating themselves, directly or via each other, like a
+// careless macro definition would
+func TestExpansionRecursion(t *testing.T) {
+	var tests = []struct {
+		name  string
+		calls map[string]string // expansion -> line it evaluates
+		cmd   string
+		chain string
+	}{
+		{name: "direct", calls: map[string]string{"a": "1 a"}, cmd: "a", chain: "a > a > a"},
+		{name: "mutual", calls: map[string]string{"a": "1 b", "b": "2 a"}, cmd: "a", chain: "a > b > a"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			calc := NewCalcWriter(&bytes.Buffer{})
+			calc.maxexpand = 100
+
+			for name, line := range test.calls {
+				calc.Commands[name] = NewArgCommand("test expansion", 0, func(c *Calc, _ []string) error {
+					c.BeginExpansion(name)
+					defer c.EndExpansion()
+
+					return c.Eval(line)
+				})
+			}
+
+			err := calc.Eval(test.cmd)
+			if err == nil {
+				t.Fatalf("%s accepted, expected error", test.cmd)
+			}
+
+			if !strings.Contains(err.Error(), "expansion budget of 100 items exceeded in "+test.chain) {
+				t.Errorf("recursion failed:\n+++  got: %s\n--- want: %s", err, test.chain)
+			}
+		})
+	}
+}
+
+func TestTee(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	calc := NewCalcWriter(&bytes.Buffer{})
+
+	for _, line := range []string{`1 2 +`, `tee ~/results.txt`, `3 4 x`, `10 dup`, `2 /`, `notee`, `5 5 +`} {
+		if err := calc.Eval(line); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := os.ReadFile(filepath.Join(os.Getenv("HOME"), "results.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := "3 4 x = 12\n2 / = 5\n"
+	if string(got) != exp {
+		t.Errorf("tee failed:\n+++  got: %q\n--- want: %q", string(got), exp)
+	}
+
+	if err := calc.Eval(`tee /nonexistent/dir/file`); err == nil {
+		t.Errorf("tee into a nonexistent directory accepted, expected error")
+	}
+}
+
+type failingWriter struct {
+	writes int
+}
+
+func (writer *failingWriter) Write([]byte) (int, error) {
+	writer.writes++
+
+	return 0, errors.New("disk full")
+}
+
+func (writer *failingWriter) Close() error {
+	return nil
+}
+
+func TestTeeError(t *testing.T) {
+	out := &bytes.Buffer{}
+	writer := &failingWriter{}
+	calc := NewCalcWriter(out)
+	calc.tee = writer
+
+	for _, line := range []string{`1 2 +`, `3 +`} {
+		if err := calc.Eval(line); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if writer.writes != 1 || strings.Count(out.String(), "disk full") != 1 {
+		t.Errorf("tee error reported more than once:\n%s", out.String())
+	}
+}
+
+func TestDivisionRounding(t *testing.T) {
+	var tests = []struct {
+		cmd string
+		exp float64
+		err bool
+	}{
+		{cmd: `45 10 ceildiv`, exp: 5},
+		{cmd: `40 10 ceildiv`, exp: 4},
+		{cmd: `-45 10 ceildiv`, exp: -4},
+		{cmd: `45 -10 ceildiv`, exp: -4},
+		{cmd: `45 0 ceildiv`, err: true},
+		{cmd: `45 10 floordiv`, exp: 4},
+		{cmd: `-45 10 floordiv`, exp: -5},
+		{cmd: `45 10 //`, exp: 4},
+		{cmd: `45 0 //`, err: true},
+		{cmd: `17 5 roundmult`, exp: 15},
+		{cmd: `18 5 roundmult`, exp: 20},
+		{cmd: `17.5 5 roundmult`, exp: 20},
+		{cmd: `-17.5 5 roundmult`, exp: -20},
+		{cmd: `-17 5 roundmult`, exp: -15},
+		{cmd: `1.3 0.5 roundmult`, exp: 1.5},
+		{cmd: `17 0 roundmult`, err: true},
+		{cmd: `17 -5 roundmult`, err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.cmd, func(t *testing.T) {
+			calc := NewCalcWriter(&bytes.Buffer{})
+
+			if !evalCase(t, calc, test.cmd, test.err) {
+				return
+			}
+
+			if got := calc.stack.Last()[0]; got != test.exp {
+				t.Errorf("%s failed:\n+++  got: %f\n--- want: %f", test.cmd, got, test.exp)
+			}
+		})
+	}
+}
+
+func TestBitfields(t *testing.T) {
+	var tests = []struct {
+		name string
+		cmd  string
+		exp  float64
+		err  bool
+	}{
+		// a 16550 UART line control register: word length in bits 0-1,
+		// stop bits in bit 2, parity in bits 3-5, DLAB in bit 7
+		{name: "lcr-wordlength", cmd: `0x9b 0 2 extract`, exp: 3},
+		{name: "lcr-stopbits", cmd: `0x9b 2 1 extract`, exp: 0},
+		{name: "lcr-parity", cmd: `0x9b 3 3 extract`, exp: 3},
+		{name: "lcr-dlab", cmd: `0x9b 7 1 extract`, exp: 1},
+		{name: "lcr-clear-dlab", cmd: `0x9b 0 7 1 deposit`, exp: 0x1b},
+		{name: "lcr-set-stopbits", cmd: `0x9b 1 2 1 deposit`, exp: 0x9f},
+		// an ARM instruction: condition code in the top 4 bits
+		{name: "arm-cond", cmd: `0xe3a01005 28 4 extract`, exp: 0xe},
+		{name: "arm-rd", cmd: `0xe3a01005 12 4 extract`, exp: 1},
+		{name: "arm-set-rd", cmd: `0xe3a01005 7 12 4 deposit`, exp: 0xe3a07005},
+		{name: "whole-word", cmd: `0xff 0 64 extract`, exp: 0xff},
+		{name: "negative", cmd: `-1 60 4 extract`, exp: 0xf},
+		{name: "negative-deposit", cmd: `-1 0 0 32 deposit`, exp: 0xffffffff00000000},
+		{name: "inexact", cmd: `-1 0 0 1 deposit`, err: true},
+		{name: "top-bits", cmd: `0 0xf 60 4 deposit`, exp: 0xf000000000000000},
+		{name: "too-wide", cmd: `0xff 60 8 extract`, err: true},
+		{name: "zero-length", cmd: `0xff 0 0 extract`, err: true},
+		{name: "negative-offset", cmd: `0xff -1 4 extract`, err: true},
+		{name: "fraction", cmd: `1.5 0 4 extract`, err: true},
+		{name: "field-too-large", cmd: `0 0x10 0 4 deposit`, err: true},
+		{name: "stack-too-small", cmd: `1 2 3 deposit`, err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			calc := NewCalcWriter(&bytes.Buffer{})
+
+			if !evalCase(t, calc, test.cmd, test.err) {
+				return
+			}
+
+			if got := calc.stack.Last()[0]; got != test.exp {
+				t.Errorf("%s failed:\n+++  got: %f\n--- want: %f", test.cmd, got, test.exp)
+			}
+		})
+	}
+
+	t.Run("history", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		calc := NewCalcWriter(out)
+		calc.progmode = true
+
+		if err := calc.Eval(`0x9b 0 7 1 deposit 3 +`); err != nil {
+			t.Fatal(err)
+		}
+
+		exp := []string{"0x9b 0x0 0x7 0x1 deposit", "27 3 +"}
+
+		for pos, entry := range calc.history {
+			if entry.Text != exp[pos] {
+				t.Errorf("history failed:\n+++  got: %s\n--- want: %s", entry.Text, exp[pos])
+			}
+		}
+	})
+}
+
+func TestProgmode(t *testing.T) {
+	var tests = []struct {
+		name  string
+		setup string
+		cmd   string
+		exp   string
+	}{
+		{name: "integer", cmd: `0xf0 0x0f or`, exp: "255 (0xff, 0b11111111)"},
+		{name: "zero", cmd: `1 1 -`, exp: "0 (0x0, 0b0)"},
+		{name: "negative", cmd: `0 10 -`, exp: "-10 (-0xa, -0b1010)"},
+		{name: "fraction", cmd: `1 4 /`, exp: "0.25"},
+		{name: "precision", setup: `precision 4`, cmd: `10 4 /`, exp: "2.5000"},
+		{name: "sci", setup: `sci`, cmd: `4096 1 x`, exp: "4.10e+03 (0x1000, 0b1000000000000)"},
+		{name: "upper", setup: `hexupper`, cmd: `250 1 x`, exp: "250 (0xFA, 0b11111010)"},
+		{name: "out-of-range", cmd: `2 64 ^`, exp: "18446744073709551616"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			calc := NewCalcWriter(out)
+			calc.stdin = true
+			calc.progmode = true
+
+			if err := calc.Eval(test.setup); err != nil {
+				t.Fatal(err)
+			}
+
+			out.Reset()
+
+			if err := calc.Eval(test.cmd); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := strings.TrimSpace(out.String()); got != test.exp {
+				t.Errorf("progmode failed:\n+++  got: %s\n--- want: %s", got, test.exp)
+			}
+		})
+	}
+
+	t.Run("prompt", func(t *testing.T) {
+		calc := NewCalc()
+		calc.progmode = true
+
+		exp := "rpn->prog [0]» "
+		if got := calc.Prompt(); got != exp {
+			t.Errorf("prompt failed:\n+++  got: %q\n--- want: %q", got, exp)
+		}
+	})
+}
+
+func TestOverflowMessages(t *testing.T) {
+	var tests = []struct {
+		name string
+		cmd  string
+		exp  float64
+		err  string
+	}{
+		{name: "factorial", cmd: `5 factorial`, exp: 120},
+		{name: "factorial-zero", cmd: `0 !`, exp: 1},
+		{name: "factorial-max", cmd: `170 factorial 1e306 /`, exp: 7.257415615307994},
+		{name: "factorial-overflow", cmd: `171 factorial`,
+			err: "Error: argument 171 exceeds float64 range (max 170)"},
+		{name: "factorial-huge", cmd: `1e17 factorial`,
+			err: "Error: argument 1e+17 exceeds float64 range (max 170)"},
+		{name: "factorial-fraction", cmd: `2.5 factorial`,
+			err: "Error: factorial needs a non-negative integer"},
+		{name: "exp-overflow", cmd: `710 exp`,
+			err: "Error: exponent 710 exceeds float64 range (limit 709.783)"},
+		{name: "exp2-overflow", cmd: `1024 exp2`,
+			err: "Error: exponent 1024 exceeds float64 range (limit 1024)"},
+		{name: "expm1-overflow", cmd: `800 expm1`,
+			err: "Error: exponent 800 exceeds float64 range (limit 709.783)"},
+		{name: "exp-underflow", cmd: `-800 exp`, exp: 0},
+		{name: "gamma", cmd: `5 gamma`, exp: 24},
+		{name: "gamma-overflow", cmd: `172 gamma`,
+			err: "Error: argument 172 exceeds float64 range (max 171.62)"},
+		{name: "gamma-pole", cmd: `-2 gamma`,
+			err: "Error: gamma is not defined for -2 (pole)"},
+		{name: "pow-overflow", cmd: `10 309 pow`,
+			err: "Error: 10^309 exceeds float64 range (max 1.7976931348623157e+308)"},
+		{name: "power-overflow", cmd: `-10 309 ^`,
+			err: "Error: -10^309 exceeds float64 range (max 1.7976931348623157e+308)"},
+		{name: "pow-zero", cmd: `0 -1 pow`,
+			err: "Error: 0 raised to the negative power -1 is infinite"},
+		{name: "allownan", cmd: `allownan 10 309 pow`, exp: math.Inf(1)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			calc := NewCalcWriter(&bytes.Buffer{})
+
+			if !evalCaseError(t, calc, test.cmd, test.err) {
+				return
+			}
+
+			if got := calc.stack.Last()[0]; got != test.exp && math.Abs(got-test.exp) > 1e-9 {
+				t.Errorf("%s failed:\n+++  got: %f\n--- want: %f", test.cmd, got, test.exp)
+			}
+		})
+	}
+}
+
+func TestEvery(t *testing.T) {
+	var tests = []struct {
+		name  string
+		every int
+		drop  bool
+		exp   string
+	}{
+		{name: "partial", every: 100, exp: "50.5\n150.5\n225.5\n"},
+		{name: "drop-partial", every: 100, drop: true, exp: "50.5\n150.5\n"},
+		{name: "exact", every: 125, exp: "63\n188\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			calc := NewCalcWriter(out)
+			calc.stdin = true
+			calc.precision = 1
+			calc.every = test.every
+			calc.droppartial = test.drop
+			calc.paragraphop = calc.TrailingOperator([]string{"mean"})
+
+			for value := 1; value <= 250; value++ {
+				if err := calc.Eval(fmt.Sprint(value)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := calc.EndLastWindow(); err != nil {
+				t.Fatal(err)
+			}
+
+			if out.String() != test.exp {
+				t.Errorf("every %d failed:\n+++  got: %s\n--- want: %s", test.every, out.String(), test.exp)
+			}
+
+			if calc.stack.Len() != 0 {
+				t.Errorf("stack not cleared after the last window: %s", list2str(calc.stack.All()))
+			}
+		})
+	}
+}
+
+func TestSession(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "rpn", "session.json")
+
+	// first session
+	calc := NewCalcWriter(&bytes.Buffer{})
+
+	if err := calc.LoadSession(filename); err != nil {
+		t.Fatalf("missing session not tolerated: %s", err)
+	}
+
+	if err := calc.Eval(`note checked`); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := calc.Eval(`1 2 + 5 >X exit 9`); err != nil {
+		t.Fatal(err)
+	}
+
+	if !calc.quit {
+		t.Errorf("exit didn't end the session")
+	}
+
+	if err := calc.SaveSession(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	// second session continues where the first one ended
+	restored := NewCalcWriter(&bytes.Buffer{})
+
+	if err := restored.LoadSession(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := restored.stack.All(); list2str(got) != "3 5" {
+		t.Errorf("session stack failed:\n+++  got: %s\n--- want: %s", list2str(got), "3 5")
+	}
+
+	if restored.Vars["X"] != 5 {
+		t.Errorf("session vars failed: %v", restored.Vars)
+	}
+
+	if len(restored.history) != 2 || restored.history[1].Text != "1 2 +" {
+		t.Errorf("session history failed: %v", restored.history)
+	}
+
+	if len(restored.notes) != 1 || restored.notes[0].Text != "checked" {
+		t.Errorf("session notes failed: %v", restored.notes)
+	}
+
+	// broken files are reported and leave the calculator alone
+	for _, content := range []string{`{"version": 1, "stack": [1`, `{"version": 99, "stack": [1]}`} {
+		if err := os.WriteFile(filename, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		fresh := NewCalcWriter(&bytes.Buffer{})
+
+		if err := fresh.LoadSession(filename); err == nil {
+			t.Errorf("session %s accepted, expected error", content)
+		}
+
+		if fresh.stack.Len() != 0 {
+			t.Errorf("session %s modified the stack: %s", content, list2str(fresh.stack.All()))
+		}
+	}
+}
+
+func TestSettings(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	filename := filepath.Join(t.TempDir(), "rpnrc")
+	out := &bytes.Buffer{}
+	calc := NewCalcWriter(out)
+
+	if err := calc.Eval(`precision 4 showstack sci prompt "» "`); err != nil {
+		t.Fatal(err)
+	}
+
+	// only the changed settings
+	count, err := calc.SaveSettings(filename, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if count != 4 {
+		t.Errorf("saved %d settings, expected 4", count)
+	}
+
+	// unknown settings and invalid values are ignored
+	file, err := os.OpenFile(filename, os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fmt.Fprintln(file, "degrees = true\nmaxiter = -1")
+	file.Close()
+
+	loaded := NewCalcWriter(out)
+	out.Reset()
+
+	warnings := &bytes.Buffer{}
+	loaded.errout = warnings
+
+	if err := loaded.LoadSettings(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, setting := range Settings {
+		if got, exp := setting.Get(loaded), setting.Get(calc); got != exp {
+			t.Errorf("setting %s failed:\n+++  got: %s\n--- want: %s", setting.Name, got, exp)
+		}
+	}
+
+	exp := "ignoring unknown setting degrees in " + filename + "\n" +
+		"ignoring setting maxiter in " + filename + ": invalid number of iterations -1\n"
+	if warnings.String() != exp {
+		t.Errorf("warnings failed:\n+++  got: %s\n--- want: %s", warnings.String(), exp)
+	}
+
+	if out.Len() != 0 {
+		t.Errorf("warnings printed with the results: %s", out.String())
+	}
+
+	if err := calc.Eval(`savesettings nosuchsetting`); err == nil {
+		t.Errorf("unknown setting accepted, expected error")
+	}
+}
+
+func TestRelaxedCase(t *testing.T) {
+	var tests = []struct {
+		name      string
+		mode      string
+		constants []string
+		cmd       string
+		exp       float64
+		err       string
+	}{
+		{name: "exact", cmd: `Pi`, exp: math.Pi},
+		{name: "lower", cmd: `pi`, exp: math.Pi},
+		{name: "upper", cmd: `PI`, exp: math.Pi},
+		{name: "mixed", cmd: `sQrT2`, exp: math.Sqrt2},
+		{name: "functions-default", cmd: `16 SQRT`, err: "Error: unknown command or operator"},
+		{name: "functions-all", mode: RelaxedCaseAll, cmd: `16 SQRT`, exp: 4},
+		{name: "batch-functions-all", mode: RelaxedCaseAll, cmd: `batch 1 2 3 Sum`, exp: 6},
+		{name: "off", mode: RelaxedCaseOff, cmd: `pi`, err: "Error: unknown command or operator"},
+		{name: "off-exact", mode: RelaxedCaseOff, cmd: `Pi`, exp: math.Pi},
+		{name: "ambiguous", constants: []string{"Tau", "TAU"}, cmd: `tau`,
+			err: "Error: ambiguous tau, candidates: TAU, Tau"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			calc := NewCalcWriter(&bytes.Buffer{})
+			calc.Constants = append(calc.Constants, test.constants...)
+
+			if test.mode != "" {
+				if err := calc.Eval(`relaxedcase ` + test.mode); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if !evalCaseError(t, calc, test.cmd, test.err) {
+				return
+			}
+
+			if got := calc.stack.Last()[0]; got != test.exp {
+				t.Errorf("%s failed:\n+++  got: %f\n--- want: %f", test.cmd, got, test.exp)
+			}
+		})
+	}
+}
+
+func TestKeepBatchFunctions(t *testing.T) {
+	var tests = []struct {
+		name string
+		cmd  string
+		exp  Numbers
+		err  bool
+	}{
+		{name: "sum", cmd: `1 2 3 sum!`, exp: Numbers{1, 2, 3, 6}},
+		{name: "alias", cmd: `1 2 3 +!`, exp: Numbers{1, 2, 3, 6}},
+		{name: "mean-includes-sum", cmd: `1 2 3 sum! mean!`, exp: Numbers{1, 2, 3, 6, 3}},
+		{name: "shift-between", cmd: `1 2 3 sum! shift mean!`, exp: Numbers{1, 2, 3, 2}},
+		{name: "batch-mode", cmd: `batch 1 2 3 max! sum`, exp: Numbers{9}},
+		{name: "undo", cmd: `1 2 3 median! undo`, exp: Numbers{1, 2, 3}},
+		{name: "lastx", cmd: `1 2 3 sum! lastx`, exp: Numbers{1, 2, 3, 6, 3}},
+		{name: "empty", cmd: `sum!`, err: true},
+		{name: "empty-batch", cmd: `batch min`, err: true},
+		{name: "no-batch-function", cmd: `1 sqrt!`, err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			calc := NewCalcWriter(&bytes.Buffer{})
+
+			if !evalCase(t, calc, test.cmd, test.err) {
+				return
+			}
+
+			if got := calc.stack.All(); list2str(got) != list2str(test.exp) {
+				t.Errorf("%s failed:\n+++  got: %s\n--- want: %s",
+					test.cmd, list2str(got), list2str(test.exp))
+			}
+		})
+	}
+
+	t.Run("history", func(t *testing.T) {
+		calc := NewCalcWriter(&bytes.Buffer{})
+
+		if err := calc.Eval(`1 2 3 sum!`); err != nil {
+			t.Fatal(err)
+		}
+
+		if got := calc.history[len(calc.history)-1].Text; !strings.Contains(got, "sum!") {
+			t.Errorf("history doesn't mention sum!: %s", got)
+		}
+	})
+}
+
+func TestWordViews(t *testing.T) {
+	var tests = []struct {
+		name string
+		cmd  string
+		exp  string
+		err  bool
+	}{
+		{name: "asfloatbits-one", cmd: `0x3ff0000000000000 asfloatbits`, exp: "1\n"},
+		{name: "asfloatbits-negative", cmd: `0xc000000000000000 asfloatbits`, exp: "-2\n"},
+		{name: "asfloatbits-twos-complement", cmd: `0x4000000000000000 -1 x asfloatbits`, exp: "-2\n"},
+		{name: "asfloatbits-inf", cmd: `0x7ff0000000000000 asfloatbits`, exp: "+Inf\n"},
+		{name: "bitsof-one", cmd: `1 bitsof hex`, exp: "0x3ff0000000000000\n"},
+		{name: "bitsof-negative", cmd: `-2 bitsof hex`, exp: "0xc000000000000000\n"},
+		{name: "roundtrip", cmd: `0.5 bitsof asfloatbits`, exp: "0.5\n"},
+		{name: "bitsof-inexact", cmd: `0.1 bitsof`, err: true},
+		{name: "asint", cmd: `0xc000000000000000 asint`, exp: "-4611686018427387904\n"},
+		{name: "asint-negative", cmd: `-1 asint`, exp: "-1\n"},
+		{name: "asuint", cmd: `-1 asuint`, exp: "18446744073709551615\n"},
+		{name: "asuint-positive", cmd: `0x8000000000000000 asuint`, exp: "9223372036854775808\n"},
+		{name: "fraction", cmd: `1.5 asint`, err: true},
+		{name: "too-large", cmd: `2 64 ^ asuint`, err: true},
+		{name: "empty", cmd: `asint`, err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			calc := NewCalc()
+			calc.out = out
+			calc.quiet = true
+
+			if !evalCase(t, calc, test.cmd, test.err) {
+				return
+			}
+
+			if out.String() != test.exp {
+				t.Errorf("%s failed:\n+++  got: %q\n--- want: %q", test.cmd, out.String(), test.exp)
+			}
+		})
+	}
+}
+
+func TestPopVar(t *testing.T) {
+	var tests = []struct {
+		name string
+		cmd  string
+		exp  string
+		err  bool
+	}{
+		{name: "pop", cmd: `1 5 >>X`, exp: "1"},
+		{name: "pop-retrieve", cmd: `5 >>X <X`, exp: "5"},
+		{name: "pop-undo", cmd: `5 >>X undo`, exp: "5"},
+		{name: "copy", cmd: `5 >X`, exp: "5"},
+		{name: "pop-empty", cmd: `>>X`, exp: "", err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			calc := NewCalcWriter(&bytes.Buffer{})
+
+			evalCase(t, calc, test.cmd, test.err)
+
+			if got := list2str(calc.stack.All()); got != test.exp {
+				t.Errorf("%s failed:\n+++  got: %s\n--- want: %s", test.cmd, got, test.exp)
+			}
+		})
+	}
+
+	t.Run("dump", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		calc := NewCalcWriter(out)
+
+		if err := calc.Eval(`5 >>X dump`); err != nil {
+			t.Fatal(err)
+		}
+
+		if strings.Contains(out.String(), "#1:") || calc.Vars["X"] != 5 {
+			t.Errorf(">>X failed, stack not empty or X not set:\n%s", out.String())
+		}
+	})
+}
+
+func TestAliases(t *testing.T) {
+	calc := NewCalc()
+
+	for _, table := range []struct {
+		name    string
+		aliases map[string]string
+		maps    []Funcalls
+	}{
+		{"function", FunctionAliases, []Funcalls{calc.Funcalls}},
+		{"batch function", BatchAliases, []Funcalls{calc.BatchFuncalls}},
+	} {
+		for alias, canonical := range table.aliases {
+			function, ok := table.maps[0][canonical]
+			if !ok {
+				t.Errorf("%s alias %s refers to unknown %s", table.name, alias, canonical)
+
+				continue
+			}
+
+			if table.maps[0][alias] != function {
+				t.Errorf("%s alias %s doesn't refer to %s", table.name, alias, canonical)
+			}
+
+			if _, chained := table.aliases[canonical]; chained {
+				t.Errorf("%s alias %s refers to another alias %s", table.name, alias, canonical)
+			}
+		}
+	}
+
+	commandmaps := []Commands{calc.SettingsCommands, calc.ShowCommands, calc.StackCommands, calc.Commands}
+
+	for alias, canonical := range CommandAliases {
+		found := 0
+
+		for _, commands := range commandmaps {
+			if command, ok := commands[canonical]; ok {
+				found++
+
+				if commands[alias] != command {
+					t.Errorf("command alias %s doesn't refer to %s", alias, canonical)
+				}
+			}
+		}
+
+		if found != 1 {
+			t.Errorf("command alias %s refers to %d commands named %s, expected 1", alias, found, canonical)
+		}
+
+		if _, chained := CommandAliases[canonical]; chained {
+			t.Errorf("command alias %s refers to another alias %s", alias, canonical)
+		}
+	}
+
+	t.Run("help", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		calc := NewCalcWriter(out)
+
+		if err := calc.Eval("help"); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, exp := range []string{
+			"quit                 alias of exit\n",
+			"c                    alias of clear\n",
+			"prec                 alias of precision\n",
+		} {
+			if !strings.Contains(out.String(), exp) {
+				t.Errorf("help failed:\n+++  got: %s\n--- want: %s", out.String(), exp)
+			}
+		}
+	})
+}
+
+func TestOptionalArguments(t *testing.T) {
+	var tests = []struct {
+		name string
+		cmd  string
+		exp  float64
+	}{
+		{name: "usage", cmd: `usagestats usage 1 2 +`, exp: 3},
+		{name: "history", cmd: `history 1 2 +`, exp: 3},
+		{name: "locale", cmd: `locale 1 2 +`, exp: 3},
+		{name: "locale de", cmd: `locale de 1 2 +`, exp: 3},
+		{name: "byteunits", cmd: `byteunits 1 2 +`, exp: 3},
+		{name: "relaxedcase", cmd: `relaxedcase 1 2 +`, exp: 3},
+		{name: "tolerance", cmd: `tolerance sqrt2 2 *`, exp: 2 * math.Sqrt2},
+		{name: "maxiter", cmd: `maxiter 2.5 2 *`, exp: 5},
+		{name: "hex", cmd: `10 hex 2.5 *`, exp: 25},
+		{name: "prompt", cmd: `prompt 1 2 +`, exp: 3},
+		{name: "showstack", cmd: `showstack 1.5 2 *`, exp: 3},
+		{name: "fraction", cmd: `0.5 fraction 2.5 +`, exp: 3},
+		{name: "savesettings", cmd: `savesettings precision 1 2 +`, exp: 3},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			calc := NewCalcWriter(&bytes.Buffer{})
+			calc.errout = &bytes.Buffer{}
+			t.Setenv("HOME", t.TempDir())
+
+			if err := calc.Eval(test.cmd); err != nil {
+				t.Fatal(err)
+			}
+
+			got := calc.stack.Last()
+			if len(got) != 1 || got[0] != test.exp {
+				t.Errorf("%s failed:\n+++  got: %v\n--- want: %v", test.cmd, got, test.exp)
+			}
+		})
+	}
+}
+
+func TestOutputWriter(t *testing.T) {
+	out := &bytes.Buffer{}
+	calc := NewCalcWriter(out)
+
+	if err := calc.Eval(`usagestats debug 1 usage`); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, exp := range []string{"DEBUG(000)", "NAME                     COUNT\n"} {
+		if !strings.Contains(out.String(), exp) {
+			t.Errorf("output not written to the calc writer:\n+++  got: %s\n--- want: %s", out.String(), exp)
+		}
+	}
+
+	if err := NewCalcWriter(out).Eval(`undo`); err == nil {
+		t.Errorf("undo on a new stack accepted, expected error")
+	}
+}
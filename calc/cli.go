@@ -0,0 +1,691 @@
+/*
+Copyright © 2023-2024 Thomas von Dein
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package calc
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/chzyer/readline"
+	flag "github.com/spf13/pflag"
+	lua "github.com/yuin/gopher-lua"
+)
+
+const VERSION string = "2.1.4"
+
+const Usage string = `This is rpn, a reverse polish notation calculator cli.
+
+Usage: rpn [-bdqvh] [<operator>]
+
+Options:
+  -b, --batchmode       enable batch mode
+  -d, --debug           enable debug mode
+  -s, --stack           show last 5 items of the stack (off by default)
+  --stack-size <int>    number of items shown with -s (default 5)
+  -i  --intermediate    print intermediate results
+  -m, --manual          show manual
+  -c, --config <file>   load <file> containing LUA code
+  -f, --file <file>     evaluate <file> line by line, results are prefixed with file:line:
+  --errors-only         only print errors, no results
+  -p, --precision <int> floating point number precision (default 2)
+  -M, --money           money mode: round every result to cents
+  -q, --quiet           don't confirm changes made by stack commands
+  --stack-in <file>     load the initial stack from <file> (- for stdin)
+  --stack-out <file>    write the final stack to <file> (- for stdout)
+  --print-final         print the last stack item on exit, if not yet done
+  --print-stack-on-exit print the whole stack on exit instead of results
+  --paragraph-mode      stdin: empty lines separate independent calculations
+  --line                stdin: every line is an independent calculation
+  --every <int>         stdin: apply the operator to every <int> numbers read
+  --drop-partial        with --every: ignore the last window if it isn't full
+  --color <mode>        colored output: auto (default), always or never
+  --history-limit <int> max number of history entries (default 10000, 0: unlimited)
+  --persist             restore stack, variables and history of the last session
+  --summary             print count, min, max, mean, median, stddev and sum
+                        of the stack on exit
+  --list-functions      list all functions, commands and constants
+  --format <format>     output format of the list and summary: text or json
+  -v, --version         show version
+  -h, --help            show help
+
+Input on stdin is evaluated first, then the arguments are applied to
+the resulting stack, e.g.: echo 5 | rpn 2 +. If the only argument is a
+batch function, batch mode is enabled. E.g.: echo "2 3 4 5" | rpn +
+Arguments which are a complete calculation like 2 3 + don't read stdin.
+
+Copyright (c) 2023-2025 T.v.Dein`
+
+// the commandline tool, returns the exit status. The rpn binary is
+// just a wrapper around it.
+func Main() int {
+	calc := NewCalc()
+	defer calc.StopTee()
+
+	showversion := false
+	showhelp := false
+	showmanual := false
+	enabledebug := false
+	listfunctions := false
+	configfile := ""
+	format := "text"
+	stackin := ""
+	stackout := ""
+	colormode := ColorAuto
+	printstack := false
+	filename := ""
+	errorsonly := false
+	showsummary := false
+
+	flag.BoolVarP(&calc.batch, "batchmode", "b", false, "batch mode")
+	flag.BoolVarP(&calc.showstack, "show-stack", "s", false, "show stack")
+	flag.IntVarP(&calc.showstacklen, "stack-size", "", ShowStackLen,
+		"number of stack items shown")
+	flag.BoolVarP(&calc.intermediate, "showin-termediate", "i", false,
+		"show intermediate results")
+	flag.BoolVarP(&enabledebug, "debug", "d", false, "debug mode")
+	flag.BoolVarP(&showversion, "version", "v", false, "show version")
+	flag.BoolVarP(&showhelp, "help", "h", false, "show usage")
+	flag.BoolVarP(&showmanual, "manual", "m", false, "show manual")
+	flag.StringVarP(&configfile, "config", "c",
+		homeFile(".rpn.lua"), "config file (lua format)")
+	flag.StringVarP(&filename, "file", "f", "", "evaluate file")
+	flag.BoolVarP(&errorsonly, "errors-only", "", false, "only print errors")
+	flag.IntVarP(&calc.precision, "precision", "p", Precision, "floating point precision")
+	flag.BoolVarP(&calc.money, "money", "M", false, "money mode")
+	flag.BoolVarP(&calc.quietstack, "quiet", "q", false, "don't confirm stack commands")
+	flag.StringVarP(&stackin, "stack-in", "", "", "load initial stack from file")
+	flag.StringVarP(&stackout, "stack-out", "", "", "write final stack to file")
+	flag.BoolVarP(&calc.printfinal, "print-final", "", false,
+		"print last stack item on exit")
+	flag.BoolVarP(&printstack, "print-stack-on-exit", "", false,
+		"print the stack on exit")
+	flag.BoolVarP(&calc.paragraph, "paragraph-mode", "", false,
+		"empty lines separate calculations")
+	flag.BoolVarP(&calc.linemode, "line", "", false,
+		"every line is a calculation")
+	flag.IntVarP(&calc.every, "every", "", 0,
+		"apply the operator to every n numbers read from stdin")
+	flag.BoolVarP(&calc.droppartial, "drop-partial", "", false,
+		"ignore the last window of --every if it isn't full")
+	flag.IntVarP(&calc.historylimit, "history-limit", "", HistoryLimit,
+		"max number of history entries")
+	flag.BoolVarP(&calc.persist, "persist", "", false,
+		"restore the last session and save it on exit")
+	flag.StringVarP(&colormode, "color", "", colormode, "colored output: auto, always or never")
+	flag.BoolVarP(&listfunctions, "list-functions", "", false, "list functions")
+	flag.BoolVarP(&showsummary, "summary", "", false, "print statistics of the stack on exit")
+	flag.StringVarP(&format, "format", "", format, "output format (text or json)")
+
+	// saved settings replace the defaults of the flags, so that flags
+	// given on the commandline win. Warnings about them must not end
+	// up in the results, which may be read by a pipeline.
+	calc.errout = os.Stderr
+	if err := calc.LoadSettings(SettingsFile()); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	calc.errout = os.Stdout
+
+	flag.Parse()
+
+	if showversion {
+		fmt.Printf("This is rpn version %s\n", VERSION)
+
+		return 0
+	}
+
+	if showhelp {
+		fmt.Println(Usage)
+
+		return 0
+	}
+
+	if enabledebug {
+		calc.ToggleDebug()
+	}
+
+	if calc.showstacklen < 1 {
+		fmt.Fprintf(os.Stderr, "invalid stack size %d, must be at least 1\n", calc.showstacklen)
+
+		return 1
+	}
+
+	if calc.every < 0 || calc.every > 0 && (calc.paragraph || calc.linemode) {
+		fmt.Fprintln(os.Stderr, "--every needs a positive window size and can't be combined with --paragraph-mode or --line")
+
+		return 1
+	}
+
+	color, err := useColor(colormode)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+
+		return 1
+	}
+
+	calc.color = color
+
+	if showmanual {
+		man()
+
+		return 0
+	}
+
+	// the lua state object is global, instantiate it early
+	LuaInterpreter = lua.NewState(lua.Options{SkipOpenLibs: true})
+	defer LuaInterpreter.Close()
+
+	// our config file is interpreted  as lua code, only functions can
+	// be defined, init() will be called by InitLua().
+	if _, err := os.Stat(configfile); err == nil {
+		luarunner := NewInterpreter(configfile, enabledebug)
+		luarunner.InitLua()
+		calc.SetInt(luarunner)
+
+		if calc.debug {
+			fmt.Println("loaded config")
+		}
+	} else if calc.debug {
+		fmt.Println(err)
+	}
+
+	if listfunctions {
+		if err := calc.PrintFunctions(format); err != nil {
+			fmt.Println(err)
+
+			return 1
+		}
+
+		return 0
+	}
+
+	if showsummary {
+		if format != "text" && format != "json" {
+			fmt.Fprintf(os.Stderr, "unsupported format %s\n", format)
+
+			return 1
+		}
+
+		calc.summary = format
+	}
+
+	// same as --stack-out -, for scripts
+	if printstack {
+		if stackout != "" && stackout != "-" {
+			fmt.Fprintln(os.Stderr, "--print-stack-on-exit can't be combined with --stack-out")
+
+			return 1
+		}
+
+		stackout = "-"
+	}
+
+	// stdout carries the stack, so don't mix results into it
+	calc.quiet = stackout == "-" || errorsonly
+
+	if calc.persist {
+		// a broken session must not prevent us from starting
+		if err := calc.LoadSession(SessionFile()); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+
+		// the common teardown of all modes, exit included
+		defer func() {
+			if err := calc.SaveSession(SessionFile()); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+		}()
+	}
+
+	if stackin != "" {
+		if err := calc.LoadStackFile(stackin); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+
+			return 1
+		}
+	}
+
+	if filename != "" {
+		// called like rpn -f calculation.rpn
+		return evalFileMode(calc, filename, stackout)
+	}
+
+	if (len(flag.Args()) > 1 && !inputIsStdin()) || calc.SelfContained(flag.Args()) {
+		// commandline calc operation, no readline etc needed
+		// called like rpn 2 2 +, stdin is left alone even if it's
+		// not a terminal, e.g. in a shell loop reading a file
+		calc.stdin = true
+		calc.errout = os.Stderr
+		if err := calc.Eval(strings.Join(flag.Args(), " ")); err != nil {
+			calc.PrintError(err)
+
+			return 1
+		}
+
+		calc.PrintFinal()
+
+		if err := calc.PrintSummary(); err != nil {
+			calc.PrintError(err)
+
+			return 1
+		}
+
+		return saveStack(calc, stackout)
+	}
+
+	// interactive mode, need readline, unless the terminal can't cope
+	// with it
+	var lines LineReader
+
+	if homeDir() == "" && !inputIsStdin() {
+		fmt.Fprintln(os.Stderr, "no home directory, history and config disabled")
+	}
+
+	if dumbTerminal() && !inputIsStdin() {
+		lines = NewPlainReader(os.Stdin, os.Stdout, calc.Prompt())
+	} else {
+		reader, err := newReadline(calc)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+
+			return 1
+		}
+		defer reader.Close()
+
+		lines = reader
+	}
+
+	if inputIsStdin() {
+		// commands are  coming on stdin, however we  will still enter
+		// the same loop since readline just reads fine from stdin
+		calc.ToggleStdin()
+		calc.finalonly = true
+
+		// scripts want errors separated from results
+		calc.errout = os.Stderr
+	}
+
+	// the  commandline arguments are evaluated after stdin has been
+	// consumed, so that they operate on the numbers read from it
+	trailing := ""
+
+	if len(flag.Args()) > 0 && calc.stdin {
+		trailing = calc.TrailingOperator(flag.Args())
+	}
+
+	if calc.paragraph || calc.linemode || calc.every > 0 {
+		// called like this: rpn --paragraph-mode + < file
+		// the operator is applied to every paragraph, line or window
+		calc.paragraphop = trailing
+	}
+
+	// primary program repl
+	failed, aborted := RunRepl(calc, lines)
+	if aborted {
+		return 1
+	}
+
+	if calc.paragraph {
+		// the last paragraph might not be followed by an empty line
+		if err := calc.EndParagraph(); err != nil {
+			calc.PrintError(err)
+
+			return 1
+		}
+	}
+
+	if err := calc.EndLastWindow(); err != nil {
+		calc.PrintError(err)
+
+		return 1
+	}
+
+	if trailing != "" && !calc.paragraph && !calc.linemode && calc.every == 0 {
+		// called like this:
+		// echo 1 2 3 4 | rpn +
+		// echo 5 | rpn 2 +
+		if err = calc.Eval(trailing); err != nil {
+			calc.PrintError(err)
+
+			return 1
+		}
+	}
+
+	calc.PrintLastResult()
+	calc.PrintFinal()
+
+	if err := calc.PrintSummary(); err != nil {
+		calc.PrintError(err)
+
+		return 1
+	}
+
+	if failed {
+		return 1
+	}
+
+	return saveStack(calc, stackout)
+}
+
+// evaluate the given file like stdin, the arguments are applied to
+// the resulting stack afterwards
+func evalFileMode(calc *Calc, filename string, stackout string) int {
+	file, err := os.Open(filename)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+
+		return 1
+	}
+	defer file.Close()
+
+	calc.stdin = true
+	calc.errout = os.Stderr
+
+	trailing := calc.TrailingOperator(flag.Args())
+
+	if calc.paragraph || calc.linemode || calc.every > 0 {
+		calc.paragraphop = trailing
+		trailing = ""
+	}
+
+	failed := !calc.EvalFile(file, filename)
+
+	if trailing != "" {
+		if err := calc.Eval(trailing); err != nil {
+			calc.PrintError(err)
+
+			return 1
+		}
+	}
+
+	calc.PrintFinal()
+
+	if err := calc.PrintSummary(); err != nil {
+		calc.PrintError(err)
+
+		return 1
+	}
+
+	if failed {
+		return 1
+	}
+
+	return saveStack(calc, stackout)
+}
+
+// evaluate a calculation file line by line. Results and errors are
+// prefixed  with the file  name and line number,  like compiler
+// diagnostics, so that editors can jump to them. Returns false if any
+// line failed.
+func (c *Calc) EvalFile(reader io.Reader, filename string) bool {
+	success := true
+	scanner := bufio.NewScanner(reader)
+	line := 0
+
+	for scanner.Scan() {
+		line++
+		c.origin = fmt.Sprintf("%s:%d: ", filename, line)
+
+		if err := c.Eval(scanner.Text()); err != nil {
+			c.PrintError(err)
+
+			success = false
+		}
+
+		if err := c.EndLine(); err != nil {
+			c.PrintError(err)
+
+			success = false
+		}
+
+		if c.quit {
+			break
+		}
+	}
+
+	if c.paragraph {
+		// the last paragraph might not be followed by an empty line
+		if err := c.EndParagraph(); err != nil {
+			c.PrintError(err)
+
+			success = false
+		}
+	}
+
+	if err := c.EndLastWindow(); err != nil {
+		c.PrintError(err)
+
+		success = false
+	}
+
+	c.origin = ""
+
+	if err := scanner.Err(); err != nil {
+		c.PrintError(fmt.Errorf("failed to read %s: %w", filename, err))
+
+		success = false
+	}
+
+	return success
+}
+
+// arguments containing numbers which are sufficient for the functions
+// given as well are a calculation on their own, like "2 3 +". Other
+// arguments like "2 +" are applied to the numbers read from stdin.
+//
+// Tokens whose effect on the stack isn't known in advance, e.g. stack
+// commands like swap or registers like >NAME, need operands which may
+// well come from stdin, so they make the arguments incomplete.
+func (c *Calc) SelfContained(args []string) bool {
+	operands := false
+
+	for _, arg := range args {
+		if !c.KnownEffect(arg) {
+			return false
+		}
+
+		if c.IsLiteral(arg) {
+			operands = true
+		}
+	}
+
+	_, complete := c.EstimateDepth(0, args)
+
+	return operands && complete
+}
+
+// arguments given on the commandline  along with input on stdin. A
+// single batch function enables batch mode,  so that it operates on all
+// numbers read from stdin.
+func (c *Calc) TrailingOperator(args []string) string {
+	if len(args) == 1 && exists(c.BatchFuncalls, args[0]) {
+		c.batch = true
+	}
+
+	return strings.Join(args, " ")
+}
+
+// write the final stack, if requested with --stack-out
+func saveStack(calc *Calc, filename string) int {
+	if filename == "" {
+		return 0
+	}
+
+	if err := calc.SaveStackFile(filename); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+
+		return 1
+	}
+
+	return 0
+}
+
+// the readline instance of the repl, with completion, history and hints
+func newReadline(calc *Calc) (*readline.Instance, error) {
+	var reader *readline.Instance
+
+	config := &readline.Config{
+		Prompt:            calc.Prompt(),
+		HistoryFile:       homeFile(".rpn-history"),
+		HistoryLimit:      500,
+		AutoComplete:      calc.completer,
+		InterruptPrompt:   "^C",
+		EOFPrompt:         "exit",
+		HistorySearchFold: true,
+	}
+
+	if outputIsTerminal() {
+		// show a hint after each finished token, if enabled
+		config.Listener = readline.FuncListener(
+			func(line []rune, pos int, key rune) ([]rune, int, bool) {
+				if tokens, ok := finishedToken(line, pos, key); ok && calc.hints {
+					if hint, ok := calc.Hint(tokens); ok {
+						fmt.Fprintln(reader.Stdout(), calc.Colorize(ColorDim, hint))
+					}
+				}
+
+				return nil, 0, false
+			})
+	}
+
+	reader, err := readline.NewEx(config)
+	if err != nil && config.HistoryFile != "" {
+		// an unwritable history file must not prevent us from starting
+		config.HistoryFile = ""
+		reader, err = readline.NewEx(config)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	reader.CaptureExitSignal()
+
+	return reader, nil
+}
+
+type ReplAction int
+
+const (
+	ReplEval  ReplAction = iota // evaluate the line just read
+	ReplSkip                    // ignore the line, read the next one
+	ReplQuit                    // end the session cleanly
+	ReplAbort                   // give up after too many read errors
+
+	MaxReadErrors int = 3
+)
+
+// keeps track of consecutive interrupts and read errors in the repl
+type ReplState struct {
+	interrupts int
+	errors     int
+}
+
+// decide what to do  after readline returned: EOF (ctrl-d) ends the
+// session, the first ctrl-c only aborts the current line, a second one
+// in a row ends the session. Other errors might be transient (e.g. on
+// suspend/resume with some terminals), so we report and retry them a
+// couple of times before giving up. The message, if any, is for the
+// user, RunRepl() prints it.
+func (state *ReplState) Next(err error) (ReplAction, string) {
+	switch {
+	case err == nil:
+		state.interrupts = 0
+		state.errors = 0
+
+		return ReplEval, ""
+	case errors.Is(err, io.EOF):
+		return ReplQuit, ""
+	case errors.Is(err, readline.ErrInterrupt):
+		state.interrupts++
+
+		if state.interrupts > 1 {
+			return ReplQuit, ""
+		}
+
+		return ReplSkip, "press ctrl-c again or ctrl-d to exit"
+	default:
+		state.errors++
+
+		message := fmt.Sprintf("failed to read input: %s", err)
+
+		if state.errors >= MaxReadErrors {
+			return ReplAbort, message
+		}
+
+		return ReplSkip, message
+	}
+}
+
+const (
+	ColorAuto   string = "auto"
+	ColorAlways string = "always"
+	ColorNever  string = "never"
+)
+
+// decide whether to colorize the output. In auto mode colors are only
+// used if we're talking to a terminal and NO_COLOR is not set, so that
+// scripts don't get escape sequences.
+func useColor(mode string) (bool, error) {
+	switch mode {
+	case ColorAlways:
+		return true, nil
+	case ColorNever:
+		return false, nil
+	case ColorAuto:
+		return os.Getenv("NO_COLOR") == "" && outputIsTerminal() && !inputIsStdin() && !dumbTerminal(), nil
+	}
+
+	return false, fmt.Errorf("invalid color mode %s, use auto, always or never", mode)
+}
+
+func outputIsTerminal() bool {
+	stat, _ := os.Stdout.Stat()
+
+	return (stat.Mode() & os.ModeCharDevice) != 0
+}
+
+func inputIsStdin() bool {
+	stat, _ := os.Stdin.Stat()
+
+	return (stat.Mode() & os.ModeCharDevice) == 0
+}
+
+func man() {
+	var buf bytes.Buffer
+
+	man := exec.Command("less", "-")
+
+	buf.WriteString(manpage)
+
+	man.Stdout = os.Stdout
+	man.Stdin = &buf
+	man.Stderr = os.Stderr
+
+	err := man.Run()
+
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,223 @@
+package calc
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/chzyer/readline"
+)
+
+func TestReplState(t *testing.T) {
+	failure := errors.New("resource temporarily unavailable")
+
+	var tests = []struct {
+		name   string
+		errors []error
+		exp    []ReplAction
+	}{
+		{
+			name:   "eof",
+			errors: []error{nil, io.EOF},
+			exp:    []ReplAction{ReplEval, ReplQuit},
+		},
+		{
+			name:   "double-interrupt",
+			errors: []error{readline.ErrInterrupt, readline.ErrInterrupt},
+			exp:    []ReplAction{ReplSkip, ReplQuit},
+		},
+		{
+			name:   "interrupt-input-interrupt",
+			errors: []error{readline.ErrInterrupt, nil, readline.ErrInterrupt},
+			exp:    []ReplAction{ReplSkip, ReplEval, ReplSkip},
+		},
+		{
+			name:   "transient-error",
+			errors: []error{failure, failure, nil, failure},
+			exp:    []ReplAction{ReplSkip, ReplSkip, ReplEval, ReplSkip},
+		},
+		{
+			name:   "persistent-error",
+			errors: []error{failure, failure, failure},
+			exp:    []ReplAction{ReplSkip, ReplSkip, ReplAbort},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			state := &ReplState{}
+
+			for i, err := range test.errors {
+				got, message := state.Next(err)
+				if got != test.exp[i] {
+					t.Errorf("wrong action for input %d (%v):\n+++  got: %d\n--- want: %d",
+						i, err, got, test.exp[i])
+				}
+
+				// skipped lines and aborts are explained, the rest isn't
+				if explained := got == ReplSkip || got == ReplAbort; explained != (message != "") {
+					t.Errorf("wrong message for input %d (%v): %q", i, err, message)
+				}
+			}
+		})
+	}
+}
+
+func TestPlainReader(t *testing.T) {
+	out := &bytes.Buffer{}
+	reader := NewPlainReader(strings.NewReader("1 2\r\n+\nlast"), out, "rpn> ")
+
+	lines := []string{}
+
+	for {
+		line, err := reader.Readline()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		lines = append(lines, line)
+		reader.SetPrompt("more> ")
+	}
+
+	if got := strings.Join(lines, "|"); got != "1 2|+|last" {
+		t.Errorf("plain reader failed:\n+++  got: %s\n--- want: %s", got, "1 2|+|last")
+	}
+
+	if got, exp := out.String(), "rpn> more> more> more> "; got != exp {
+		t.Errorf("plain reader prompt failed:\n+++  got: %s\n--- want: %s", got, exp)
+	}
+}
+
+func TestRunRepl(t *testing.T) {
+	var tests = []struct {
+		name   string
+		input  string
+		exp    string
+		stdin  bool
+		failed bool
+	}{
+		{
+			name:  "interactive",
+			input: "1 2\n+\n3 x\n",
+			exp:   "rpn> rpn> = 3\nrpn> = 9\nrpn> ",
+		},
+		{
+			name:  "exit",
+			input: "1 2 +\nexit\n4 5 +\n",
+			exp:   "rpn> = 3\nrpn> ",
+		},
+		{
+			name:  "error",
+			input: "1 0 /\n",
+			exp:   "rpn> Error: division by null\nrpn> ",
+		},
+		{
+			name:   "stdin-error",
+			input:  "1 0 /\n1 2 +\n",
+			exp:    "rpn> Error: division by null\nrpn> 3\nrpn> ",
+			stdin:  true,
+			failed: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			calc := NewCalcWriter(out)
+			calc.stdin = test.stdin
+			calc.prompt = "rpn> "
+
+			failed, aborted := RunRepl(calc, NewPlainReader(strings.NewReader(test.input), out, calc.Prompt()))
+
+			if aborted {
+				t.Fatal("repl aborted")
+			}
+
+			if failed != test.failed {
+				t.Errorf("repl failure state wrong:\n+++  got: %t\n--- want: %t", failed, test.failed)
+			}
+
+			if out.String() != test.exp {
+				t.Errorf("repl failed:\n+++  got: %q\n--- want: %q", out.String(), test.exp)
+			}
+		})
+	}
+}
+
+// returns the lines, then fails forever
+type failingReader struct {
+	lines []string
+}
+
+func (reader *failingReader) Readline() (string, error) {
+	if len(reader.lines) == 0 {
+		return "", errors.New("resource temporarily unavailable")
+	}
+
+	line := reader.lines[0]
+	reader.lines = reader.lines[1:]
+
+	return line, nil
+}
+
+func (reader *failingReader) SetPrompt(string) {}
+
+func TestRunReplReadErrors(t *testing.T) {
+	out, errout := &bytes.Buffer{}, &bytes.Buffer{}
+	calc := NewCalcWriter(out)
+	calc.errout = errout
+	calc.stdin = true
+
+	_, aborted := RunRepl(calc, &failingReader{lines: []string{"1 2 +"}})
+
+	if !aborted {
+		t.Errorf("repl didn't give up after %d read errors", MaxReadErrors)
+	}
+
+	if out.String() != "3\n" {
+		t.Errorf("read errors mixed into the results: %q", out.String())
+	}
+
+	exp := strings.Repeat("failed to read input: resource temporarily unavailable\n", MaxReadErrors)
+	if errout.String() != exp {
+		t.Errorf("read errors failed:\n+++  got: %q\n--- want: %q", errout.String(), exp)
+	}
+}
+
+func TestSelfContained(t *testing.T) {
+	var tests = []struct {
+		args []string
+		exp  bool
+	}{
+		{args: []string{"1", "2", "+"}, exp: true},
+		{args: []string{"Pi", "2", "x", "floor"}, exp: true},
+		{args: []string{"4", "sqrt"}, exp: true},
+		{args: []string{"2", "dup", "x"}, exp: false},
+		{args: []string{"2", "swap", "-"}, exp: false},
+		{args: []string{"10", "over", "+"}, exp: false},
+		{args: []string{"2", ">A"}, exp: false},
+		{args: []string{"2", "+"}, exp: false},
+		{args: []string{"10", "x"}, exp: false},
+		{args: []string{"1", "2", "+", "x"}, exp: false},
+		{args: []string{"sqrt"}, exp: false},
+		{args: []string{"+"}, exp: false},
+		{args: []string{"dup", "x"}, exp: false},
+		{args: []string{}, exp: false},
+	}
+
+	for _, test := range tests {
+		t.Run(strings.Join(test.args, " "), func(t *testing.T) {
+			calc := NewCalcWriter(&bytes.Buffer{})
+
+			if got := calc.SelfContained(test.args); got != test.exp {
+				t.Errorf("self contained failed:\n+++  got: %t\n--- want: %t", got, test.exp)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+/*
+Copyright © 2025 Thomas von Dein
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package calc
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Something the copy command can put text into, replaced in tests
+type Clipboard interface {
+	Copy(text string) error
+}
+
+// A tool reading the clipboard contents from stdin. It is only usable
+// if the environment variable env is set (if any), e.g. xclip needs an
+// X11 display.
+type ClipboardTool struct {
+	env  string
+	args []string
+}
+
+// the tools are tried in this order
+var ClipboardTools = []ClipboardTool{
+	{args: []string{"pbcopy"}},
+	{env: "WAYLAND_DISPLAY", args: []string{"wl-copy"}},
+	{env: "DISPLAY", args: []string{"xclip", "-selection", "clipboard"}},
+	{env: "DISPLAY", args: []string{"xsel", "--clipboard", "--input"}},
+}
+
+// the system clipboard, we don't talk to it directly but use the first
+// available clipboard tool
+type SystemClipboard struct{}
+
+func (SystemClipboard) Copy(text string) error {
+	for _, tool := range ClipboardTools {
+		if tool.env != "" && os.Getenv(tool.env) == "" {
+			continue
+		}
+
+		path, err := exec.LookPath(tool.args[0])
+		if err != nil {
+			continue
+		}
+
+		cmd := exec.Command(path, tool.args[1:]...)
+		cmd.Stdin = strings.NewReader(text)
+
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("failed to copy to clipboard using %s: %w", tool.args[0], err)
+		}
+
+		return nil
+	}
+
+	return errors.New("no clipboard available, install pbcopy, wl-copy, xclip or xsel")
+}
+
+// copy the last stack item to the clipboard, formatted the same way as
+// the result
+func CommandCopy(c *Calc, _ []string) error {
+	if c.stdin || c.batch {
+		return errors.New("copy is only available in interactive mode")
+	}
+
+	if c.stack.Len() == 0 {
+		return errors.New("stack is empty")
+	}
+
+	text := c.FormatResult(c.stack.Last()[0])
+
+	if err := c.clipboard.Copy(text); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.out, "copied %s to clipboard\n", text)
+
+	return nil
+}
@@ -0,0 +1,1739 @@
+/*
+Copyright © 2023 Thomas von Dein
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package calc
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type CommandFunction func(*Calc)
+
+// commands which  consume arguments  or which  may fail.  They get the
+// items following them on the input line, which might be less than
+// expected or even none.
+type ArgCommandFunction func(*Calc, []string) error
+
+type Command struct {
+	Help       string
+	Func       CommandFunction
+	ArgFunc    ArgCommandFunction
+	Expectargs int // number of items consumed, -1 means the rest of the line
+
+	// optional, returns how many of the candidates are arguments of the
+	// command, the remaining ones are evaluated as usual
+	Accepts func(c *Calc, candidates []string) int
+}
+
+type Commands map[string]*Command
+
+func NewCommand(help string, function CommandFunction) *Command {
+	return &Command{
+		Help: help,
+		Func: function,
+	}
+}
+
+// convenience function, create a command which consumes up to
+// expectargs items from the input line as its arguments
+func NewArgCommand(help string, expectargs int, function ArgCommandFunction) *Command {
+	return &Command{
+		Help:       help,
+		ArgFunc:    function,
+		Expectargs: expectargs,
+	}
+}
+
+// create a command whose arguments are optional, it only consumes
+// the following items the accepts function agrees to, so that in
+// "precision 1 2 +" the 1 is the precision, but in "precision 2.5 +"
+// the 2.5 is a number
+func NewOptionalArgCommand(help string, expectargs int,
+	accepts func(*Calc, []string) int, function ArgCommandFunction) *Command {
+	command := NewArgCommand(help, expectargs, function)
+	command.Accepts = accepts
+
+	return command
+}
+
+// accept the first candidate if it's one of the given words
+func acceptWords(words ...string) func(*Calc, []string) int {
+	return func(_ *Calc, candidates []string) int {
+		if len(candidates) > 0 && slices.Contains(words, candidates[0]) {
+			return 1
+		}
+
+		return 0
+	}
+}
+
+// accept the first candidate if it's an integer
+func acceptInt(_ *Calc, candidates []string) int {
+	if len(candidates) > 0 {
+		if _, err := strconv.Atoi(candidates[0]); err == nil {
+			return 1
+		}
+	}
+
+	return 0
+}
+
+// accept the first candidate if it's a number
+func acceptNumber(_ *Calc, candidates []string) int {
+	if len(candidates) > 0 {
+		if _, err := strconv.ParseFloat(candidates[0], 64); err == nil {
+			return 1
+		}
+	}
+
+	return 0
+}
+
+// a prompt template is either quoted, contains a placeholder or is
+// "default", then the rest of the line belongs to it
+func acceptTemplate(_ *Calc, candidates []string) int {
+	if len(candidates) == 0 {
+		return 0
+	}
+
+	first := candidates[0]
+	if first == "default" || strings.HasPrefix(first, `"`) || strings.Contains(first, "{") {
+		return len(candidates)
+	}
+
+	return 0
+}
+
+// accept the leading candidates which are names of settings
+func acceptSettings(_ *Calc, candidates []string) int {
+	for count, candidate := range candidates {
+		if _, ok := FindSetting(candidate); !ok {
+			return count
+		}
+	}
+
+	return len(candidates)
+}
+
+func (c *Calc) SetSettingsCommands() Commands {
+	return Commands{
+		// Toggles
+		"debug": NewCommand(
+			"toggle debugging",
+			func(c *Calc) {
+				c.ToggleDebug()
+			},
+		),
+
+		"nodebug": NewCommand(
+			"disable debugging",
+			func(c *Calc) {
+				c.debug = false
+				c.stack.debug = false
+			},
+		),
+
+		"batch": NewCommand(
+			"toggle batch mode",
+			func(c *Calc) {
+				c.ToggleBatch()
+			},
+		),
+
+		"nobatch": NewCommand(
+			"disable batch mode",
+			func(c *Calc) {
+				c.batch = false
+			},
+		),
+
+		"prompt": NewOptionalArgCommand(
+			"set the prompt template, e.g. prompt \"{stack} {top} » \"",
+			-1,
+			acceptTemplate,
+			CommandPrompt,
+		),
+
+		"showstack": NewOptionalArgCommand(
+			"toggle show last items of the stack, showstack n shows the last n",
+			1,
+			acceptInt,
+			CommandShowStack,
+		),
+
+		"noshowstack": NewCommand(
+			"disable display of the stack",
+			func(c *Calc) {
+				c.showstack = false
+			},
+		),
+
+		"money": NewCommand(
+			"toggle money mode, round every result to cents",
+			func(c *Calc) {
+				c.ToggleMoney()
+			},
+		),
+
+		"nomoney": NewCommand(
+			"disable money mode",
+			func(c *Calc) {
+				c.money = false
+			},
+		),
+
+		"groupdigits": NewCommand(
+			"toggle thousands separators in results",
+			func(c *Calc) {
+				c.ToggleGroupDigits()
+			},
+		),
+
+		"nogroupdigits": NewCommand(
+			"disable thousands separators",
+			func(c *Calc) {
+				c.groupdigits = false
+			},
+		),
+
+		"decimalcomma": NewCommand(
+			"toggle european style output: 1.234,56",
+			func(c *Calc) {
+				c.ToggleDecimalComma()
+			},
+		),
+
+		"nodecimalcomma": NewCommand(
+			"disable european style output",
+			func(c *Calc) {
+				c.decimalcomma = false
+			},
+		),
+
+		"showfull": NewCommand(
+			"toggle printing results with full precision",
+			func(c *Calc) {
+				c.ToggleShowFull()
+			},
+		),
+
+		"noshowfull": NewCommand(
+			"print results with the configured precision",
+			func(c *Calc) {
+				c.showfull = false
+			},
+		),
+
+		"progmode": NewCommand(
+			"toggle programmer mode, print integer results in hex and binary as well",
+			func(c *Calc) {
+				c.ToggleProgmode()
+			},
+		),
+
+		"noprogmode": NewCommand(
+			"disable programmer mode",
+			func(c *Calc) {
+				c.progmode = false
+			},
+		),
+
+		"linemode": NewCommand(
+			"toggle line mode, calculate every input line independently",
+			func(c *Calc) {
+				c.ToggleLinemode()
+			},
+		),
+
+		"nolinemode": NewCommand(
+			"disable line mode",
+			func(c *Calc) {
+				c.linemode = false
+			},
+		),
+
+		"lenientmoney": NewCommand(
+			"toggle lenient input of amounts, guessing the decimal separator",
+			func(c *Calc) {
+				c.ToggleLenientMoney()
+			},
+		),
+
+		"nolenientmoney": NewCommand(
+			"disable lenient input of amounts",
+			func(c *Calc) {
+				c.lenientmoney = false
+			},
+		),
+
+		"hints": NewCommand(
+			"toggle hints about functions while typing",
+			func(c *Calc) {
+				c.ToggleHints()
+			},
+		),
+
+		"nohints": NewCommand(
+			"disable hints",
+			func(c *Calc) {
+				c.hints = false
+			},
+		),
+
+		"color": NewCommand(
+			"toggle colored output",
+			func(c *Calc) {
+				c.ToggleColor()
+			},
+		),
+
+		"nocolor": NewCommand(
+			"disable colored output",
+			func(c *Calc) {
+				c.color = false
+			},
+		),
+
+		"teach": NewCommand(
+			"toggle teach mode, show each operation along with the stack",
+			func(c *Calc) {
+				c.ToggleTeach()
+			},
+		),
+
+		"noteach": NewCommand(
+			"disable teach mode",
+			func(c *Calc) {
+				c.teach = false
+			},
+		),
+
+		"hexupper": NewCommand(
+			"toggle uppercase hex digits",
+			func(c *Calc) {
+				c.ToggleHexUpper()
+			},
+		),
+
+		"nohexupper": NewCommand(
+			"print lowercase hex digits",
+			func(c *Calc) {
+				c.hexupper = false
+			},
+		),
+
+		"fix": NewCommand(
+			"display results in fixed point notation (default)",
+			func(c *Calc) {
+				c.SetNotation(NotationFix)
+			},
+		),
+
+		"sci": NewCommand(
+			"display results in scientific notation: 1.25e+04",
+			func(c *Calc) {
+				c.SetNotation(NotationSci)
+			},
+		),
+
+		"eng": NewCommand(
+			"display results in engineering notation: 12.5e3",
+			func(c *Calc) {
+				c.SetNotation(NotationEng)
+			},
+		),
+
+		"usagestats": NewCommand(
+			"toggle counting of function and command usage",
+			func(c *Calc) {
+				c.ToggleUsageStats()
+			},
+		),
+
+		"nousagestats": NewCommand(
+			"disable counting of function and command usage",
+			func(c *Calc) {
+				c.usagestats = false
+			},
+		),
+
+		"precision": NewOptionalArgCommand(
+			"set the floating point number precision (default 2)",
+			1,
+			acceptInt,
+			CommandPrecision,
+		),
+
+		"byteunits": NewOptionalArgCommand(
+			"set the units of human: iec (1024, default) or si (1000)",
+			1,
+			acceptWords("iec", "si"),
+			CommandByteUnits,
+		),
+
+		"tolerance": NewOptionalArgCommand(
+			"set the tolerance of solve and integrate (default 1e-10)",
+			1,
+			acceptNumber,
+			CommandTolerance,
+		),
+
+		"maxiter": NewOptionalArgCommand(
+			"set the max number of iterations of solve and integrate (default 100)",
+			1,
+			acceptInt,
+			CommandMaxIterations,
+		),
+
+		"maxexpand": NewOptionalArgCommand(
+			"set the max number of items evaluated by nested repeats per line (default 10000)",
+			1,
+			acceptInt,
+			CommandMaxExpand,
+		),
+
+		"allownan": NewCommand(
+			"toggle acceptance of NaN and Inf results",
+			func(c *Calc) {
+				c.ToggleAllowNaN()
+			},
+		),
+
+		"noallownan": NewCommand(
+			"reject NaN and Inf results",
+			func(c *Calc) {
+				c.allownan = false
+			},
+		),
+
+		"locale": NewOptionalArgCommand(
+			"set number format: en (1,234.56, default) or de (1.234,56)",
+			1,
+			acceptWords(LocaleEN, LocaleDE),
+			CommandLocale,
+		),
+
+		"relaxedcase": NewOptionalArgCommand(
+			"match constants and functions case insensitive: off, constants (default) or all",
+			1,
+			acceptWords("off", "constants", "all"),
+			CommandRelaxedCase,
+		),
+
+		"settings": NewArgCommand(
+			"show all settings which can be saved",
+			0,
+			CommandSettings,
+		),
+
+		"savesettings": NewOptionalArgCommand(
+			"save the given settings (default: all changed ones) to ~/.rpnrc",
+			-1,
+			acceptSettings,
+			CommandSaveSettings,
+		),
+	}
+}
+
+func (c *Calc) SetShowCommands() Commands {
+	return Commands{
+		// Display commands
+		"dump": NewCommand(
+			"display the stack contents",
+			func(c *Calc) {
+				c.stack.Dump(c.out, c.FormatResult)
+			},
+		),
+
+		"diff": NewCommand(
+			"compare the stack before the last operation with the current one",
+			func(c *Calc) {
+				fmt.Fprintf(c.out, "--- backup revision %d\n+++ stack revision %d\n",
+					c.stack.backuprev, c.stack.rev)
+
+				for _, line := range c.stack.Diff(c.FormatItem) {
+					fmt.Fprintln(c.out, line)
+				}
+			},
+		),
+
+		"history": NewOptionalArgCommand(
+			"display calculation history, 'history math|stack|note' shows only those",
+			1,
+			acceptWords("math", "stack", "note"),
+			func(c *Calc, args []string) error {
+				if len(args) == 0 {
+					c.PrintHistory("")
+
+					return nil
+				}
+
+				if !contains([]string{HistoryMath, HistoryStack, HistoryNote}, args[0]) {
+					return fmt.Errorf("unknown history kind %s", args[0])
+				}
+
+				c.PrintHistory(args[0])
+
+				return nil
+			},
+		),
+
+		"notes": NewCommand(
+			"list the notes taken in this session",
+			func(c *Calc) {
+				for idx, note := range c.notes {
+					fmt.Fprintf(c.out, "%d %s\n", idx+1, note)
+				}
+			},
+		),
+
+		"vars": NewCommand(
+			"show list of variables",
+			func(c *Calc) {
+				if len(c.Vars) > 0 {
+					fmt.Fprintf(c.out, "%-20s     %s\n", "VARIABLE", "VALUE")
+					names := []string{}
+					for name := range c.Vars {
+						names = append(names, name)
+					}
+
+					sort.Strings(names)
+
+					for _, name := range names {
+						fmt.Fprintf(c.out, "%-20s  -> %.2f\n", name, c.Vars[name])
+					}
+				} else {
+					fmt.Fprintln(c.out, "no vars registered")
+				}
+			},
+		),
+
+		"hex": NewOptionalArgCommand(
+			"show last stack item in hex form (converted to int), 'hex 8' pads to 8 digits",
+			1,
+			acceptInt,
+			CommandHex,
+		),
+
+		"base": NewArgCommand(
+			"show last stack item (integer) in base n (2-36)",
+			1,
+			CommandBase,
+		),
+
+		"human": NewArgCommand(
+			"show last stack item (bytes) with a unit, e.g. 1.46 GiB",
+			0,
+			func(c *Calc, _ []string) error {
+				return c.ShowLast(func(value float64) (string, error) {
+					return formatBytes(value, c.precision, c.byteunits == ByteUnitsSI), nil
+				})
+			},
+		),
+
+		"fraction": NewOptionalArgCommand(
+			"show last stack item as fraction, 'fraction 100' limits the denominator to 100",
+			1,
+			acceptInt,
+			CommandFraction,
+		),
+
+		"jsondump": NewArgCommand(
+			"show stack, variables and precision as json",
+			0,
+			func(c *Calc, _ []string) error {
+				return c.DumpJSON()
+			},
+		),
+
+		"save": NewArgCommand(
+			"save stack and variables under the given name",
+			1,
+			CommandSave,
+		),
+
+		"stats": NewArgCommand(
+			"show count, min, max, mean, median, stddev and sum of the stack",
+			0,
+			CommandStats,
+		),
+
+		"stacks": NewArgCommand(
+			"list the names of saved stacks",
+			0,
+			CommandStacks,
+		),
+
+		"to-time": NewArgCommand(
+			"show last stack item as time (h:mm:ss)",
+			0,
+			func(c *Calc, _ []string) error {
+				return c.ShowLast(func(value float64) (string, error) {
+					return formatTime(value), nil
+				})
+			},
+		),
+
+		"to-duration": NewArgCommand(
+			"show last stack item (seconds) as duration",
+			0,
+			func(c *Calc, _ []string) error {
+				return c.ShowLast(formatDuration)
+			},
+		),
+
+		"to-dms": NewArgCommand(
+			"show last stack item as degrees, minutes and seconds",
+			0,
+			func(c *Calc, _ []string) error {
+				return c.ShowLast(func(value float64) (string, error) {
+					return formatDMS(value, c.precision), nil
+				})
+			},
+		),
+
+		"to-date": NewArgCommand(
+			"show last stack item (unix timestamp) as local date",
+			0,
+			func(c *Calc, _ []string) error {
+				return c.ShowLast(func(value float64) (string, error) {
+					return formatDate(value, time.Local), nil
+				})
+			},
+		),
+
+		"to-ip": NewArgCommand(
+			"show last stack item as ip address",
+			0,
+			func(c *Calc, _ []string) error {
+				return c.ShowLast(formatIP)
+			},
+		),
+
+		"full": NewArgCommand(
+			"show last stack item with full precision",
+			0,
+			func(c *Calc, _ []string) error {
+				return c.ShowLast(func(value float64) (string, error) {
+					return strconv.FormatFloat(value, 'g', 17, 64), nil
+				})
+			},
+		),
+
+		"roman": NewArgCommand(
+			"show last stack item as roman numeral",
+			0,
+			func(c *Calc, _ []string) error {
+				return c.ShowLast(formatRoman)
+			},
+		),
+
+		"asint": NewArgCommand(
+			"show last stack item (64 bit word) as signed integer",
+			0,
+			func(c *Calc, _ []string) error {
+				return c.ShowWord(func(word uint64) string {
+					return strconv.FormatInt(int64(word), 10)
+				})
+			},
+		),
+
+		"asuint": NewArgCommand(
+			"show last stack item (64 bit word) as unsigned integer",
+			0,
+			func(c *Calc, _ []string) error {
+				return c.ShowWord(func(word uint64) string {
+					return strconv.FormatUint(word, 10)
+				})
+			},
+		),
+
+		"asfloatbits": NewArgCommand(
+			"show the double whose bit pattern is the last stack item (64 bit word)",
+			0,
+			func(c *Calc, _ []string) error {
+				return c.ShowWord(func(word uint64) string {
+					return strconv.FormatFloat(math.Float64frombits(word), 'g', -1, 64)
+				})
+			},
+		),
+
+		"usage": NewOptionalArgCommand(
+			"show usage statistics, 'usage save' writes them to ~/.rpn-usage",
+			1,
+			acceptWords("save"),
+			CommandUsage,
+		),
+	}
+}
+
+func (c *Calc) SetStackCommands() Commands {
+	return Commands{
+		"clear": NewCommand(
+			"clear the whole stack",
+			func(c *Calc) {
+				c.stack.Backup()
+				c.StackHistory("clear: removed %d items", c.stack.Len())
+				c.stack.Clear()
+				c.ClearLastX()
+			},
+		),
+
+		"shift": NewCommand(
+			"remove the last element of the stack",
+			CommandShift,
+		),
+
+		"drop": NewArgCommand(
+			"remove the last n elements of the stack",
+			1,
+			CommandDrop,
+		),
+
+		"reverse": NewCommand(
+			"reverse the stack elements",
+			func(c *Calc) {
+				c.stack.Backup()
+				c.stack.Reverse()
+				c.StackHistory("reverse: %d items", c.stack.Len())
+			},
+		),
+
+		"swap": NewArgCommand(
+			"exchange the last two elements",
+			0,
+			CommandSwap,
+		),
+
+		"undo": NewArgCommand(
+			"undo last operation",
+			0,
+			func(c *Calc, _ []string) error {
+				if err := c.stack.Restore(); err != nil {
+					return err
+				}
+
+				c.StackHistory("undo: %d items", c.stack.Len())
+
+				return nil
+			},
+		),
+
+		"dup": NewArgCommand(
+			"duplicate last stack item",
+			0,
+			CommandDup,
+		),
+
+		"dupn": NewArgCommand(
+			"duplicate the last n items as a block: a b, 2 -> a b a b",
+			1,
+			CommandDupN,
+		),
+
+		"roll": NewArgCommand(
+			"move the n-th item, counting from the top (1), to the top",
+			1,
+			CommandRoll,
+		),
+
+		"rot": NewArgCommand(
+			"rotate the last three items, moving the third one to the top",
+			0,
+			func(c *Calc, _ []string) error {
+				return c.Roll(3)
+			},
+		),
+
+		"checkpoint": NewCommand(
+			"remember the stack, see rollback",
+			func(c *Calc) {
+				c.stack.Checkpoint()
+				c.StackHistory("checkpoint: %d items", c.stack.Len())
+			},
+		),
+
+		"rollback": NewArgCommand(
+			"restore the stack remembered by checkpoint",
+			0,
+			CommandRollback,
+		),
+
+		"depth": NewCommand(
+			"push the number of stack items",
+			func(c *Calc) {
+				depth := c.stack.Len()
+				c.stack.Backup()
+				c.stack.Push(float64(depth))
+				c.StackHistory("depth: %d", depth)
+			},
+		),
+
+		"over": NewArgCommand(
+			"copy the second last item to the top: a b -> a b a",
+			0,
+			CommandOver,
+		),
+
+		"nip": NewArgCommand(
+			"remove the second last item: a b -> b",
+			0,
+			CommandNip,
+		),
+
+		"pick": NewArgCommand(
+			"copy the n-th item, counting from the top (1), to the top",
+			1,
+			CommandPick,
+		),
+
+		"jsonload": NewArgCommand(
+			"replace the stack with a json array from a file or the next line",
+			1,
+			CommandJSONLoad,
+		),
+
+		"load": NewArgCommand(
+			"replace the stack with the one saved under the given name",
+			1,
+			CommandLoad,
+		),
+
+		"lastx": NewArgCommand(
+			"push the last operand of the last math operation",
+			0,
+			CommandLastX,
+		),
+
+		"edit": NewArgCommand(
+			"edit the stack interactively",
+			0,
+			CommandEdit,
+		),
+
+		"rmoutliers": NewArgCommand(
+			"pop k, remove all items farther than k*madev from the median",
+			0,
+			CommandRemoveOutliers,
+		),
+	}
+}
+
+// define all management (that is: non calculation) commands
+func (c *Calc) SetCommands() {
+	c.SettingsCommands = c.SetSettingsCommands()
+	c.ShowCommands = c.SetShowCommands()
+	c.StackCommands = c.SetStackCommands()
+
+	// general commands
+	c.Commands = Commands{
+		"exit": NewCommand(
+			"exit program",
+			func(c *Calc) {
+				// the repl ends the session, so that it can be saved
+				c.quit = true
+			},
+		),
+
+		"manual": NewCommand(
+			"show manual",
+			func(c *Calc) {
+				man()
+			},
+		),
+
+		"solve": NewArgCommand(
+			"find a root of a lua function, starting at the last stack item",
+			1,
+			CommandSolve,
+		),
+
+		"integrate": NewArgCommand(
+			"integrate a lua function between the last two stack items",
+			1,
+			CommandIntegrate,
+		),
+
+		"deriv": NewArgCommand(
+			"derivative of a lua function at the last stack item",
+			1,
+			CommandDerivative,
+		),
+
+		"note": NewArgCommand(
+			"store the rest of the line as a note, see notes",
+			-1,
+			func(c *Calc, args []string) error {
+				if len(args) == 0 {
+					return errors.New("usage: note <text>")
+				}
+
+				c.AddNote(strings.Join(args, " "))
+
+				return nil
+			},
+		),
+
+		"repeat": NewArgCommand(
+			"pop n and evaluate the rest of the line n times",
+			-1,
+			CommandRepeat,
+		),
+
+		"nop": NewCommand(
+			"do nothing, useful as a placeholder in scripts",
+			func(c *Calc) {},
+		),
+
+		"tee": NewArgCommand(
+			"append results along with the input to a file",
+			1,
+			CommandTee,
+		),
+
+		"notee": NewCommand(
+			"stop writing results to the tee file",
+			func(c *Calc) {
+				c.StopTee()
+			},
+		),
+
+		"copy": NewArgCommand(
+			"copy the last stack item to the clipboard, formatted like the result",
+			0,
+			CommandCopy,
+		),
+
+		"which": NewArgCommand(
+			"show how the next item would be interpreted",
+			1,
+			CommandWhich,
+		),
+
+		"convert": NewArgCommand(
+			"convert the last stack item between units, e.g. convert feet cm",
+			2,
+			CommandConvert,
+		),
+	}
+
+	for _, commands := range []Commands{c.SettingsCommands, c.ShowCommands, c.StackCommands, c.Commands} {
+		addAliases(commands, CommandAliases)
+	}
+}
+
+// alternative names of commands of any kind, alias -> canonical name
+var CommandAliases = map[string]string{
+	"quit": "exit",
+
+	"d":    "debug",
+	"b":    "batch",
+	"s":    "showstack",
+	"prec": "precision",
+
+	"togglebatch":     "batch",
+	"toggledebug":     "debug",
+	"toggleshowstack": "showstack",
+
+	"h": "history",
+	"p": "dump",
+	"v": "vars",
+
+	"c":      "clear",
+	"u":      "undo",
+	"shiftn": "drop",
+}
+
+// the canonical name of an alias of the given kind, e.g. mean for avg
+func (c *Calc) AliasOf(kind TokenKind, item string) (string, bool) {
+	var aliases map[string]string
+
+	switch kind.Name {
+	case "function":
+		aliases = FunctionAliases
+	case "batch function":
+		aliases = BatchAliases
+	case "keep function":
+		canonical, ok := BatchAliases[strings.TrimSuffix(item, KeepSuffix)]
+
+		return canonical + KeepSuffix, ok
+	case "command", "show command", "stack command", "setting":
+		aliases = CommandAliases
+	}
+
+	canonical, ok := aliases[item]
+
+	return canonical, ok
+}
+
+// added to the command map:
+func CommandShowStack(c *Calc, args []string) error {
+	if len(args) == 0 {
+		c.ToggleShow()
+
+		return nil
+	}
+
+	number, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid stack size %s", args[0])
+	}
+
+	if number < 1 {
+		return fmt.Errorf("invalid stack size %d, must be at least 1", number)
+	}
+
+	c.showstacklen = number
+	c.showstack = true
+
+	return nil
+}
+
+// prompt TEMPLATE sets the template, without one it's being shown and
+// "prompt default" restores the default
+func CommandPrompt(c *Calc, args []string) error {
+	if len(args) == 0 {
+		fmt.Fprintf(c.out, "prompt: %q\n", c.PromptTemplate())
+
+		return nil
+	}
+
+	// the line has been split by whitespace, so quotes are needed to
+	// retain a trailing space
+	template := strings.Join(args, " ")
+	if len(template) > 1 && strings.HasPrefix(template, `"`) && strings.HasSuffix(template, `"`) {
+		template = template[1 : len(template)-1]
+	}
+
+	if template == "default" {
+		c.prompt = ""
+
+		return nil
+	}
+
+	if err := c.CheckPrompt(template); err != nil {
+		return err
+	}
+
+	c.prompt = template
+
+	return nil
+}
+
+func CommandHex(c *Calc, args []string) error {
+	width := 0
+
+	if len(args) > 0 {
+		number, err := strconv.Atoi(args[0])
+		if err != nil {
+			return err
+		}
+
+		if number < 1 || number > MaxHexWidth {
+			return fmt.Errorf("invalid hex width %d, use 1-%d", number, MaxHexWidth)
+		}
+
+		width = number
+	}
+
+	return c.ShowLast(func(value float64) (string, error) {
+		return formatHex(value, width, c.hexupper)
+	})
+}
+
+// show the last stack item formatted by format, which may fail. Like
+// all show commands it's an error if the stack is empty.
+func (c *Calc) ShowLast(format func(float64) (string, error)) error {
+	if c.stack.Len() == 0 {
+		return errors.New("stack is empty")
+	}
+
+	text, err := format(c.stack.Last()[0])
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(c.out, text)
+
+	return nil
+}
+
+// show the last stack item as 64 bit word, negative numbers in two's
+// complement, see toWord()
+func (c *Calc) ShowWord(format func(uint64) string) error {
+	return c.ShowLast(func(value float64) (string, error) {
+		word, err := toWord(value)
+		if err != nil {
+			return "", err
+		}
+
+		return format(word), nil
+	})
+}
+
+func CommandBase(c *Calc, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: base <n>")
+	}
+
+	base, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid base %s, use 2-36", args[0])
+	}
+
+	return c.ShowLast(func(value float64) (string, error) {
+		return formatBase(value, base)
+	})
+}
+
+func CommandWhich(c *Calc, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: which <item>")
+	}
+
+	kind, ok := c.Classify(args[0])
+	if !ok {
+		fmt.Fprintf(c.out, "%s: unknown\n", args[0])
+
+		return nil
+	}
+
+	if canonical, ok := c.AliasOf(kind, args[0]); ok {
+		fmt.Fprintf(c.out, "%s: %s (alias of %s)\n", args[0], kind.Name, canonical)
+
+		return nil
+	}
+
+	fmt.Fprintf(c.out, "%s: %s\n", args[0], kind.Name)
+
+	return nil
+}
+
+func CommandFraction(c *Calc, args []string) error {
+	maxden := MaxDenominator
+
+	if len(args) > 0 {
+		number, err := strconv.Atoi(args[0])
+		if err != nil {
+			return err
+		}
+
+		if number < 1 {
+			return fmt.Errorf("invalid max denominator %d", number)
+		}
+
+		maxden = number
+	}
+
+	if c.stack.Len() == 0 {
+		return errors.New("stack is empty")
+	}
+
+	value := c.stack.Last()[0]
+
+	num, den, err := approximateFraction(value, int64(maxden))
+	if err != nil {
+		return err
+	}
+
+	deviation := math.Abs(value - float64(num)/float64(den))
+
+	switch {
+	case deviation == 0 && den == 1:
+		fmt.Fprintf(c.out, "%d\n", num)
+	case deviation == 0:
+		fmt.Fprintf(c.out, "%d/%d\n", num, den)
+	default:
+		fmt.Fprintf(c.out, "%d/%d (error %.1e)\n", num, den, deviation)
+	}
+
+	return nil
+}
+
+func CommandPrecision(c *Calc, args []string) error {
+	if len(args) == 0 {
+		fmt.Fprintf(c.out, "precision: %d\n", c.precision)
+
+		return nil
+	}
+
+	precision, err := strconv.Atoi(args[0])
+	if err != nil || precision < 0 || precision > MaxPrecision {
+		return fmt.Errorf("invalid precision %s, use 0-%d", args[0], MaxPrecision)
+	}
+
+	c.precision = precision
+
+	return nil
+}
+
+func CommandByteUnits(c *Calc, args []string) error {
+	if len(args) == 0 {
+		fmt.Fprintf(c.out, "byteunits: %s\n", c.byteunits)
+
+		return nil
+	}
+
+	switch args[0] {
+	case ByteUnitsIEC, ByteUnitsSI:
+		c.byteunits = args[0]
+	default:
+		return fmt.Errorf("unsupported byte units %s, use iec or si", args[0])
+	}
+
+	return nil
+}
+
+func CommandLocale(c *Calc, args []string) error {
+	if len(args) == 0 {
+		fmt.Fprintf(c.out, "locale: %s\n", c.locale)
+
+		return nil
+	}
+
+	switch args[0] {
+	case LocaleEN, LocaleDE:
+		c.locale = args[0]
+	default:
+		return fmt.Errorf("unsupported locale %s, use en or de", args[0])
+	}
+
+	return nil
+}
+
+func CommandRelaxedCase(c *Calc, args []string) error {
+	if len(args) == 0 {
+		fmt.Fprintf(c.out, "relaxedcase: %s\n", c.relaxedcase)
+
+		return nil
+	}
+
+	switch args[0] {
+	case RelaxedCaseOff, RelaxedCaseConstants, RelaxedCaseAll:
+		c.relaxedcase = args[0]
+	default:
+		return fmt.Errorf("unsupported relaxedcase %s, use off, constants or all", args[0])
+	}
+
+	return nil
+}
+
+func CommandShift(c *Calc) {
+	item := c.stack.Last()
+	if len(item) == 1 {
+		c.stack.Backup()
+		c.stack.Shift()
+		c.StackHistory("shift: removed %s", list2str(item))
+	}
+}
+
+func CommandDrop(c *Calc, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: drop <n>")
+	}
+
+	count, err := strconv.Atoi(args[0])
+	if err != nil || count < 0 {
+		return fmt.Errorf("invalid number of items %s", args[0])
+	}
+
+	if count > c.stack.Len() {
+		return fmt.Errorf("can't drop %d items, stack has %d items", count, c.stack.Len())
+	}
+
+	// nothing to do, so don't overwrite the backup
+	if count == 0 {
+		return nil
+	}
+
+	removed := c.stack.Last(count)
+
+	c.stack.Backup()
+	c.stack.Shift(count)
+	c.StackHistory("drop: removed %s", list2str(removed))
+
+	return nil
+}
+
+func CommandSwap(c *Calc, _ []string) error {
+	if c.stack.Len() < 2 {
+		return errors.New("stack too small, can't swap")
+	}
+
+	c.stack.Backup()
+	c.stack.Swap()
+	c.StackHistory("swap: %s", list2str(c.stack.Last(2)))
+
+	return nil
+}
+
+func CommandDup(c *Calc, _ []string) error {
+	item := c.stack.Last()
+	if len(item) == 0 {
+		return errors.New("stack is empty, can't dup")
+	}
+
+	c.stack.Backup()
+	c.stack.Push(item[0])
+	c.StackHistory("dup: %s", list2str(item))
+
+	return nil
+}
+
+func CommandDupN(c *Calc, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: dupn <n>")
+	}
+
+	count, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid item count %s", args[0])
+	}
+
+	if count < 1 || count > c.stack.Len() {
+		return fmt.Errorf("can't duplicate %d items, stack has %d items", count, c.stack.Len())
+	}
+
+	c.stack.Backup()
+
+	if err := c.stack.DupN(count); err != nil {
+		return err
+	}
+
+	c.StackHistory("dupn %d: %s", count, list2str(c.stack.Last(count)))
+
+	return nil
+}
+
+func CommandRoll(c *Calc, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: roll <n>")
+	}
+
+	index, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid stack position %s", args[0])
+	}
+
+	return c.Roll(index)
+}
+
+// roll the n-th item to the top, the backup is only being made if the
+// stack is large enough
+func (c *Calc) Roll(index int) error {
+	if index < 1 || index > c.stack.Len() {
+		return fmt.Errorf("no stack item %d, stack has %d items", index, c.stack.Len())
+	}
+
+	c.stack.Backup()
+
+	if err := c.stack.Roll(index); err != nil {
+		return err
+	}
+
+	c.StackHistory("roll %d: %s", index, list2str(c.stack.Last(index)))
+
+	return nil
+}
+
+func CommandRollback(c *Calc, _ []string) error {
+	if !c.stack.HasCheckpoint() {
+		return errors.New("no checkpoint set")
+	}
+
+	before := c.stack.All()
+
+	c.stack.Backup()
+
+	if err := c.stack.Rollback(); err != nil {
+		return err
+	}
+
+	changed := ChangedItems(before, c.stack.All())
+	c.StackHistory("rollback: %d items changed", changed)
+
+	if !c.quiet {
+		fmt.Fprintf(c.out, "rolled back, %d items changed\n", changed)
+	}
+
+	return nil
+}
+
+func CommandOver(c *Calc, _ []string) error {
+	if c.stack.Len() < 2 {
+		return errors.New("stack too small, over needs 2 items")
+	}
+
+	c.stack.Backup()
+
+	if err := c.stack.Over(); err != nil {
+		return err
+	}
+
+	c.StackHistory("over: %s", list2str(c.stack.Last(3)))
+
+	return nil
+}
+
+func CommandNip(c *Calc, _ []string) error {
+	if c.stack.Len() < 2 {
+		return errors.New("stack too small, nip needs 2 items")
+	}
+
+	removed := c.stack.Last(2)[0]
+
+	c.stack.Backup()
+
+	if err := c.stack.Nip(); err != nil {
+		return err
+	}
+
+	c.StackHistory("nip: removed %v", removed)
+
+	return nil
+}
+
+func CommandPick(c *Calc, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: pick <n>")
+	}
+
+	index, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid stack position %s", args[0])
+	}
+
+	item, err := c.stack.PickFrom(index)
+	if err != nil {
+		return err
+	}
+
+	c.stack.Backup()
+	c.stack.Push(item)
+	c.StackHistory("pick %d: %v", index, item)
+
+	return nil
+}
+
+func CommandLastX(c *Calc, _ []string) error {
+	lastx, ok := c.LastRegister(LastX)
+	if !ok {
+		return errors.New("no math operation done yet")
+	}
+
+	c.stack.Backup()
+	c.stack.Push(lastx)
+	c.StackHistory("lastx: %v", lastx)
+
+	return nil
+}
+
+// the common robust outlier filter
+func CommandRemoveOutliers(c *Calc, _ []string) error {
+	if c.stack.Len() < 2 {
+		return errors.New("stack doesn't provide enough arguments")
+	}
+
+	items := c.stack.All()
+	factor, data := items[len(items)-1], items[:len(items)-1]
+
+	if factor <= 0 {
+		return errors.New("outlier factor must be positive")
+	}
+
+	center := median(data)
+	limit := factor * medianAbsoluteDeviation(data)
+
+	c.stack.Backup()
+	c.stack.Clear()
+
+	for _, item := range data {
+		if math.Abs(item-center) <= limit {
+			c.stack.Push(item)
+		}
+	}
+
+	removed := len(data) - c.stack.Len()
+	c.StackHistory("rmoutliers %s -> removed %d",
+		sample2str(items, HistorySample), removed)
+
+	fmt.Fprintf(c.out, "removed %d outliers\n", removed)
+
+	return nil
+}
+
+// evaluate line count times without output and history entries, the
+// previous settings are restored afterwards, repeat may be nested
+func (c *Calc) repeatLine(line string, count int) error {
+	quiet, nohistory := c.quiet, c.nohistory
+
+	c.quiet = true
+	c.nohistory = true
+
+	c.BeginExpansion("repeat")
+
+	defer func() {
+		c.EndExpansion()
+
+		c.quiet = quiet
+		c.nohistory = nohistory
+	}()
+
+	for repetition := range count {
+		if err := c.Eval(line); err != nil {
+			// Eval() already added the Error: prefix
+			return fmt.Errorf("repetition %d: %s",
+				repetition+1, strings.TrimPrefix(err.Error(), "Error: "))
+		}
+	}
+
+	return nil
+}
+
+// evaluate the rest of the line n times, n being the last stack item.
+// The whole repetition is atomic: in case of an error the stack is
+// restored and undo reverts all repetitions at once.
+func CommandRepeat(c *Calc, args []string) error {
+	if c.stack.Len() == 0 {
+		return errors.New("repeat needs a count on the stack")
+	}
+
+	if len(args) == 0 {
+		return errors.New("nothing to repeat")
+	}
+
+	count := c.stack.Last()[0]
+	if count < 0 || count > float64(MaxRepeat) || count != math.Trunc(count) {
+		return fmt.Errorf("repeat count must be an integer between 0 and %d", MaxRepeat)
+	}
+
+	before := c.stack.All()
+	line := strings.Join(args, " ")
+
+	c.stack.Shift()
+
+	err := c.repeatLine(line, int(count))
+
+	if err != nil {
+		c.replaceStack(before)
+
+		return err
+	}
+
+	// so that undo reverts the whole repetition
+	after := c.stack.All()
+	c.replaceStack(before)
+	c.stack.Backup()
+	c.replaceStack(after)
+
+	c.History("repeat %.0fx: %s", count, line)
+
+	if c.stack.Len() > 0 {
+		c.Result()
+	}
+
+	return nil
+}
+
+func CommandMaxExpand(c *Calc, args []string) error {
+	if len(args) == 0 {
+		fmt.Fprintf(c.out, "maxexpand: %d\n", c.maxexpand)
+
+		return nil
+	}
+
+	maxexpand, err := strconv.Atoi(args[0])
+	if err != nil || maxexpand <= 0 {
+		return fmt.Errorf("invalid expansion budget %s", args[0])
+	}
+
+	c.maxexpand = maxexpand
+
+	return nil
+}
+
+func (c *Calc) replaceStack(items Numbers) {
+	c.stack.Clear()
+
+	for _, item := range items {
+		c.stack.Push(item)
+	}
+}
+
+func CommandUsage(c *Calc, args []string) error {
+	if !c.usagestats {
+		return errors.New("usage statistics are disabled, enable with usagestats")
+	}
+
+	if len(args) == 0 {
+		c.PrintUsage()
+
+		return nil
+	}
+
+	if args[0] != "save" {
+		return fmt.Errorf("unknown usage argument %s", args[0])
+	}
+
+	return c.SaveUsage(UsageFile())
+}
+
+func CommandEdit(calc *Calc, _ []string) error {
+	if calc.stack.Len() == 0 {
+		fmt.Fprintln(calc.out, "empty stack")
+
+		return nil
+	}
+
+	// put the stack contents into a tmp file, only readable by us
+	tmp, err := os.CreateTemp("", "stack")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(tmp.Name())
+
+	if err := writeEditFile(tmp, calc.stack.All()); err != nil {
+		tmp.Close()
+
+		return fmt.Errorf("failed to write stack: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write stack: %w", err)
+	}
+
+	// determine which editor to use
+	editor := "vi"
+
+	if enveditor, present := os.LookupEnv("EDITOR"); present && enveditor != "" {
+		editor = enveditor
+	}
+
+	// execute editor with our tmp file containing current stack
+	cmd := exec.Command(editor, tmp.Name())
+
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	// a failing editor (e.g. :cq in vi) aborts the edit
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("editor failed, stack unchanged: %w", err)
+	}
+
+	// read the file back in
+	modified, err := os.Open(tmp.Name())
+	if err != nil {
+		return fmt.Errorf("failed to read stack: %w", err)
+	}
+	defer modified.Close()
+
+	items, skipped, err := calc.ReadEditedStack(modified)
+	if err != nil {
+		return err
+	}
+
+	calc.stack.Backup()
+	calc.stack.Clear()
+
+	for _, item := range items {
+		calc.stack.Push(item)
+	}
+
+	fmt.Fprintf(calc.out, "kept %d, skipped %d invalid lines\n", len(items), skipped)
+
+	calc.StackHistory("edit: %d items -> %d items",
+		calc.stack.backup.Len(), calc.stack.Len())
+
+	return nil
+}
+
+func writeEditFile(file *os.File, items Numbers) error {
+	if err := file.Chmod(0o600); err != nil {
+		return err
+	}
+
+	comment := `# add or remove numbers as you wish.
+# each number must be on its own line.
+# numbers must be floating point formatted.
+# an empty file or a failing editor (e.g. :cq) aborts.
+`
+	if _, err := file.WriteString(comment); err != nil {
+		return err
+	}
+
+	// with full precision, so that unchanged values survive
+	if err := WriteStack(file, items); err != nil {
+		return err
+	}
+
+	// make sure the editor sees everything
+	return file.Sync()
+}
+
+// parse the file modified by the user, one number per line. Invalid
+// lines are reported and skipped, their number is returned. If there's
+// no valid number left, the edit is aborted: the user either emptied
+// the file or all lines are broken, in both cases we'd lose the stack.
+func (c *Calc) ReadEditedStack(reader io.Reader) (Numbers, int, error) {
+	items := Numbers{}
+	skipped := 0
+
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(c.Comment.ReplaceAllString(scanner.Text(), ""))
+		if line == "" {
+			continue
+		}
+
+		num, err := strconv.ParseFloat(line, 64)
+		if err != nil {
+			fmt.Fprintf(c.out, "%s is not a floating point number!\n", line)
+
+			skipped++
+
+			continue
+		}
+
+		items = append(items, num)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to read stack: %w", err)
+	}
+
+	switch {
+	case len(items) == 0 && skipped > 0:
+		return nil, skipped, errors.New("no valid numbers, stack unchanged")
+	case len(items) == 0:
+		return nil, 0, errors.New("no numbers left, stack unchanged, use clear to empty it")
+	}
+
+	return items, skipped, nil
+}
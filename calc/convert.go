@@ -0,0 +1,139 @@
+/*
+Copyright © 2025 Thomas von Dein
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package calc
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// find the shortest chain of conversion functions leading from one unit
+// to another, e.g. feet-to-inch, inch-to-cm. Every converter is an edge
+// of the graph, so conversions registered in Lua are included.
+func (c *Calc) ConversionPath(from, to string) ([]string, error) {
+	units := c.UnitWords()
+
+	for _, unit := range []string{from, to} {
+		if !contains(units, unit) {
+			if suggestion, ok := closest(unit, units, max(1, len(unit)/3)); ok {
+				return nil, fmt.Errorf("unknown unit %s, did you mean %s?", unit, suggestion)
+			}
+
+			return nil, fmt.Errorf("unknown unit %s", unit)
+		}
+	}
+
+	if from == to {
+		return nil, errors.New("nothing to convert")
+	}
+
+	// sorted, so that the chain is always the same if there are more
+	// than one of equal length
+	edges := map[string][]string{}
+
+	for name := range c.Funcalls {
+		source, target, ok := strings.Cut(name, ConversionSeparator)
+		if ok && source != "" && target != "" {
+			edges[source] = append(edges[source], target)
+		}
+	}
+
+	for _, targets := range edges {
+		sort.Strings(targets)
+	}
+
+	// breadth first, remembering where we came from
+	previous := map[string]string{from: ""}
+	queue := []string{from}
+
+	for len(queue) > 0 {
+		unit := queue[0]
+		queue = queue[1:]
+
+		if unit == to {
+			break
+		}
+
+		for _, target := range edges[unit] {
+			if _, seen := previous[target]; !seen {
+				previous[target] = unit
+				queue = append(queue, target)
+			}
+		}
+	}
+
+	if _, found := previous[to]; !found {
+		return nil, fmt.Errorf("no conversion from %s to %s", from, to)
+	}
+
+	path := []string{}
+	for unit := to; unit != from; unit = previous[unit] {
+		path = append([]string{previous[unit] + ConversionSeparator + unit}, path...)
+	}
+
+	return path, nil
+}
+
+// convert FROM TO: replace the last stack item by applying the chain of
+// converters leading from one unit to the other
+func CommandConvert(c *Calc, args []string) error {
+	if len(args) != 2 {
+		return errors.New("usage: convert <from unit> <to unit>")
+	}
+
+	path, err := c.ConversionPath(args[0], args[1])
+	if err != nil {
+		return err
+	}
+
+	if c.stack.Len() == 0 {
+		return errors.New("stack doesn't provide enough arguments")
+	}
+
+	c.Debug("conversion chain: " + strings.Join(path, ", "))
+
+	value := c.stack.Last()[0]
+	result := value
+
+	for _, name := range path {
+		funcresult := c.Funcalls[name].Func(Numbers{result})
+		if funcresult.Err != nil {
+			return funcresult.Err
+		}
+
+		result = funcresult.Res
+	}
+
+	if err := c.CheckNumber(result); err != nil {
+		return err
+	}
+
+	result = c.Round(result)
+
+	c.stack.Backup()
+	c.stack.Shift()
+	c.stack.Push(result)
+
+	c.ResultHistory(result, "convert %f %s to %s", value, args[0], args[1])
+	c.SetLastX(Numbers{value})
+	c.Result()
+
+	return nil
+}
@@ -0,0 +1,1076 @@
+/*
+Copyright © 2023 Thomas von Dein
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package calc
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"time"
+)
+
+// returns the current time, replaced by tests
+var TimeNow = time.Now
+
+type Result struct {
+	Res float64
+	Err error
+}
+
+type Numbers []float64
+
+type Function func(Numbers) Result
+
+// every function we  are able to call must be  of type Funcall, which
+// needs to  specify how  many numbers  it expects  and the  actual go
+// function to be executed.
+//
+// The function  has to take  a float slice  as argument and  return a
+// float and  an error object. The  float slice is guaranteed  to have
+// the expected number of arguments.
+//
+// However, Lua functions are handled differently, see interpreter.go.
+type Funcall struct {
+	Expectargs int // -1 means batch only mode, you'll get the whole stack as arg
+	Func       Function
+	Help       string
+	Category   string // set by DefineFunctions() and DefineBatchFunctions()
+
+	// optional, explains why the result is NaN or Inf, used instead of
+	// the generic error if allownan is not set
+	Overflow func(arg Numbers) error
+}
+
+// will hold all hard coded functions and operators
+type Funcalls map[string]*Funcall
+
+// convenience function,  create a  new Funcall object,  if expectargs
+// was not specified, 2 is assumed.
+func NewFuncall(help string, function Function, expectargs ...int) *Funcall {
+	expect := 2
+
+	if len(expectargs) > 0 {
+		expect = expectargs[0]
+	}
+
+	return &Funcall{
+		Expectargs: expect,
+		Func:       function,
+		Help:       help,
+	}
+}
+
+// Convenience function, create new result
+func NewResult(n float64, e error) Result {
+	return Result{Res: n, Err: e}
+}
+
+// the actual functions, called once during initialization. They are
+// grouped by category, which is used for the function listing.
+func DefineFunctions() Funcalls {
+	funcmap := Funcalls{}
+
+	for category, functions := range map[string]Funcalls{
+		"operator":  DefineOperators(),
+		"math":      DefineMathFunctions(),
+		"converter": DefineConverters(),
+		"bitwise":   DefineBitwiseOperators(),
+		"time":      DefineTimeFunctions(),
+	} {
+		for name, function := range functions {
+			function.Category = category
+			funcmap[name] = function
+		}
+	}
+
+	// tell the user where the float64 range ends
+	funcmap["exp"].Overflow = exponentOverflow(MaxExp)
+	funcmap["expm1"].Overflow = exponentOverflow(MaxExp)
+	funcmap["exp2"].Overflow = exponentOverflow(MaxExp2)
+	funcmap["factorial"].Overflow = factorialOverflow
+	funcmap["gamma"].Overflow = gammaOverflow
+	funcmap["pow"].Overflow = powerOverflow
+	funcmap["^"].Overflow = powerOverflow
+
+	addAliases(funcmap, FunctionAliases)
+
+	return funcmap
+}
+
+// alternative names of functions, alias -> canonical name
+var FunctionAliases = map[string]string{
+	"*":         "x",
+	"remainder": "mod",
+	"//":        "floordiv",
+	"!":         "factorial",
+}
+
+// alternative names of batch functions, alias -> canonical name
+var BatchAliases = map[string]string{
+	"+":   "sum",
+	"avg": "mean",
+}
+
+// simple operators, they all expect 2 args
+func DefineOperators() Funcalls {
+	return Funcalls{
+		"+": NewFuncall(
+			"add",
+			func(arg Numbers) Result {
+				return NewResult(arg[0]+arg[1], nil)
+			},
+		),
+
+		"-": NewFuncall(
+			"subtract",
+			func(arg Numbers) Result {
+				return NewResult(arg[0]-arg[1], nil)
+			},
+		),
+
+		"x": NewFuncall(
+			"multiply",
+			func(arg Numbers) Result {
+				return NewResult(arg[0]*arg[1], nil)
+			},
+		),
+
+		"/": NewFuncall(
+			"divide",
+			func(arg Numbers) Result {
+				if arg[1] == 0 {
+					return NewResult(0, errors.New("division by null"))
+				}
+
+				return NewResult(arg[0]/arg[1], nil)
+			},
+		),
+
+		"^": NewFuncall(
+			"power",
+			func(arg Numbers) Result {
+				return NewResult(math.Pow(arg[0], arg[1]), nil)
+			},
+		),
+
+		"%": NewFuncall(
+			"percent",
+			func(arg Numbers) Result {
+				return NewResult((arg[0]/100)*arg[1], nil)
+			},
+		),
+
+		"%-": NewFuncall(
+			"subtract percent",
+			func(arg Numbers) Result {
+				return NewResult(arg[0]-((arg[0]/100)*arg[1]), nil)
+			},
+		),
+
+		"%+": NewFuncall(
+			"add percent",
+			func(arg Numbers) Result {
+				return NewResult(arg[0]+((arg[0]/100)*arg[1]), nil)
+			},
+		),
+	}
+}
+
+func DefineMathFunctions() Funcalls {
+	return Funcalls{
+		"mod": NewFuncall(
+			"remainder of x/y",
+			func(arg Numbers) Result {
+				return NewResult(math.Remainder(arg[0], arg[1]), nil)
+			},
+		),
+
+		"ceildiv": NewFuncall(
+			"divide x by y, rounded up",
+			func(arg Numbers) Result {
+				if arg[1] == 0 {
+					return NewResult(0, errors.New("division by null"))
+				}
+
+				return NewResult(math.Ceil(arg[0]/arg[1]), nil)
+			},
+		),
+
+		"floordiv": NewFuncall(
+			"divide x by y, rounded down",
+			func(arg Numbers) Result {
+				if arg[1] == 0 {
+					return NewResult(0, errors.New("division by null"))
+				}
+
+				return NewResult(math.Floor(arg[0]/arg[1]), nil)
+			},
+		),
+
+		"roundmult": NewFuncall(
+			"round x to the nearest multiple of y, ties away from zero",
+			func(arg Numbers) Result {
+				if arg[1] <= 0 {
+					return NewResult(0, errors.New("multiple must be positive"))
+				}
+
+				return NewResult(math.Round(arg[0]/arg[1])*arg[1], nil)
+			},
+		),
+
+		"sqrt": NewFuncall(
+			"square root",
+			func(arg Numbers) Result {
+				return NewResult(math.Sqrt(arg[0]), nil)
+			},
+			1),
+
+		"abs": NewFuncall(
+			"absolute value",
+			func(arg Numbers) Result {
+				return NewResult(math.Abs(arg[0]), nil)
+			},
+			1),
+
+		"acos": NewFuncall(
+			"arccosine (radians)",
+			func(arg Numbers) Result {
+				return NewResult(math.Acos(arg[0]), nil)
+			},
+			1),
+
+		"acosh": NewFuncall(
+			"inverse hyperbolic cosine",
+			func(arg Numbers) Result {
+				return NewResult(math.Acosh(arg[0]), nil)
+			},
+			1),
+
+		"asin": NewFuncall(
+			"arcsine (radians)",
+			func(arg Numbers) Result {
+				return NewResult(math.Asin(arg[0]), nil)
+			},
+			1),
+
+		"asinh": NewFuncall(
+			"inverse hyperbolic sine",
+			func(arg Numbers) Result {
+				return NewResult(math.Asinh(arg[0]), nil)
+			},
+			1),
+
+		"atan": NewFuncall(
+			"arctangent (radians)",
+			func(arg Numbers) Result {
+				return NewResult(math.Atan(arg[0]), nil)
+			},
+			1),
+
+		"atan2": NewFuncall(
+			"arctangent of y/x (radians)",
+			func(arg Numbers) Result {
+				return NewResult(math.Atan2(arg[0], arg[1]), nil)
+			},
+			2),
+
+		"atanh": NewFuncall(
+			"inverse hyperbolic tangent",
+			func(arg Numbers) Result {
+				return NewResult(math.Atanh(arg[0]), nil)
+			},
+			1),
+
+		"cbrt": NewFuncall(
+			"cube root",
+			func(arg Numbers) Result {
+				return NewResult(math.Cbrt(arg[0]), nil)
+			},
+			1),
+
+		"ceil": NewFuncall(
+			"round up to the next integer",
+			func(arg Numbers) Result {
+				return NewResult(math.Ceil(arg[0]), nil)
+			},
+			1),
+
+		"cos": NewFuncall(
+			"cosine (radians)",
+			func(arg Numbers) Result {
+				return NewResult(math.Cos(arg[0]), nil)
+			},
+			1),
+
+		"cosh": NewFuncall(
+			"hyperbolic cosine",
+			func(arg Numbers) Result {
+				return NewResult(math.Cosh(arg[0]), nil)
+			},
+			1),
+
+		"erf": NewFuncall(
+			"error function",
+			func(arg Numbers) Result {
+				return NewResult(math.Erf(arg[0]), nil)
+			},
+			1),
+
+		"erfc": NewFuncall(
+			"complementary error function",
+			func(arg Numbers) Result {
+				return NewResult(math.Erfc(arg[0]), nil)
+			},
+			1),
+
+		"erfcinv": NewFuncall(
+			"inverse of erfc",
+			func(arg Numbers) Result {
+				return NewResult(math.Erfcinv(arg[0]), nil)
+			},
+			1),
+
+		"erfinv": NewFuncall(
+			"inverse error function",
+			func(arg Numbers) Result {
+				return NewResult(math.Erfinv(arg[0]), nil)
+			},
+			1),
+
+		"exp": NewFuncall(
+			"e^x",
+			func(arg Numbers) Result {
+				return NewResult(math.Exp(arg[0]), nil)
+			},
+			1),
+
+		"exp2": NewFuncall(
+			"2^x",
+			func(arg Numbers) Result {
+				return NewResult(math.Exp2(arg[0]), nil)
+			},
+			1),
+
+		"expm1": NewFuncall(
+			"e^x - 1",
+			func(arg Numbers) Result {
+				return NewResult(math.Expm1(arg[0]), nil)
+			},
+			1),
+
+		"factorial": NewFuncall(
+			"n!",
+			func(arg Numbers) Result {
+				return factorial(arg[0])
+			},
+			1),
+
+		"floor": NewFuncall(
+			"round down to the next integer",
+			func(arg Numbers) Result {
+				return NewResult(math.Floor(arg[0]), nil)
+			},
+			1),
+
+		"gamma": NewFuncall(
+			"gamma function",
+			func(arg Numbers) Result {
+				return NewResult(math.Gamma(arg[0]), nil)
+			},
+			1),
+
+		"ilogb": NewFuncall(
+			"binary exponent as integer",
+			func(arg Numbers) Result {
+				return NewResult(float64(math.Ilogb(arg[0])), nil)
+			},
+			1),
+
+		"j0": NewFuncall(
+			"bessel function of the first kind, order 0",
+			func(arg Numbers) Result {
+				return NewResult(math.J0(arg[0]), nil)
+			},
+			1),
+
+		"j1": NewFuncall(
+			"bessel function of the first kind, order 1",
+			func(arg Numbers) Result {
+				return NewResult(math.J1(arg[0]), nil)
+			},
+			1),
+
+		"log": NewFuncall(
+			"natural logarithm",
+			func(arg Numbers) Result {
+				return NewResult(math.Log(arg[0]), nil)
+			},
+			1),
+
+		"log10": NewFuncall(
+			"decimal logarithm",
+			func(arg Numbers) Result {
+				return NewResult(math.Log10(arg[0]), nil)
+			},
+			1),
+
+		"log1p": NewFuncall(
+			"natural logarithm of 1 + x",
+			func(arg Numbers) Result {
+				return NewResult(math.Log1p(arg[0]), nil)
+			},
+			1),
+
+		"log2": NewFuncall(
+			"binary logarithm",
+			func(arg Numbers) Result {
+				return NewResult(math.Log2(arg[0]), nil)
+			},
+			1),
+
+		"logb": NewFuncall(
+			"binary exponent",
+			func(arg Numbers) Result {
+				return NewResult(math.Logb(arg[0]), nil)
+			},
+			1),
+
+		"pow": NewFuncall(
+			"x^y",
+			func(arg Numbers) Result {
+				return NewResult(math.Pow(arg[0], arg[1]), nil)
+			},
+			2),
+
+		"round": NewFuncall(
+			"round half away from zero",
+			func(arg Numbers) Result {
+				return NewResult(math.Round(arg[0]), nil)
+			},
+			1),
+
+		"roundtoeven": NewFuncall(
+			"round half to even",
+			func(arg Numbers) Result {
+				return NewResult(math.RoundToEven(arg[0]), nil)
+			},
+			1),
+
+		"sin": NewFuncall(
+			"sine (radians)",
+			func(arg Numbers) Result {
+				return NewResult(math.Sin(arg[0]), nil)
+			},
+			1),
+
+		"sinh": NewFuncall(
+			"hyperbolic sine",
+			func(arg Numbers) Result {
+				return NewResult(math.Sinh(arg[0]), nil)
+			},
+			1),
+
+		"tan": NewFuncall(
+			"tangent (radians)",
+			func(arg Numbers) Result {
+				return NewResult(math.Tan(arg[0]), nil)
+			},
+			1),
+
+		"tanh": NewFuncall(
+			"hyperbolic tangent",
+			func(arg Numbers) Result {
+				return NewResult(math.Tanh(arg[0]), nil)
+			},
+			1),
+
+		"trunc": NewFuncall(
+			"integer part",
+			func(arg Numbers) Result {
+				return NewResult(math.Trunc(arg[0]), nil)
+			},
+			1),
+
+		"y0": NewFuncall(
+			"bessel function of the second kind, order 0",
+			func(arg Numbers) Result {
+				return NewResult(math.Y0(arg[0]), nil)
+			},
+			1),
+
+		"y1": NewFuncall(
+			"bessel function of the second kind, order 1",
+			func(arg Numbers) Result {
+				return NewResult(math.Y1(arg[0]), nil)
+			},
+			1),
+
+		"ncr": NewFuncall(
+			"combinations, n choose k",
+			func(arg Numbers) Result {
+				return NewResult(binomial(arg[0], arg[1]))
+			},
+			2),
+
+		"npr": NewFuncall(
+			"permutations of k out of n",
+			func(arg Numbers) Result {
+				return NewResult(permutations(arg[0], arg[1]))
+			},
+			2),
+
+		"multichoose": NewFuncall(
+			"combinations of k out of n with repetition",
+			func(arg Numbers) Result {
+				if arg[0] < 1 {
+					return NewResult(0, errors.New("n must be a positive integer"))
+				}
+
+				return NewResult(binomial(arg[0]+arg[1]-1, arg[1]))
+			},
+			2),
+
+		"copysign": NewFuncall(
+			"x with the sign of y",
+			func(arg Numbers) Result {
+				return NewResult(math.Copysign(arg[0], arg[1]), nil)
+			},
+			2),
+
+		"dim": NewFuncall(
+			"maximum of x-y or 0",
+			func(arg Numbers) Result {
+				return NewResult(math.Dim(arg[0], arg[1]), nil)
+			},
+			2),
+
+		"hypot": NewFuncall(
+			"sqrt(x*x + y*y)",
+			func(arg Numbers) Result {
+				return NewResult(math.Hypot(arg[0], arg[1]), nil)
+			},
+			2),
+	}
+}
+
+// converters of all kinds
+func DefineConverters() Funcalls {
+	return Funcalls{
+		"cm-to-inch": NewFuncall(
+			"convert centimeters to inches",
+			func(arg Numbers) Result {
+				return NewResult(arg[0]/2.54, nil)
+			},
+			1),
+
+		"inch-to-cm": NewFuncall(
+			"convert inches to centimeters",
+			func(arg Numbers) Result {
+				return NewResult(arg[0]*2.54, nil)
+			},
+			1),
+
+		"gallons-to-liters": NewFuncall(
+			"convert gallons to liters",
+			func(arg Numbers) Result {
+				return NewResult(arg[0]*3.785, nil)
+			},
+			1),
+
+		"liters-to-gallons": NewFuncall(
+			"convert liters to gallons",
+			func(arg Numbers) Result {
+				return NewResult(arg[0]/3.785, nil)
+			},
+			1),
+
+		"yards-to-meters": NewFuncall(
+			"convert yards to meters",
+			func(arg Numbers) Result {
+				return NewResult(arg[0]*91.44, nil)
+			},
+			1),
+
+		"meters-to-yards": NewFuncall(
+			"convert meters to yards",
+			func(arg Numbers) Result {
+				return NewResult(arg[0]/91.44, nil)
+			},
+			1),
+
+		"miles-to-kilometers": NewFuncall(
+			"convert miles to kilometers",
+			func(arg Numbers) Result {
+				return NewResult(arg[0]*1.609, nil)
+			},
+			1),
+
+		"kilometers-to-miles": NewFuncall(
+			"convert kilometers to miles",
+			func(arg Numbers) Result {
+				return NewResult(arg[0]/1.609, nil)
+			},
+			1),
+	}
+}
+
+func DefineTimeFunctions() Funcalls {
+	return Funcalls{
+		"now": NewFuncall(
+			"current time as unix timestamp",
+			func(_ Numbers) Result {
+				return NewResult(float64(TimeNow().Unix()), nil)
+			},
+			0,
+		),
+	}
+}
+
+func DefineBitwiseOperators() Funcalls {
+	return Funcalls{
+		"or": NewFuncall(
+			"bitwise or",
+			func(arg Numbers) Result {
+				return NewResult(float64(int(arg[0])|int(arg[1])), nil)
+			},
+			2),
+
+		"and": NewFuncall(
+			"bitwise and",
+			func(arg Numbers) Result {
+				return NewResult(float64(int(arg[0])&int(arg[1])), nil)
+			},
+			2),
+
+		"xor": NewFuncall(
+			"bitwise xor",
+			func(arg Numbers) Result {
+				return NewResult(float64(int(arg[0])^int(arg[1])), nil)
+			},
+			2),
+
+		"<": NewFuncall(
+			"left shift",
+			func(arg Numbers) Result {
+				// Shift by negative number provibited, so check it.
+				// Note that we check against uint64 overflow as well here
+				if arg[1] < 0 || uint64(arg[1]) > math.MaxInt64 {
+					return NewResult(0, errors.New("negative shift amount"))
+				}
+
+				return NewResult(float64(int(arg[0])<<int(arg[1])), nil)
+			},
+			2),
+
+		">": NewFuncall(
+			"right shift",
+			func(arg Numbers) Result {
+				if arg[1] < 0 || uint64(arg[1]) > math.MaxInt64 {
+					return NewResult(0, errors.New("negative shift amount"))
+				}
+
+				return NewResult(float64(int(arg[0])>>int(arg[1])), nil)
+			},
+			2),
+
+		"extract": NewFuncall(
+			"value offset length: the bitfield as unsigned value",
+			func(arg Numbers) Result {
+				word, err := toWord(arg[0])
+				if err != nil {
+					return NewResult(0, err)
+				}
+
+				mask, offset, err := bitfield(arg[1], arg[2])
+				if err != nil {
+					return NewResult(0, err)
+				}
+
+				field := word >> offset & mask
+				if uint64(float64(field)) != field {
+					return NewResult(0, errors.New("result can't be represented exactly"))
+				}
+
+				return NewResult(float64(field), nil)
+			},
+			3),
+
+		"deposit": NewFuncall(
+			"value field offset length: value with the bitfield replaced by field",
+			func(arg Numbers) Result {
+				word, err := toWord(arg[0])
+				if err != nil {
+					return NewResult(0, err)
+				}
+
+				field, err := toWord(arg[1])
+				if err != nil {
+					return NewResult(0, err)
+				}
+
+				mask, offset, err := bitfield(arg[2], arg[3])
+				if err != nil {
+					return NewResult(0, err)
+				}
+
+				if field&^mask != 0 {
+					return NewResult(0, fmt.Errorf("field %v doesn't fit into %v bits", arg[1], arg[3]))
+				}
+
+				result := word&^(mask<<offset) | field<<offset
+				if uint64(float64(result)) != result {
+					return NewResult(0, errors.New("result can't be represented exactly"))
+				}
+
+				return NewResult(float64(result), nil)
+			},
+			4),
+
+		"bitsof": NewFuncall(
+			"the IEEE-754 bit pattern of a double as unsigned value",
+			func(arg Numbers) Result {
+				bits := math.Float64bits(arg[0])
+				if uint64(float64(bits)) != bits {
+					return NewResult(0, fmt.Errorf("bit pattern 0x%016x of %v can't be represented exactly",
+						bits, arg[0]))
+				}
+
+				return NewResult(float64(bits), nil)
+			},
+			1),
+	}
+}
+
+// an integer as  machine word, negative numbers  in two's complement
+func toWord(value float64) (uint64, error) {
+	if value != math.Trunc(value) {
+		return 0, fmt.Errorf("%v is not an integer", value)
+	}
+
+	if value >= math.Exp2(float64(WordSize)) || value < -math.Exp2(float64(WordSize-1)) {
+		return 0, fmt.Errorf("%v doesn't fit into %d bits", value, WordSize)
+	}
+
+	if value < 0 {
+		return uint64(int64(value)), nil
+	}
+
+	return uint64(value), nil
+}
+
+// the mask of a bitfield of length bits starting at bit offset (0 is
+// the least significant bit), which must fit into a word
+func bitfield(offset, length float64) (uint64, uint, error) {
+	if offset != math.Trunc(offset) || length != math.Trunc(length) {
+		return 0, 0, errors.New("offset and length must be integers")
+	}
+
+	if offset < 0 || length < 1 || offset+length > float64(WordSize) {
+		return 0, 0, fmt.Errorf("invalid bitfield, offset + length must be within 1-%d", WordSize)
+	}
+
+	mask := uint64(math.MaxUint64)
+	if length < float64(WordSize) {
+		mask = 1<<uint(length) - 1
+	}
+
+	return mask, uint(offset), nil
+}
+
+func DefineBatchFunctions() Funcalls {
+	funcmap := Funcalls{
+		"median": NewFuncall(
+			"median of all values",
+			func(args Numbers) Result {
+				return NewResult(median(args), nil)
+			},
+			-1),
+
+		"madev": NewFuncall(
+			"median absolute deviation of all values",
+			func(args Numbers) Result {
+				return NewResult(medianAbsoluteDeviation(args), nil)
+			},
+			-1),
+
+		"mean": NewFuncall(
+			"mean of all values",
+			func(args Numbers) Result {
+				var sum float64
+				for _, item := range args {
+					sum += item
+				}
+
+				return NewResult(sum/float64(len(args)), nil)
+			},
+			-1),
+
+		"min": NewFuncall(
+			"min of all values",
+			func(args Numbers) Result {
+				var min float64
+				min, args = args[0], args[1:]
+				for _, item := range args {
+					if item < min {
+						min = item
+					}
+				}
+
+				return NewResult(min, nil)
+			},
+			-1),
+
+		"max": NewFuncall(
+			"max of all values",
+			func(args Numbers) Result {
+				var max float64
+				max, args = args[0], args[1:]
+				for _, item := range args {
+					if item > max {
+						max = item
+					}
+				}
+
+				return NewResult(max, nil)
+			},
+			-1),
+
+		"sum": NewFuncall(
+			"sum of all values",
+			func(args Numbers) Result {
+				var sum float64
+				for _, item := range args {
+					sum += item
+				}
+
+				return NewResult(sum, nil)
+			},
+			-1),
+	}
+
+	for _, function := range funcmap {
+		function.Category = "batch"
+	}
+
+	addAliases(funcmap, BatchAliases)
+
+	return funcmap
+}
+
+// the median of a list of numbers, which doesn't need to be sorted. If
+// the list has an even number of items, the mean of the two middle
+// ones is returned.
+func median(args Numbers) float64 {
+	sorted := make(Numbers, len(args))
+	copy(sorted, args)
+	sort.Float64s(sorted)
+
+	middle := len(sorted) / 2
+
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+
+	return sorted[middle]
+}
+
+// the sample standard deviation, 0 for a single value
+func standardDeviation(args Numbers) float64 {
+	if len(args) < 2 {
+		return 0
+	}
+
+	var sum float64
+	for _, item := range args {
+		sum += item
+	}
+
+	mean := sum / float64(len(args))
+
+	var squares float64
+	for _, item := range args {
+		squares += (item - mean) * (item - mean)
+	}
+
+	return math.Sqrt(squares / float64(len(args)-1))
+}
+
+// median of the absolute deviations from the median
+func medianAbsoluteDeviation(args Numbers) float64 {
+	center := median(args)
+	deviations := make(Numbers, len(args))
+
+	for i, item := range args {
+		deviations[i] = math.Abs(item - center)
+	}
+
+	return median(deviations)
+}
+
+// Combinatorics are computed  exactly using big integers and rounded
+// to float64 only at the end, so results up to 2^53 are exact integers
+// and larger  results are correctly rounded.  The magnitude of the
+// result is estimated first  using lgamma, so that we don't waste time
+// on results which don't fit into a float64 anyway. If it fits, the
+// number of factors is bounded, because each of them is at least 2.
+
+// validate and convert combinatoric arguments
+func combinatoricArgs(n, k float64) (int64, int64, error) {
+	if n < 0 || k < 0 || n != math.Trunc(n) || k != math.Trunc(k) ||
+		n > math.MaxInt64/2 {
+		return 0, 0, errors.New("arguments must be non-negative integers")
+	}
+
+	return int64(n), int64(k), nil
+}
+
+// the natural logarithm of n!
+func logFactorial(n int64) float64 {
+	result, _ := math.Lgamma(float64(n) + 1)
+
+	return result
+}
+
+// convert a big integer to float64, fails if it doesn't fit
+func bigToFloat(num *big.Int, name string, n, k int64) (float64, error) {
+	result, _ := new(big.Float).SetInt(num).Float64()
+	if math.IsInf(result, 0) {
+		return 0, fmt.Errorf("%s(%d, %d) exceeds float64 range", name, n, k)
+	}
+
+	return result, nil
+}
+
+// n choose k
+func binomial(nf, kf float64) (float64, error) {
+	n, k, err := combinatoricArgs(nf, kf)
+	if err != nil {
+		return 0, err
+	}
+
+	if k > n {
+		return 0, nil
+	}
+
+	k = min(k, n-k)
+
+	// + 1: don't fail on rounding errors of the estimation
+	if logFactorial(n)-logFactorial(k)-logFactorial(n-k) > math.Log(math.MaxFloat64)+1 {
+		return 0, fmt.Errorf("ncr(%d, %d) exceeds float64 range", n, k)
+	}
+
+	return bigToFloat(new(big.Int).Binomial(n, k), "ncr", n, k)
+}
+
+// number of ordered selections of k out of n: n!/(n-k)!
+func permutations(nf, kf float64) (float64, error) {
+	n, k, err := combinatoricArgs(nf, kf)
+	if err != nil {
+		return 0, err
+	}
+
+	if k > n {
+		return 0, nil
+	}
+
+	if k == 0 {
+		return 1, nil
+	}
+
+	if logFactorial(n)-logFactorial(n-k) > math.Log(math.MaxFloat64)+1 {
+		return 0, fmt.Errorf("npr(%d, %d) exceeds float64 range", n, k)
+	}
+
+	return bigToFloat(new(big.Int).MulRange(n-k+1, n), "npr", n, k)
+}
+
+// The largest arguments which don't overflow float64. Beyond them the
+// functions return +Inf, which would be rejected with the generic
+// "result is not a number", so we tell the user what the limit is.
+const (
+	MaxFactorial int     = 170
+	MaxExp       float64 = 709.782712893384 // log(MaxFloat64)
+	MaxExp2      float64 = 1024
+	MaxGamma     float64 = 171.6243769563027
+)
+
+// create an Overflow function for functions of one argument, which
+// overflow at limit
+func exponentOverflow(limit float64) func(Numbers) error {
+	return func(arg Numbers) error {
+		if arg[0] >= limit {
+			return fmt.Errorf("exponent %v exceeds float64 range (limit %.6g)", arg[0], limit)
+		}
+
+		return nil
+	}
+}
+
+func factorial(n float64) Result {
+	if n < 0 || n != math.Trunc(n) {
+		return NewResult(0, errors.New("factorial needs a non-negative integer"))
+	}
+
+	// don't loop forever, see factorialOverflow()
+	if n > float64(MaxFactorial) {
+		return NewResult(math.Inf(1), nil)
+	}
+
+	result := 1.0
+	for factor := 2.0; factor <= n; factor++ {
+		result *= factor
+	}
+
+	return NewResult(result, nil)
+}
+
+func factorialOverflow(arg Numbers) error {
+	if arg[0] > float64(MaxFactorial) {
+		return fmt.Errorf("argument %v exceeds float64 range (max %d)", arg[0], MaxFactorial)
+	}
+
+	return nil
+}
+
+func gammaOverflow(arg Numbers) error {
+	switch {
+	case arg[0] <= 0 && arg[0] == math.Trunc(arg[0]):
+		return fmt.Errorf("gamma is not defined for %v (pole)", arg[0])
+	case arg[0] > MaxGamma:
+		return fmt.Errorf("argument %v exceeds float64 range (max %.2f)", arg[0], MaxGamma)
+	}
+
+	return nil
+}
+
+func powerOverflow(arg Numbers) error {
+	base, exponent := arg[0], arg[1]
+
+	switch {
+	case math.IsInf(base, 0) || math.IsInf(exponent, 0) || math.IsNaN(base) || math.IsNaN(exponent):
+		return nil
+	case base == 0 && exponent < 0:
+		return fmt.Errorf("0 raised to the negative power %v is infinite", exponent)
+	case math.IsInf(math.Pow(base, exponent), 0):
+		return fmt.Errorf("%v^%v exceeds float64 range (max %g)", base, exponent, math.MaxFloat64)
+	}
+
+	return nil
+}
@@ -0,0 +1,182 @@
+/*
+Copyright © 2025 Thomas von Dein
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package calc
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Operands are named like on HP calculators: x is the last stack item,
+// y the one before and so on.
+var operandNames = []string{"x", "y", "z", "t"}
+
+// the signature of a function expecting count operands, e.g. atan2(y, x)
+func signature(name string, count int) string {
+	switch {
+	case count < 0:
+		return name + "(...)"
+	case count > len(operandNames):
+		return fmt.Sprintf("%s(%d args)", name, count)
+	}
+
+	names := make([]string, count)
+	for i := range count {
+		names[count-1-i] = operandNames[i]
+	}
+
+	return name + "(" + strings.Join(names, ", ") + ")"
+}
+
+// describe what the last of the tokens typed on the current line will
+// do with the stack, e.g. "atan2(y, x) — needs 2 args, stack has 1".
+// The tokens before it are not evaluated yet, so their effect on the
+// stack is estimated. Returns false for unknown tokens.
+func (c *Calc) Hint(tokens []string) (string, bool) {
+	if len(tokens) == 0 {
+		return "", false
+	}
+
+	token := tokens[len(tokens)-1]
+	depth, _ := c.EstimateDepth(c.stack.Len(), tokens[:len(tokens)-1])
+
+	arity := func(name string, count int) string {
+		switch count {
+		case -1:
+			return fmt.Sprintf("%s — uses all %d items of the stack", signature(name, count), depth)
+		case 0:
+			// lua functions which don't modify the stack
+			return fmt.Sprintf("%s — uses the last item, stack has %d", signature(name, 1), depth)
+		case 1:
+			return fmt.Sprintf("%s — needs 1 arg, stack has %d", signature(name, count), depth)
+		}
+
+		return fmt.Sprintf("%s — needs %d args, stack has %d", signature(name, count), count, depth)
+	}
+
+	kind, ok := c.Classify(token)
+	if !ok {
+		return "", false
+	}
+
+	switch kind.Name {
+	case "lua function":
+		return arity(token, c.interpreter.FuncNumArgs(token)), true
+	case "function":
+		return arity(token, c.Funcalls[token].Expectargs), true
+	case "batch function":
+		return arity(token, c.BatchFuncalls[token].Expectargs), true
+	case "command":
+		return token + " — " + c.Commands[token].Help, true
+	case "show command":
+		return token + " — " + c.ShowCommands[token].Help, true
+	case "stack command":
+		return token + " — " + c.StackCommands[token].Help, true
+	case "setting":
+		return token + " — " + c.SettingsCommands[token].Help, true
+	}
+
+	return "", false
+}
+
+// estimate the stack depth after evaluating tokens, starting with depth
+// items. Stops at the first token whose effect isn't known in advance,
+// e.g. a command, and returns false if a function needs more items than
+// the stack would have.
+func (c *Calc) EstimateDepth(depth int, tokens []string) (int, bool) {
+	for _, token := range tokens {
+		kind, ok := c.Classify(token)
+		if !ok {
+			return depth, true
+		}
+
+		count := 0
+
+		switch kind.Name {
+		case "number", "constant":
+			depth++
+
+			continue
+		case "register":
+			if !strings.HasPrefix(token, "<") {
+				return depth, true
+			}
+
+			depth++
+
+			continue
+		case "lua function":
+			count = c.interpreter.FuncNumArgs(token)
+		case "function":
+			count = c.Funcalls[token].Expectargs
+		case "batch function":
+			count = c.BatchFuncalls[token].Expectargs
+		default:
+			return depth, true
+		}
+
+		switch {
+		case count < 0:
+			// all items are replaced by the result
+			count = max(depth, 1)
+		case count == 0:
+			// lua functions which don't modify the stack
+			count, depth = 1, depth+1
+		}
+
+		if depth < count {
+			return depth, false
+		}
+
+		depth = depth - count + 1
+	}
+
+	return depth, true
+}
+
+// does EstimateDepth() know the effect of token on the stack
+func (c *Calc) KnownEffect(token string) bool {
+	kind, ok := c.Classify(token)
+	if !ok {
+		return false
+	}
+
+	switch kind.Name {
+	case "number", "constant", "lua function", "function", "batch function":
+		return true
+	case "register":
+		return strings.HasPrefix(token, "<")
+	}
+
+	return false
+}
+
+// the tokens up to the one just finished by typing a space, used by
+// the repl to show hints while typing
+func finishedToken(line []rune, pos int, key rune) ([]string, bool) {
+	if key != ' ' || pos < 2 || pos > len(line) {
+		return nil, false
+	}
+
+	fields := strings.Fields(string(line[:pos-1]))
+	if len(fields) == 0 || line[pos-2] == ' ' {
+		return nil, false
+	}
+
+	return fields, true
+}
@@ -0,0 +1,358 @@
+/*
+Copyright © 2023 Thomas von Dein
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package calc
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+type Interpreter struct {
+	debug  bool
+	script string
+	out    io.Writer // debug output
+}
+
+// LuaInterpreter is the lua interpreter, instantiated in Main()
+var LuaInterpreter *lua.LState
+
+// holds a user provided lua function
+type LuaFunction struct {
+	name    string
+	help    string
+	numargs int
+}
+
+// LuaFuncs must be global since init() is being called from lua which
+// doesn't have access to the interpreter instance
+var LuaFuncs map[string]LuaFunction
+
+// a unit conversion registered with register_conversion(), converting
+// from to to: from * factor + offset
+type LuaConversion struct {
+	from   string
+	to     string
+	factor float64
+	offset float64
+	help   string
+}
+
+// same as LuaFuncs
+var LuaConversions []LuaConversion
+
+func NewInterpreter(script string, debug bool) *Interpreter {
+	return &Interpreter{debug: debug, script: script, out: os.Stdout}
+}
+
+// initialize the lua environment properly
+func (i *Interpreter) InitLua() {
+	// we only  load a subset of lua Open  modules and don't allow
+	// net, system or io stuff
+	for _, pair := range []struct {
+		n string
+		f lua.LGFunction
+	}{
+		{lua.LoadLibName, lua.OpenPackage},
+		{lua.BaseLibName, lua.OpenBase},
+		{lua.TabLibName, lua.OpenTable},
+		{lua.DebugLibName, lua.OpenDebug},
+		{lua.MathLibName, lua.OpenMath},
+		{lua.StringLibName, lua.OpenString},
+	} {
+		if err := LuaInterpreter.CallByParam(lua.P{
+			Fn:      LuaInterpreter.NewFunction(pair.f),
+			NRet:    0,
+			Protect: true,
+		}, lua.LString(pair.n)); err != nil {
+			panic(err)
+		}
+	}
+
+	// load the lua config (which we expect to contain init() and math functions)
+	if err := LuaInterpreter.DoFile(i.script); err != nil {
+		panic(err)
+	}
+
+	// instantiate
+	LuaFuncs = map[string]LuaFunction{}
+	LuaConversions = []LuaConversion{}
+
+	// that way the user can call register(...) from lua inside init()
+	LuaInterpreter.SetGlobal("register", LuaInterpreter.NewFunction(register))
+	LuaInterpreter.SetGlobal("register_conversion",
+		LuaInterpreter.NewFunction(registerConversion))
+
+	// actually call init()
+	if err := LuaInterpreter.CallByParam(lua.P{
+		Fn:      LuaInterpreter.GetGlobal("init"),
+		NRet:    0,
+		Protect: true,
+	}); err != nil {
+		panic(err)
+	}
+}
+
+func (i *Interpreter) Debug(msg string) {
+	if i.debug {
+		fmt.Fprintf(i.out, "DEBUG(lua): %s\n", msg)
+	}
+}
+
+func (i *Interpreter) FuncNumArgs(name string) int {
+	return LuaFuncs[name].numargs
+}
+
+// Call a user provided math function registered with register().
+//
+// Each function has  to tell us how many args  it expects, the actual
+// function call  from here  is different depending  on the  number of
+// arguments. 1 uses the last item of the stack, 2 the last two and -1
+// all items (which translates to batch mode)
+//
+// The  items  array  will  be  provided  by  calc.Eval(),  these  are
+// non-popped stack  items. So  the items will  only removed  from the
+// stack when the lua function execution is successful.
+func (i *Interpreter) CallLuaFunc(funcname string, items []float64) (float64, error) {
+	i.Debug(fmt.Sprintf("calling lua func %s() with %d args",
+		funcname, LuaFuncs[funcname].numargs))
+
+	// at least one item, even for batch functions, like DoFuncall()
+	if len(items) < max(LuaFuncs[funcname].numargs, 1) {
+		return 0, errors.New("stack doesn't provide enough arguments")
+	}
+
+	switch LuaFuncs[funcname].numargs {
+	case 0, 1:
+		// 1 arg variant
+		if err := LuaInterpreter.CallByParam(lua.P{
+			Fn:      LuaInterpreter.GetGlobal(funcname),
+			NRet:    1,
+			Protect: true,
+		}, lua.LNumber(items[0])); err != nil {
+			return 0, fmt.Errorf("failed to exec lua func %s: %w", funcname, err)
+		}
+	case 2:
+		// 2 arg variant
+		if err := LuaInterpreter.CallByParam(lua.P{
+			Fn:      LuaInterpreter.GetGlobal(funcname),
+			NRet:    1,
+			Protect: true,
+		}, lua.LNumber(items[0]), lua.LNumber(items[1])); err != nil {
+			return 0, fmt.Errorf("failed to exec lua func %s: %w", funcname, err)
+		}
+	case -1:
+		// batch variant, use lua table as array
+		table := LuaInterpreter.NewTable()
+
+		// put the whole stack into it
+		for _, item := range items {
+			table.Append(lua.LNumber(item))
+		}
+
+		if err := LuaInterpreter.CallByParam(lua.P{
+			Fn:      LuaInterpreter.GetGlobal(funcname),
+			NRet:    1,
+			Protect: true,
+		}, table); err != nil {
+			return 0, fmt.Errorf("failed to exec lua func %s: %w", funcname, err)
+		}
+	}
+
+	// get result and cast to float64
+	if res, ok := LuaInterpreter.Get(-1).(lua.LNumber); ok {
+		LuaInterpreter.Pop(1)
+
+		return float64(res), nil
+	}
+
+	return 0, errors.New("function did not return a float64")
+}
+
+// Call the optional  lua function format_result(value),  which returns
+// the result formatted  as string. ok is false if there  is no such
+// function or if it failed, in which case the default format is used.
+func (i *Interpreter) FormatResult(value float64) (string, bool) {
+	hook, isfunction := LuaInterpreter.GetGlobal("format_result").(*lua.LFunction)
+	if !isfunction {
+		return "", false
+	}
+
+	if err := LuaInterpreter.CallByParam(lua.P{
+		Fn:      hook,
+		NRet:    1,
+		Protect: true,
+	}, lua.LNumber(value)); err != nil {
+		i.Debug(fmt.Sprintf("format_result() failed: %s", err))
+
+		return "", false
+	}
+
+	result := LuaInterpreter.Get(-1)
+	LuaInterpreter.Pop(1)
+
+	if text, ok := result.(lua.LString); ok {
+		return string(text), true
+	}
+
+	i.Debug("format_result() did not return a string")
+
+	return "", false
+}
+
+// the state of the calculator handed to the lua function prompt(state)
+type PromptState struct {
+	Stack     Numbers
+	Batch     bool
+	Debug     bool
+	Precision int
+}
+
+// Call the optional lua function prompt(state), which returns the
+// prompt as string. state is a table containing the stack (1 is the
+// bottom), its depth, the top item (nil if the stack is empty), the
+// batch and debug modes and the precision. ok is false if there is no
+// such function, err tells why it failed.
+func (i *Interpreter) Prompt(state PromptState) (string, bool, error) {
+	hook, isfunction := LuaInterpreter.GetGlobal("prompt").(*lua.LFunction)
+	if !isfunction {
+		return "", false, nil
+	}
+
+	stack := LuaInterpreter.NewTable()
+	for _, item := range state.Stack {
+		stack.Append(lua.LNumber(item))
+	}
+
+	table := LuaInterpreter.NewTable()
+	table.RawSetString("stack", stack)
+	table.RawSetString("depth", lua.LNumber(len(state.Stack)))
+	table.RawSetString("batch", lua.LBool(state.Batch))
+	table.RawSetString("debug", lua.LBool(state.Debug))
+	table.RawSetString("precision", lua.LNumber(state.Precision))
+
+	if len(state.Stack) > 0 {
+		table.RawSetString("top", lua.LNumber(state.Stack[len(state.Stack)-1]))
+	}
+
+	if err := LuaInterpreter.CallByParam(lua.P{
+		Fn:      hook,
+		NRet:    1,
+		Protect: true,
+	}, table); err != nil {
+		return "", true, fmt.Errorf("prompt() failed: %w", err)
+	}
+
+	result := LuaInterpreter.Get(-1)
+	LuaInterpreter.Pop(1)
+
+	text, ok := result.(lua.LString)
+	if !ok {
+		return "", true, fmt.Errorf("prompt() returned %s instead of a string", result.Type())
+	}
+
+	return string(text), true, nil
+}
+
+// the optional global variable prompt contains a prompt template, see
+// Calc.Prompt()
+func (i *Interpreter) PromptTemplate() (string, bool) {
+	template, ok := LuaInterpreter.GetGlobal("prompt").(lua.LString)
+
+	return string(template), ok
+}
+
+// the optional global variable persist enables session persistence,
+// see Calc.SaveSession()
+func (i *Interpreter) PersistSetting() bool {
+	persist, ok := LuaInterpreter.GetGlobal("persist").(lua.LBool)
+
+	return ok && bool(persist)
+}
+
+// called from lua to register a math  function numargs may be 1, 2 or
+// -1, it denotes the number of  items from the stack requested by the
+// lua function. -1 means batch mode, that is all items
+func register(lstate *lua.LState) int {
+	function := lstate.ToString(1)
+	numargs := lstate.ToInt(2)
+	help := lstate.ToString(3)
+
+	LuaFuncs[function] = LuaFunction{
+		name:    function,
+		numargs: numargs,
+		help:    help,
+	}
+
+	return 1
+}
+
+// called from lua to register a unit conversion, e.g.:
+// register_conversion("furlong", "meters", 201.168, "furlongs to meters")
+// The optional 5th parameter is an offset added after multiplying with
+// the factor, which is needed for temperatures.
+func registerConversion(lstate *lua.LState) int {
+	conversion := LuaConversion{
+		from:   lstate.CheckString(1),
+		to:     lstate.CheckString(2),
+		factor: float64(lstate.CheckNumber(3)),
+		help:   lstate.OptString(4, ""),
+		offset: float64(lstate.OptNumber(5, 0)),
+	}
+
+	if conversion.factor == 0 {
+		lstate.ArgError(3, "factor must not be zero")
+	}
+
+	LuaConversions = append(LuaConversions, conversion)
+
+	return 0
+}
+
+// create Funcalls for both directions of the conversion
+func (conversion LuaConversion) Funcalls() Funcalls {
+	help := conversion.help
+	if help == "" {
+		help = fmt.Sprintf("%s to %s", conversion.from, conversion.to)
+	}
+
+	forward := NewFuncall(
+		"convert "+help,
+		func(arg Numbers) Result {
+			return NewResult(arg[0]*conversion.factor+conversion.offset, nil)
+		},
+		1)
+
+	inverse := NewFuncall(
+		fmt.Sprintf("convert %s to %s", conversion.to, conversion.from),
+		func(arg Numbers) Result {
+			return NewResult((arg[0]-conversion.offset)/conversion.factor, nil)
+		},
+		1)
+
+	forward.Category = "converter"
+	inverse.Category = "converter"
+
+	return Funcalls{
+		conversion.from + "-to-" + conversion.to: forward,
+		conversion.to + "-to-" + conversion.from: inverse,
+	}
+}
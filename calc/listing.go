@@ -0,0 +1,144 @@
+/*
+Copyright © 2025 Thomas von Dein
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package calc
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// A machine readable  description of every token rpn  knows about, to
+// be used by  external tooling like editor plugins.  The field names
+// are part of the interface, don't change them.
+type FunctionInfo struct {
+	Name     string `json:"name"`
+	Category string `json:"category"`
+	Arity    int    `json:"arity"` // -1 means the whole stack
+	Help     string `json:"help"`
+	Alias    string `json:"alias,omitempty"` // the canonical name, if this is an alias
+}
+
+// collect all known tokens, sorted by name and category
+func (c *Calc) ListFunctions() []FunctionInfo {
+	list := []FunctionInfo{}
+
+	for _, group := range []struct {
+		functions Funcalls
+		aliases   map[string]string
+	}{
+		{c.Funcalls, FunctionAliases},
+		{c.BatchFuncalls, BatchAliases},
+	} {
+		for name, function := range group.functions {
+			list = append(list, FunctionInfo{
+				Name:     name,
+				Category: function.Category,
+				Arity:    function.Expectargs,
+				Help:     function.Help,
+				Alias:    group.aliases[name],
+			})
+		}
+	}
+
+	for _, group := range []struct {
+		category string
+		commands Commands
+	}{
+		{"setting", c.SettingsCommands},
+		{"show", c.ShowCommands},
+		{"stack", c.StackCommands},
+		{"command", c.Commands},
+	} {
+		for name, command := range group.commands {
+			list = append(list, FunctionInfo{
+				Name:     name,
+				Category: group.category,
+				Arity:    command.Expectargs,
+				Help:     command.Help,
+				Alias:    CommandAliases[name],
+			})
+		}
+	}
+
+	// handled directly in EvalItem()
+	for _, name := range []string{"?", "help"} {
+		list = append(list, FunctionInfo{
+			Name:     name,
+			Category: "command",
+			Help:     "show this message",
+		})
+	}
+
+	for _, name := range c.Constants {
+		list = append(list, FunctionInfo{
+			Name:     name,
+			Category: "constant",
+			Help:     strconv.FormatFloat(const2num(name), 'g', -1, 64),
+		})
+	}
+
+	for name, function := range LuaFuncs {
+		list = append(list, FunctionInfo{
+			Name:     name,
+			Category: "lua",
+			Arity:    function.numargs,
+			Help:     function.help,
+		})
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Name == list[j].Name {
+			return list[i].Category < list[j].Category
+		}
+
+		return list[i].Name < list[j].Name
+	})
+
+	return list
+}
+
+// print the list of tokens either as a table or as JSON
+func (c *Calc) PrintFunctions(format string) error {
+	list := c.ListFunctions()
+
+	switch format {
+	case "json":
+		out, err := json.MarshalIndent(list, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintln(c.out, string(out))
+	case "text":
+		for _, function := range list {
+			help := function.Help
+			if function.Alias != "" {
+				help += " (alias of " + function.Alias + ")"
+			}
+
+			fmt.Fprintf(c.out, "%-20s %-10s %2d  %s\n",
+				function.Name, function.Category, function.Arity, help)
+		}
+	default:
+		return fmt.Errorf("unsupported format %s", format)
+	}
+
+	return nil
+}
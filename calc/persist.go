@@ -0,0 +1,444 @@
+/*
+Copyright © 2025 Thomas von Dein
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package calc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The  stack file  format is  as  simple as  possible: one  number per
+// line, with full precision, so that the values survive a round trip
+// unchanged. Empty lines and comments (#) are ignored when reading.
+
+func WriteStack(writer io.Writer, items Numbers) error {
+	for _, item := range items {
+		if _, err := fmt.Fprintln(writer, strconv.FormatFloat(item, 'g', -1, 64)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func ReadStack(reader io.Reader) (Numbers, error) {
+	items := Numbers{}
+	scanner := bufio.NewScanner(reader)
+	line := 0
+
+	for scanner.Scan() {
+		line++
+
+		text := strings.TrimSpace(scanner.Text())
+		if idx := strings.Index(text, "#"); idx >= 0 {
+			text = strings.TrimSpace(text[:idx])
+		}
+
+		if text == "" {
+			continue
+		}
+
+		num, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s is not a number", line, text)
+		}
+
+		items = append(items, num)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// write the whole stack to the given file, - means stdout
+func (c *Calc) SaveStackFile(filename string) error {
+	if filename == "-" {
+		return WriteStack(c.out, c.stack.All())
+	}
+
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("failed to write stack: %w", err)
+	}
+	defer file.Close()
+
+	if err := WriteStack(file, c.stack.All()); err != nil {
+		return fmt.Errorf("failed to write stack: %w", err)
+	}
+
+	return nil
+}
+
+// push the contents of the given stack file, - means stdin
+func (c *Calc) LoadStackFile(filename string) error {
+	var reader io.Reader = os.Stdin
+
+	if filename != "-" {
+		file, err := os.Open(filename)
+		if err != nil {
+			return fmt.Errorf("failed to read stack: %w", err)
+		}
+		defer file.Close()
+
+		reader = file
+	}
+
+	items, err := ReadStack(reader)
+	if err != nil {
+		return fmt.Errorf("failed to read stack from %s: %w", filename, err)
+	}
+
+	c.stack.Backup()
+
+	for _, item := range items {
+		c.stack.Push(item)
+	}
+
+	return nil
+}
+
+// the state written by jsondump and read by jsonload, notes are only
+// included if there are any
+type JSONState struct {
+	Stack     Numbers            `json:"stack"`
+	Vars      map[string]float64 `json:"vars"`
+	Precision int                `json:"precision"`
+	Notes     []Note             `json:"notes,omitempty"`
+}
+
+// print the stack, variables and precision as JSON in one line
+func (c *Calc) DumpJSON() error {
+	out, err := json.Marshal(JSONState{
+		Stack:     c.stack.All(),
+		Vars:      c.Vars,
+		Precision: c.precision,
+		Notes:     c.notes,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to dump stack: %w", err)
+	}
+
+	fmt.Fprintln(c.out, string(out))
+
+	return nil
+}
+
+// replace the stack with the contents of a JSON array or the output of
+// jsondump, in which case the variables and precision are restored as
+// well. Nothing is modified if the input is invalid.
+func (c *Calc) LoadJSON(input []byte) error {
+	state := JSONState{Precision: c.precision}
+
+	input = bytes.TrimSpace(input)
+	if bytes.HasPrefix(input, []byte("{")) {
+		if err := json.Unmarshal(input, &state); err != nil {
+			return fmt.Errorf("invalid json: %w", err)
+		}
+	} else if err := json.Unmarshal(input, &state.Stack); err != nil {
+		return fmt.Errorf("invalid json: %w", err)
+	}
+
+	if state.Precision < 0 || state.Precision > MaxPrecision {
+		return fmt.Errorf("invalid precision %d, use 0-%d", state.Precision, MaxPrecision)
+	}
+
+	// undo reverts the variables, precision and notes as well
+	vars, precision, notes := maps.Clone(c.Vars), c.precision, slices.Clone(c.notes)
+
+	c.stack.BackupWith(func() {
+		c.Vars, c.precision, c.notes = maps.Clone(vars), precision, slices.Clone(notes)
+	})
+	c.stack.Clear()
+
+	for _, item := range state.Stack {
+		c.stack.Push(item)
+	}
+
+	for name, value := range state.Vars {
+		c.Vars[name] = value
+	}
+
+	c.precision = state.Precision
+	c.notes = append(c.notes, state.Notes...)
+	c.StackHistory("jsonload: %d items", len(state.Stack))
+
+	return nil
+}
+
+// jsonload FILE  reads the given  file, without  a file (or  -) the
+// next input line is being loaded
+func CommandJSONLoad(c *Calc, args []string) error {
+	if len(args) == 0 || args[0] == "-" {
+		c.loadnext = true
+
+		return nil
+	}
+
+	input, err := os.ReadFile(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to read stack: %w", err)
+	}
+
+	return c.LoadJSON(input)
+}
+
+// names of saved stacks end up as filenames, so they must not contain
+// path separators or start with a dot
+var stackName = regexp.MustCompile(`^[a-zA-Z0-9_][a-zA-Z0-9_.-]*$`)
+
+// saved stacks live in $XDG_DATA_HOME/rpn/stacks if set, otherwise in
+// ~/.rpn/stacks
+func StackDir() string {
+	if datadir := os.Getenv("XDG_DATA_HOME"); datadir != "" {
+		return filepath.Join(datadir, "rpn", "stacks")
+	}
+
+	return homeFile(".rpn", "stacks")
+}
+
+func StackPath(name string) (string, error) {
+	if !stackName.MatchString(name) {
+		return "", fmt.Errorf("invalid stack name %s, use letters, digits, _, . and -", name)
+	}
+
+	if StackDir() == "" {
+		return "", ErrNoHome
+	}
+
+	return filepath.Join(StackDir(), name+".json"), nil
+}
+
+// save NAME: write the stack and variables in the jsondump format
+func CommandSave(c *Calc, args []string) error {
+	path, err := StackPath(args[0])
+	if err != nil {
+		return err
+	}
+
+	out, err := json.Marshal(JSONState{
+		Stack:     c.stack.All(),
+		Vars:      c.Vars,
+		Precision: c.precision,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save stack: %w", err)
+	}
+
+	if err := os.MkdirAll(StackDir(), 0o700); err != nil {
+		return fmt.Errorf("failed to save stack: %w", err)
+	}
+
+	if err := os.WriteFile(path, append(out, '\n'), 0o600); err != nil {
+		return fmt.Errorf("failed to save stack: %w", err)
+	}
+
+	if !c.quiet {
+		fmt.Fprintf(c.out, "saved %d items to %s\n", c.stack.Len(), args[0])
+	}
+
+	return nil
+}
+
+// load NAME: replace the stack with a saved one, undo brings back the
+// previous stack. A corrupt file leaves everything untouched.
+func CommandLoad(c *Calc, args []string) error {
+	path, err := StackPath(args[0])
+	if err != nil {
+		return err
+	}
+
+	input, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("no saved stack %s", args[0])
+		}
+
+		return fmt.Errorf("failed to load stack: %w", err)
+	}
+
+	if err := c.LoadJSON(input); err != nil {
+		return fmt.Errorf("failed to load stack %s: %w", args[0], err)
+	}
+
+	return nil
+}
+
+// the names of all saved stacks, sorted
+func SavedStacks() ([]string, error) {
+	if StackDir() == "" {
+		return nil, ErrNoHome
+	}
+
+	files, err := os.ReadDir(StackDir())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	names := []string{}
+
+	for _, file := range files {
+		name, ok := strings.CutSuffix(file.Name(), ".json")
+		if ok && !file.IsDir() && stackName.MatchString(name) {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+
+	return names, nil
+}
+
+func CommandStacks(c *Calc, _ []string) error {
+	names, err := SavedStacks()
+	if err != nil {
+		return fmt.Errorf("failed to list stacks: %w", err)
+	}
+
+	if len(names) == 0 {
+		fmt.Fprintln(c.out, "no saved stacks")
+
+		return nil
+	}
+
+	for _, name := range names {
+		fmt.Fprintln(c.out, name)
+	}
+
+	return nil
+}
+
+// The session saved on exit with --persist. The version is increased
+// on incompatible changes, files of other versions are ignored.
+const SessionVersion = 1
+
+type Session struct {
+	Version int                `json:"version"`
+	Stack   Numbers            `json:"stack"`
+	Vars    map[string]float64 `json:"vars"`
+	History []HistoryEntry     `json:"history"`
+	Notes   []Note             `json:"notes,omitempty"`
+}
+
+// the session lives in $XDG_STATE_HOME/rpn if set, otherwise in
+// ~/.local/state/rpn
+func SessionFile() string {
+	if statedir := os.Getenv("XDG_STATE_HOME"); statedir != "" {
+		return filepath.Join(statedir, "rpn", "session.json")
+	}
+
+	return homeFile(".local", "state", "rpn", "session.json")
+}
+
+// write stack, variables, history and notes, the file is replaced atomically,
+// so that a crash can't leave a truncated session behind
+func (c *Calc) SaveSession(filename string) error {
+	if filename == "" {
+		return fmt.Errorf("failed to save session: %w", ErrNoHome)
+	}
+
+	out, err := json.Marshal(Session{
+		Version: SessionVersion,
+		Stack:   c.stack.All(),
+		Vars:    c.Vars,
+		History: c.history,
+		Notes:   c.notes,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filename), 0o700); err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+
+	tmpfile := filename + ".tmp"
+
+	if err := os.WriteFile(tmpfile, append(out, '\n'), 0o600); err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+
+	if err := os.Rename(tmpfile, filename); err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+
+	return nil
+}
+
+// restore a saved session, a missing file is not an error, there's
+// just nothing to restore yet. Nothing is modified if the file is
+// corrupt or of another version.
+func (c *Calc) LoadSession(filename string) error {
+	if filename == "" {
+		return nil
+	}
+
+	input, err := os.ReadFile(filename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+
+		return fmt.Errorf("failed to load session: %w", err)
+	}
+
+	session := Session{}
+
+	if err := json.Unmarshal(input, &session); err != nil {
+		return fmt.Errorf("ignoring corrupt session %s: %w", filename, err)
+	}
+
+	if session.Version != SessionVersion {
+		return fmt.Errorf("ignoring session %s of unsupported version %d", filename, session.Version)
+	}
+
+	for _, item := range session.Stack {
+		c.stack.Push(item)
+	}
+
+	for name, value := range session.Vars {
+		c.Vars[name] = value
+	}
+
+	c.history = session.History
+	c.notes = append(c.notes, session.Notes...)
+
+	if c.historylimit > 0 && len(c.history) > c.historylimit {
+		c.history = c.history[len(c.history)-c.historylimit:]
+	}
+
+	return nil
+}
@@ -0,0 +1,243 @@
+/*
+Copyright © 2025 Thomas von Dein
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package calc
+
+import (
+	"io"
+	"math/rand"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"testing/quick"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// Invariants of the calculation engine, checked using random stacks
+// and operations, see testing/quick.
+
+// the operands of the generated calculations: small integers, which are
+// valid arguments for most functions, and fractions. There's always at
+// least one of them.
+type operands Numbers
+
+func (operands) Generate(rand *rand.Rand, size int) reflect.Value {
+	items := make(operands, rand.Intn(size)+1)
+
+	for pos := range items {
+		if rand.Intn(2) == 0 {
+			items[pos] = float64(rand.Intn(41) - 20)
+		} else {
+			items[pos] = (rand.Float64() - 0.5) * 200
+		}
+	}
+
+	return reflect.ValueOf(items)
+}
+
+// at least count operands, missing ones are filled up with 1
+func (items operands) atLeast(count int) operands {
+	for len(items) < count {
+		items = append(items, 1)
+	}
+
+	return items
+}
+
+// a calculator with the given items on the stack, entered one by one
+// like numbers typed by the user
+func propertyCalc(items operands) *Calc {
+	calc := NewCalcWriter(io.Discard)
+
+	for _, item := range items {
+		calc.stack.Backup()
+		calc.stack.Push(item)
+	}
+
+	return calc
+}
+
+func sortedNames(funcmap Funcalls) []string {
+	names := []string{}
+
+	for name := range funcmap {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+func TestPropertyUndo(t *testing.T) {
+	// all of them succeed with at least 4 items on the stack
+	operations := append(sortedNames(NewCalc().Funcalls),
+		"swap", "dup", "shift", "reverse", "clear", "over", "nip", "rot",
+		"depth", "pick 2", "roll 3", "dupn 2")
+
+	property := func(items operands, choice uint16) bool {
+		calc := propertyCalc(items.atLeast(4))
+		operation := operations[int(choice)%len(operations)]
+		before := calc.stack.All()
+
+		if err := calc.Eval(operation); err != nil {
+			// a failed operation must leave the stack untouched
+			return reflect.DeepEqual(calc.stack.All(), before)
+		}
+
+		if err := calc.Eval("undo"); err != nil {
+			return false
+		}
+
+		if !reflect.DeepEqual(calc.stack.All(), before) {
+			t.Logf("undo after %s failed:\n+++  got: %v\n--- want: %v",
+				operation, calc.stack.All(), before)
+
+			return false
+		}
+
+		return true
+	}
+
+	if err := quick.Check(property, &quick.Config{MaxCount: 2000}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPropertyBackupRestore(t *testing.T) {
+	property := func(items operands, mutations []byte) bool {
+		stack := NewStack()
+
+		for _, item := range items {
+			stack.Push(item)
+		}
+
+		before := stack.All()
+		stack.Backup()
+
+		for _, mutation := range mutations {
+			switch mutation % 6 {
+			case 0:
+				stack.Push(float64(mutation))
+			case 1:
+				stack.Shift()
+			case 2:
+				stack.Shift(int(mutation) % 5)
+			case 3:
+				stack.Swap()
+			case 4:
+				stack.Reverse()
+			case 5:
+				stack.Clear()
+			}
+		}
+
+		stack.Restore()
+
+		return reflect.DeepEqual(stack.All(), before)
+	}
+
+	if err := quick.Check(property, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPropertyFuncallArity(t *testing.T) {
+	for _, name := range sortedNames(NewCalc().Funcalls) {
+		property := func(items operands) bool {
+			function := NewCalc().Funcalls[name]
+			calc := propertyCalc(items.atLeast(function.Expectargs)[:function.Expectargs])
+			before := calc.stack.All()
+
+			if err := calc.Eval(name); err != nil {
+				return reflect.DeepEqual(calc.stack.All(), before)
+			}
+
+			return calc.stack.Len() == 1
+		}
+
+		if err := quick.Check(property, nil); err != nil {
+			t.Errorf("function %s: %s", name, err)
+		}
+	}
+}
+
+func TestPropertyBatchFunctions(t *testing.T) {
+	for _, name := range sortedNames(NewCalc().BatchFuncalls) {
+		if NewCalc().BatchFuncalls[name].Expectargs != -1 {
+			continue
+		}
+
+		property := func(items operands) bool {
+			calc := propertyCalc(items)
+			calc.batch = true
+			before := calc.stack.All()
+
+			if err := calc.Eval(name); err != nil {
+				return reflect.DeepEqual(calc.stack.All(), before)
+			}
+
+			return calc.stack.Len() == 1
+		}
+
+		if err := quick.Check(property, nil); err != nil {
+			t.Errorf("batch function %s: %s", name, err)
+		}
+	}
+}
+
+func TestPropertyLuaFunctions(t *testing.T) {
+	LuaInterpreter = lua.NewState(lua.Options{SkipOpenLibs: true})
+	defer LuaInterpreter.Close()
+
+	luarunner := NewInterpreter(filepath.Join("..", "example.lua"), false)
+	luarunner.InitLua()
+
+	names := NewCalc()
+	names.SetInt(luarunner)
+	sort.Strings(names.LuaFunctions)
+
+	for _, name := range names.LuaFunctions {
+		numargs := luarunner.FuncNumArgs(name)
+
+		// count picks the stack size, including too small ones
+		property := func(items operands, count uint8) bool {
+			calc := propertyCalc(items[:int(count)%(len(items)+1)])
+			calc.SetInt(luarunner)
+			before := calc.stack.All()
+
+			if err := calc.Eval(name); err != nil {
+				return reflect.DeepEqual(calc.stack.All(), before)
+			}
+
+			switch numargs {
+			case -1:
+				return calc.stack.Len() == 1
+			case 0, 1:
+				return calc.stack.Len() == len(before)
+			}
+
+			return calc.stack.Len() == len(before)-numargs+1
+		}
+
+		if err := quick.Check(property, nil); err != nil {
+			t.Errorf("lua function %s: %s", name, err)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+/*
+Copyright © 2025 Thomas von Dein
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package calc
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// where the repl gets its input lines from, implemented by readline
+// and by PlainReader
+type LineReader interface {
+	Readline() (string, error)
+	SetPrompt(prompt string)
+}
+
+// line reader for terminals which don't understand the escape
+// sequences of readline, e.g. TERM=dumb in an emacs shell. There's no
+// completion, no history and no line editing beyond what the terminal
+// itself provides.
+type PlainReader struct {
+	input  *bufio.Reader
+	out    io.Writer
+	prompt string
+}
+
+func NewPlainReader(input io.Reader, out io.Writer, prompt string) *PlainReader {
+	return &PlainReader{
+		input:  bufio.NewReader(input),
+		out:    out,
+		prompt: prompt,
+	}
+}
+
+// print the prompt and read the next line, io.EOF at the end of the
+// input, just like readline
+func (reader *PlainReader) Readline() (string, error) {
+	fmt.Fprint(reader.out, reader.prompt)
+
+	line, err := reader.input.ReadString('\n')
+	if err == io.EOF && line != "" {
+		// the last line isn't terminated, the next call returns EOF
+		err = nil
+	}
+
+	return strings.TrimRight(line, "\r\n"), err
+}
+
+func (reader *PlainReader) SetPrompt(prompt string) {
+	reader.prompt = prompt
+}
+
+// readline garbles the output on terminals without cursor movement
+func dumbTerminal() bool {
+	term := os.Getenv("TERM")
+
+	return term == "" || term == "dumb"
+}
+
+// the main loop, evaluate line by line until the input ends or the
+// session is exited. Returns whether any line failed while reading
+// stdin and whether we gave up because of too many read errors.
+func RunRepl(calc *Calc, reader LineReader) (bool, bool) {
+	state := &ReplState{}
+
+	// any error makes the exit status non-zero when reading stdin
+	failed := false
+
+	for {
+		line, err := reader.Readline()
+
+		action, message := state.Next(err)
+		if message != "" {
+			fmt.Fprintln(calc.errout, message)
+		}
+
+		switch action {
+		case ReplQuit:
+			return failed, false
+		case ReplAbort:
+			return failed, true
+		case ReplSkip:
+			continue
+		}
+
+		err = calc.Eval(line)
+		if err != nil {
+			calc.PrintError(err)
+
+			if calc.stdin {
+				failed = true
+			}
+		}
+
+		if err := calc.EndLine(); err != nil {
+			calc.PrintError(err)
+
+			if calc.stdin {
+				failed = true
+			}
+		}
+
+		if calc.quit {
+			return failed, false
+		}
+
+		reader.SetPrompt(calc.Prompt())
+	}
+}
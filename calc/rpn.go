@@ -0,0 +1,1292 @@
+package calc
+
+var manpage = `
+NAME
+    rpn - Programmable command-line calculator using reverse polish notation
+
+SYNOPSIS
+        Usage: rpn [-bdqvh] [<operator>]
+    
+        Options:
+          -b, --batchmode       enable batch mode
+          -d, --debug           enable debug mode
+          -s, --stack           show last 5 items of the stack (off by default)
+          --stack-size <int>    number of items shown with -s (default 5)
+          -i  --intermediate    print intermediate results
+          -m, --manual          show manual
+          -c, --config <file>   load <file> containing LUA code
+          -f, --file <file>     evaluate <file> line by line, results are prefixed with file:line:
+          --errors-only         only print errors, no results
+          -p, --precision <int> floating point number precision (default 2)
+          -M, --money           money mode: round every result to cents
+          -q, --quiet           don't confirm changes made by stack commands
+          --stack-in <file>     load the initial stack from <file> (- for stdin)
+          --stack-out <file>    write the final stack to <file> (- for stdout)
+          --print-final         print the last stack item on exit, if not yet done
+          --print-stack-on-exit print the whole stack on exit instead of results
+          --paragraph-mode      stdin: empty lines separate independent calculations
+          --line                stdin: every line is an independent calculation
+          --every <int>         stdin: apply the operator to every <int> numbers read
+          --drop-partial        with --every: ignore the last window if it isn't full
+          --color <mode>        colored output: auto (default), always or never
+          --history-limit <int> max number of history entries (default 10000, 0: unlimited)
+          --persist             restore stack, variables and history of the last session
+          --summary             print count, min, max, mean, median, stddev and sum
+                                of the stack on exit
+          --list-functions      list all functions, commands and constants
+          --format <format>     output format of the list and summary: text or json
+          -v, --version         show version
+          -h, --help            show help
+    
+        Input on stdin is evaluated first, then the arguments are applied to
+        the resulting stack, e.g.: echo 5 | rpn 2 +. If the only argument is a
+        batch function, batch mode is enabled. E.g.: echo "2 3 4 5" | rpn +
+        Arguments which are a complete calculation like 2 3 + don't read stdin.
+
+DESCRIPTION
+    rpn is a command line calculator using reverse polish notation.
+
+  Working principle
+    Reverse Polish Notation (short: RPN) requires to have a stack where
+    numbers and results are being put. So, you put numbers onto the stack
+    and each math operation uses these for calculation, removes them and
+    puts the result back.
+
+    To visualize it, let's look at a calculation:
+
+        ((80 + 20) / 2) * 4
+
+    This is how you enter the formula int an RPN calculator and how the
+    stack evolves during the operation:
+
+        | rpn commands | stack contents | calculation   |
+        |--------------|----------------|---------------|
+        |           80 |             80 |               |
+        |           20 |          80 20 |               |
+        |            + |            100 | 80 + 20 = 100 |
+        |            2 |          100 2 |               |
+        |            / |             50 | 100 / 2 = 50  |
+        |            4 |           50 4 |               |
+        |            x |            200 | 50 * 4 = 200  |
+
+    The last stack element 200 is the calculation result.
+
+  USAGE
+    The default mode of operation is the interactive mode. You'll get a
+    prompt which shows you the current size of the stack. At the prompt you
+    enter numbers followed by operators or mathematical functions. You can
+    use completion for the functions. You can either enter each number or
+    operator on its own line or separated by whitespace, that doesn't
+    matter. After a calculation the result will be immediately displayed
+    (and added to the stack). You can quit interactive mode using the
+    commands quit or exit or hit "ctrl-d". "ctrl-c" aborts the current line,
+    hitting it twice in a row exits as well.
+
+    If you feed data to standard input (STDIN), rpn just does the
+    calculation denoted in the contet fed in via stdin, prints the result
+    and exits. You can also specify a calculation on the commandline.
+
+    Here are the three variants ($ is the shell prompt):
+
+        $ rpn
+        rpn> 2
+        rpn> 2
+        rpn> +
+        = 4
+    
+        $ rpn
+        rpn> 2 2 +
+        = 4
+    
+        $ echo 2 2 + | rpn
+        4
+    
+        $ rpn 2 2 +
+        4
+
+    In the last two variants errors are printed to standard error (STDERR)
+    and rpn exits with status 1 if any calculation failed, so that scripts
+    can check $?:
+
+        $ echo 2 0 / | rpn 2>/dev/null || echo failed
+        failed
+
+    When reading STDIN, only the result of the very last operation is
+    printed, once all input has been consumed. It makes no difference
+    whether the calculation is given on one line or spread over many lines,
+    with comments and empty lines in between, both of these print 2:
+
+        $ echo 16 sqrt 2 / | rpn
+        $ printf "16\nsqrt # root\n\n2\n/\n" | rpn
+
+    If anything but an operation comes last, e.g. a number, nothing is
+    printed, use "--print-final" in that case. Use "-i" to see every
+    intermediate result as well.
+
+    The rpn calculator provides a batch mode which you can use to do math
+    operations on many numbers. Batch mode can be enabled using the
+    commandline option "-b" or toggled using the interactive command batch.
+    Not all math operations and functions work in batch mode though.
+
+    Example of batch mode usage:
+
+        $ rpn -b
+        rpn->batch > 2 2 2 2 +
+        = 8
+    
+        $ rpn
+        rpn> batch
+        rpn->batch> 2 2 2 2 +
+        8
+    
+        $ echo 2 2 2 2 + | rpn -b
+        8
+    
+        $ echo 2 2 2 2 | rpn +
+        8
+
+    Batch functions replace the whole stack with their result. Append "!" to
+    keep the values instead, e.g. sum! or mean!, this works without batch
+    mode as well. The result is pushed on top of the values, so a second
+    aggregate includes it:
+
+        rpn> 1 2 3 sum!
+        = 6
+        rpn> mean!
+        = 3
+
+    The stack is now "1 2 3 6 3", the mean of 1, 2, 3 and 6 being 3. To
+    compute several aggregates of the same values, use undo in between or
+    shift the previous result.
+
+    If input is piped into rpn and the only parameter is a batch function,
+    batch mode is enabled automatically, see last example. Other parameters
+    are evaluated after the input has been read, so they operate on the
+    numbers read from stdin:
+
+        $ echo 5 | rpn 2 +
+        7
+
+        $ echo 4 9 | rpn sqrt
+        3
+
+    Parameters which are a complete calculation on their own, that is they
+    contain numbers and every function finds enough of them, don't read
+    stdin at all. That way rpn can be used in a shell loop which reads from
+    a file. Stack commands like swap and registers like >NAME always read
+    stdin first, since they may need its numbers:
+
+        $ while read a b; do rpn $a $b +; done < pairs.txt
+
+    You can enter integers, floating point numbers (positive or negative) or
+    hex numbers (prefixed with 0x). Time values in h:mm or h:mm:ss format
+    are possible as well, they are converted to fractional hours, e.g.
+    "7:30" becomes 7.5. Minutes and seconds must be below 60. Use the
+    to-time command to display the last stack item as time.
+
+    A number followed by "%" is a percentage of the last stack item, e.g.
+    "400 5% +" adds 5% to 400 which results in 420. The stack must not be
+    empty in this case.
+
+    Angles can be entered in degrees, minutes and seconds, like "52d31m12s"
+    (minutes and seconds are optional), they are converted to decimal
+    degrees. Use the to-dms command to display the last stack item in this
+    format.
+
+    Durations like "1h30m", "45s" or "500ms" are converted to seconds (see
+    <https://pkg.go.dev/time#ParseDuration> for the supported units). Use
+    the to-duration command to display the last stack item as duration.
+
+    Fractions of two integers like "3/4" (without spaces) are pushed as
+    their quotient, so "1/3 3 x" gives 1.
+
+    Items starting with a digit which can't be parsed as any kind of number
+    lead to a descriptive error, e.g. "malformed number: 2e".
+
+    Hex numbers may be negative (e.g. "-0x10"). Since all numbers are stored
+    as 64 bit floating point numbers, hex numbers which can't be represented
+    exactly are rejected. This affects numbers above 2^53 which are not a
+    multiple of the according power of 2.
+
+    hex displays the last stack item in hex. It accepts an optional width of
+    1 to 16 digits, e.g. "48879 hex 8" prints 0x0000beef. In this case
+    negative numbers are printed in two's complement, e.g. "-1 hex 4" prints
+    0xffff, without a width as "-0x1". Numbers which don't fit into the
+    width lead to an error. Enable hexupper to get uppercase digits.
+
+    Other bases from 2 to 36 are available with base, e.g. "1295 base 36"
+    prints "zz". The last stack item must be an integer.
+
+    human displays the last stack item as a number of bytes with the largest
+    fitting unit, rounded to the precision, e.g. "1500000000 human" prints
+    "1.40 GiB". Enter byteunits si to use powers of 1000 instead, which
+    prints "1.50 GB" in this case.
+
+    fraction displays the last stack item as the closest fraction whose
+    denominator doesn't exceed 10000, or the given maximum, along with the
+    error if it's not exact, e.g. "0.333333 fraction" prints "1/3 (error
+    3.3e-07)" and "Pi fraction 10" prints "22/7 (error 1.3e-03)".
+
+    Roman numerals like "MCMLXXXIV" are converted to their integer value.
+    They must consist of at least two letters, single letters need the
+    prefix "r:", e.g. "r:X". Only the canonical form is accepted, so "IIII"
+    is an error. Use roman to display the last stack item as roman numeral,
+    which only works for integers from 1 to 3999.
+
+    ISO dates like "2024-06-01" are converted to the unix timestamp of
+    midnight UTC of that day. The function now pushes the current unix
+    timestamp and to-date displays the last stack item as date in your local
+    timezone. E.g. to get the number of days since a date:
+
+        $ rpn 2024-06-01 now swap - 86400 /
+
+    IPv4 addresses like 192.168.1.1 are converted to their integer value, so
+    you can use the bitwise operators on them. Use to-ip to display the last
+    stack item as ip address again, e.g. to get the network address of a
+    host:
+
+        $ rpn 192.168.1.37 0xffffff00 and to-ip
+        192.168.1.0
+
+    With "locale de" the dots are digit separators, so 1.000.000.000 is a
+    billion there, not an ip address.
+
+    Numbers may contain the digit separators "_" or "," to make them more
+    readable, e.g. "1_000_000", "1,234.56" or "0xDEAD_BEEF". A separator
+    must always be placed between two digits. In decimal numbers the comma
+    separates thousands, so it has to be followed by exactly three digits:
+    "5,5" is a malformed number and not 55.
+
+    The leading sign and the digits around the decimal point are optional,
+    so +5, 5. and .5 are valid numbers, while "5e" or "--5" are rejected as
+    malformed number.
+
+    Vectors pasted from other tools can be entered as bracketed group, e.g.
+    "[1, 2, 3]". The items of a group are separated by whitespace, "," or
+    ";" and pushed onto the stack one by one, so "batch [1, 2, 3] mean"
+    works as expected. Inside a group the comma is not a digit separator.
+    With locale de only ";" separates the items, since the comma is the
+    decimal point. Unterminated or nested groups lead to an error before
+    anything is evaluated.
+
+    Amounts pasted from invoices may contain a leading or trailing currency
+    symbol ("$", "€" or "£"), which is ignored, e.g. "$1,234.56" or "-$5". A
+    currency symbol standing alone is ignored as well. If you're used to the
+    german number format, enter locale de. Then "." is the digit separator
+    and "," the decimal point, e.g. "1.234,56 €". Use locale en to switch
+    back to the default.
+
+    If you paste amounts in both formats, enable lenientmoney. Then the
+    decimal separator of amounts is guessed, regardless of the locale: the
+    last "." or "," is the decimal point, unless it occurs more than once,
+    so "1.234,56", "$1,234.56" and "1,234,567" all work. Negative amounts
+    may be enclosed in parentheses, like "(1,234.56)". A single separator
+    followed by exactly three digits, like "1,234", could be either and is
+    rejected. That's a heuristic, therefore it's disabled by default. In
+    debug mode every amount is printed along with its interpretation. Since
+    items are separated by whitespace, amounts can't contain spaces.
+
+    The locale only affects the input. Results are printed as plain numbers
+    unless you enable groupdigits, which inserts thousands separators, e.g.
+    "1,234,567.89". decimalcomma prints the european style instead, e.g.
+    "1.234.567,89". Both respect the precision and also apply to dump and
+    the stack display.
+
+    Results are rounded to the precision when being printed, which may hide
+    the real value, e.g. "0.1 0.2 +" prints 0.30. Use full to see the exact
+    value of the last stack item, 0.30000000000000004 in this case. showfull
+    prints all results this way (using the shortest representation which is
+    still exact), noshowfull switches back.
+
+    Very large or small results are easier to read in sci (scientific) or
+    eng (engineering) notation, where the exponent is always a multiple of
+    3, e.g. 0.000123 is displayed as 1.23e-04 and 123e-6 respectively. In
+    both the precision determines the number of significant digits: the
+    digits after the decimal point in scientific notation plus one. Use fix
+    to switch back to fixed point notation.
+
+    Results which are not a number (NaN), e.g. "-1 sqrt", or infinite, e.g.
+    "0 log" or "10 400 ^", are rejected with an error and the stack remains
+    unchanged. Otherwise every subsequent calculation would be poisoned. If
+    you really want the IEEE 754 behavior, enable it with allownan.
+
+  STACK MANIPULATION
+    There are lots of stack manipulation commands provided. The most
+    important one is undo which goes back to the stack before the last math
+    operation.
+
+    In interactive mode every stack manipulation command confirms what it
+    changed in one line, unchanged items at the bottom of the stack are
+    abbreviated with "...":
+
+        1 5 3 swap
+        swap: ... 5 3 -> ... 3 5
+
+    This is not being done if showstack is enabled or if rpn has been
+    started with "-q" ("--quiet").
+
+    showstack displays the last 5 items of the stack after every input line,
+    use "showstack n" (or "--stack-size n") to see the last n items instead.
+    Note that a number directly following showstack is always taken as the
+    size.
+
+    You can use dump to display the stack. Every item is printed with its
+    position, counting from the top, formatted like a result:
+
+        1 10.5 3.14159 dump
+        Stack revision 3 (0xc000010030):
+        #3:  1.00
+        #2: 10.50
+        #1:  3.14 <- top
+
+    If debugging is enabled ("-d" switch or debug toggle command), then the
+    backup stack is also being displayed.
+
+    The stack can be reversed using the reverse command. However, sometimes
+    only the last two values are in the wrong order. Use the swap command to
+    exchange them.
+
+    You can use the shift command to remove the last number from the stack.
+
+    Like on HP calculators, "pick n" copies the n-th item counting from the
+    top (which is 1) onto the top of the stack, while "roll n" moves it
+    there. rot is the same as "roll 3", so "1 2 3 rot" leaves "2 3 1" on the
+    stack. "dupn n" duplicates the last n items as a block, so "1 2 3 dupn
+    2" leaves "1 2 3 2 3" on the stack, which is handy if the same operands
+    are needed by more than one formula. If the stack has less than n items,
+    it remains unchanged and an error is reported.
+
+    edit opens the stack in your editor ($EDITOR or vi), one number per
+    line. Lines which are not a number are skipped and reported along with a
+    summary, e.g. "kept 5, skipped 2 invalid lines". If there's no valid
+    number left, e.g. because you emptied the file, or the editor fails
+    (e.g. ":cq" in vi), the stack remains unchanged. Use clear to empty the
+    stack.
+
+    undo only goes back one operation. To experiment with a known state, use
+    checkpoint, which remembers the current stack until the next checkpoint.
+    rollback restores it, as often as you like, and reports how many items
+    it changed. While a checkpoint is set, the prompt shows a "*" after the
+    number of stack items. undo reverts a rollback.
+
+  BUILTIN OPERATORS AND FUNCTIONS
+    Basic operators:
+
+        +                    add
+        -                    subtract
+        /                    divide
+        x                    multiply (alias: *)
+        ^                    power
+
+    Bitwise operators:
+
+        and                  bitwise and
+        or                   bitwise or
+        xor                  bitwise xor
+        <                    left shift
+        >                    right shift
+        extract              value offset length: the bitfield as unsigned value
+        deposit              value field offset length: replace the bitfield
+        bitsof               the IEEE-754 bit pattern of a double as value
+
+    The bitfield functions work on 64 bit words, the offset counts from the
+    least significant bit, which is 0. Negative values are taken in two's
+    complement. E.g. to get the 4 bit field at bit 4 of a register and to
+    set it to 0xa afterwards:
+
+        0x1234 4 4 extract
+        = 3
+        0x1234 0xa 4 4 deposit hex
+        0x12a4
+
+    To look at raw register values, asint and asuint show the last stack
+    item as signed and unsigned 64 bit integer, asfloatbits shows the double
+    it would be if its bits were reinterpreted. bitsof is the inverse, it
+    replaces a double by its bit pattern:
+
+        0x3ff0000000000000 asfloatbits
+        1
+        -1 asuint
+        18446744073709551615
+        -2 bitsof hex
+        0xc000000000000000
+
+    Keep in mind that the stack itself consists of doubles, which hold
+    integers exactly up to 2^53 only. Larger hex literals are rejected
+    unless their lower bits are zero, e.g. 0xffffffffffffffff can't be
+    entered, use -1 instead. Likewise bitsof fails if the pattern can't be
+    represented exactly, which is the case for most fractions, e.g. 0.1, the
+    error shows the pattern in hex though.
+
+    In programmer mode the operands of bitwise operations are shown in hex
+    in the history.
+
+    Percent functions:
+
+        %                    percent
+        %-                   subtract percent
+        %+                   add percent
+
+    Batch functions:
+
+        sum                  sum of all values (alias: +)
+        max                  max of all values
+        min                  min of all values
+        mean                 mean of all values (alias: avg)
+        median               median of all values
+        madev                median absolute deviation of all values
+
+    Append "!" to a batch function to keep the values on the stack, e.g.
+    sum!, see DESCRIPTION.
+
+    Math functions:
+
+        mod sqrt abs acos acosh asin asinh atan atan2 atanh cbrt ceil cos cosh
+        erf erfc  erfcinv erfinv exp  exp2 expm1 floor  gamma ilogb j0  j1 log
+        log10 log1p log2 logb pow round roundtoeven sin sinh tan tanh trunc y0
+        y1 copysign dim hypot ncr npr multichoose ceildiv floordiv (alias: //)
+        roundmult factorial (alias: !)
+
+    Time functions:
+
+        now                  current time as unix timestamp
+
+    Constants:
+
+        E Pi Phi Sqrt2 SqrtE SqrtPi SqrtPhi Ln2 Log2E Ln10 Log10E
+
+    Constant names are case insensitive, so "pi" and "PI" work as well as
+    "Pi". The setting relaxedcase controls this: with "off" only the exact
+    names work, with "constants" (the default) constants are matched case
+    insensitive and with "all" function names as well, so "16 SQRT" computes
+    the square root. Functions are not included by default, because Lua
+    functions might differ in case only. If more than one name matches, an
+    error lists the candidates. A constant wins over a Lua function of the
+    same name. There is no ambiguity with variables, because they are always
+    accessed using the "<NAME" syntax.
+
+    Conversion functions:
+
+        cm-to-inch
+        inch-to-cm
+        gallons-to-liters
+        liters-to-gallons
+        yards-to-meters
+        meters-to-yards
+        miles-to-kilometers
+        kilometers-to-miles
+
+    If you enter an unknown item, rpn suggests the closest known name, if
+    any. The units of conversion functions are matched separately, so
+    "mile-to-kilometer" leads to "did you mean miles-to-kilometers?". This
+    works for conversions registered in Lua as well.
+
+    If there is no direct conversion between two units, "convert FROM TO"
+    looks for a chain of conversion functions leading from one unit to the
+    other and applies it to the last stack item. With a Lua conversion from
+    feet to inch, "2 convert feet cm" uses "feet-to-inch" and "inch-to-cm"
+    and results in 60.96. In debug mode the chain being used is printed. The
+    shortest chain wins.
+
+    Configuration Commands:
+
+        [no]batch            toggle batch mode (nobatch turns it off)
+        [no]debug            toggle debug output (nodebug turns it off)
+        prompt [TEMPLATE]    set the prompt template, see PROMPT
+        [no]showstack [n]    show the last 5 (or n) items of the stack (noshowtack turns it off)
+        [no]usagestats       count function and command usage (nousagestats turns it off)
+        [no]money            round every result to cents (nomoney turns it off)
+        [no]allownan         accept NaN and Inf results (noallownan turns it off)
+        byteunits [iec|si]   set the units of human: iec (1024, default) or si (1000)
+        tolerance [x]        set the tolerance of solve and integrate (default 1e-10)
+        maxiter [n]          set the max number of iterations of solve and integrate (default 100)
+        maxexpand [n]        set the max number of items evaluated by nested repeats per line (default 10000)
+        precision [n]        set the floating point number precision (0-15, default 2)
+        locale [en|de]       set number format: en (1,234.56, default) or de (1.234,56)
+        [no]groupdigits      print thousands separators: 1,234,567.89
+        [no]showfull         print results with full precision (%g)
+        [no]color            colored output: red errors, green results
+        [no]teach            show each operation along with the resulting stack
+        [no]hints            show function arguments while typing
+        [no]hexupper         print uppercase hex digits
+        [no]progmode         print integer results in hex and binary as well
+        [no]linemode         calculate every input line independently
+        [no]lenientmoney     guess the decimal separator of pasted amounts
+        fix                  display results in fixed point notation (default)
+        sci                  display results in scientific notation: 1.25e+04
+        eng                  display results in engineering notation: 12.5e3
+        [no]decimalcomma     print european style: 1234567,89 (1.234.567,89 with groupdigits)
+        relaxedcase [MODE]   match case insensitive: off, constants (default) or all
+        settings             show all settings which can be saved
+        savesettings [NAME..] save the given settings (default: all changed ones) to ~/.rpnrc
+
+    Show commands:
+
+        dump                 display the stack contents
+        diff                 compare the stack before the last operation with the current one
+        hex [n]              show last stack item in hex form (converted to int)
+        history [math|stack|note] display calculation history
+        notes                list the notes taken in this session
+        vars                 show list of variables
+        base n               show last stack item (integer) in base n (2-36)
+        human                show last stack item (bytes) with a unit, e.g. 1.46 GiB
+        fraction [n]         show last stack item as fraction, denominator up to n (10000)
+        jsondump             show stack, variables and precision as json
+        save NAME            save stack and variables under NAME
+        stacks               list the names of saved stacks
+        stats                show count, min, max, mean, median, stddev and sum of the stack
+        to-time              show last stack item as time (h:mm:ss)
+        to-duration          show last stack item (seconds) as duration
+        to-dms               show last stack item as degrees, minutes and seconds
+        to-ip                show last stack item as ip address
+        to-date              show last stack item (unix timestamp) as local date
+        full                 show last stack item with full precision (%.17g)
+        roman                show last stack item as roman numeral
+        asint                show last stack item (64 bit word) as signed integer
+        asuint               show last stack item (64 bit word) as unsigned integer
+        asfloatbits          show the double whose bit pattern is the last stack item
+        usage [save]         show usage statistics, save writes them to ~/.rpn-usage
+
+    Stack manipulation commands:
+
+        clear                clear the whole stack
+        shift                remove the last element of the stack
+        drop N               remove the last N elements of the stack (alias: shiftn)
+        reverse              reverse the stack elements
+        swap                 exchange the last two stack elements
+        dup                  duplicate last stack item
+        dupn N               duplicate the last N items as a block
+        depth                push the number of stack items
+        over                 copy the second last item to the top: a b -> a b a
+        nip                  remove the second last item: a b -> b
+        pick N               copy the N-th item (1 is the top) to the top
+        roll N               move the N-th item (1 is the top) to the top
+        rot                  rotate the last three items: c b a -> b a c
+        checkpoint           remember the stack, see rollback
+        rollback             restore the stack remembered by checkpoint
+        undo                 undo last operation
+        jsonload [FILE]      replace the stack with json from FILE or the next line
+        load NAME            replace the stack with the one saved under NAME
+        lastx                push the last operand of the last math operation
+        edit                 edit the stack interactively using vi or $EDITOR
+        rmoutliers           pop k, remove all items farther than k*madev from the median
+
+    Other commands:
+
+        help|?               show this message
+        manual               show manual
+        which ITEM           show how ITEM would be interpreted
+        convert FROM TO      convert the last stack item from unit FROM to TO
+        copy                 copy the last stack item to the clipboard
+        tee FILE             append results along with the input to FILE
+        notee                stop writing results to the tee file
+        nop                  do nothing, useful as a placeholder in scripts
+        repeat ...           pop n and evaluate the rest of the line n times
+        note TEXT            store the rest of the line as a note
+        solve FUNC           find a root of a lua function, starting at the last stack item
+        integrate FUNC       integrate a lua function between the last two stack items
+        deriv FUNC           derivative of a lua function at the last stack item
+        quit|exit|c-d|c-c    exit program
+
+    Register variables:
+
+        >NAME                Put last stack element into variable NAME
+        >>NAME               Move last stack element into variable NAME
+        <NAME                Retrieve variable NAME and put onto stack
+
+    Refer to https://pkg.go.dev/math for details about those functions.
+
+    The combinatoric functions ncr (n choose k), npr (permutations of k out
+    of n) and multichoose (combinations with repetition) are computed
+    exactly and the result is rounded only at the end. So results up to 2^53
+    are exact integers. Results which exceed the floating point range lead
+    to an error.
+
+    ceildiv and floordiv (or "//") divide x by y and round the result up or
+    down respectively, e.g. to compute the number of pages needed for 45
+    items with 10 per page: "45 10 ceildiv" results in 5. roundmult rounds x
+    to the nearest multiple of y, ties are rounded away from zero: "17 5
+    roundmult" results in 15, "17.5 5 roundmult" in 20. The multiple must be
+    positive.
+
+    factorial (or "!") computes n! of a non-negative integer. The largest
+    factorial which fits into a float64 is 170!, so "171 !" results in the
+    error "argument 171 exceeds float64 range (max 170)". Likewise exp,
+    exp2, expm1, gamma, pow and "^" report the limit or the operands which
+    overflowed, unless NaN and Inf results are allowed using allownan.
+
+    The history contains both math operations and stack manipulations
+    (clear, shift, reverse, swap, dup, undo, edit, rmoutliers), so you can
+    see how the stack got into its current state. Use "history math" or
+    "history stack" to only see one kind of entries.
+
+    To keep track of what the numbers mean during a long session, use note
+    to store the rest of the line as a timestamped note, e.g. "note result 3
+    is the Q3 figure". Notes are never evaluated and don't touch the stack.
+    notes lists them, they are also part of the history ("history note") and
+    of the output of jsondump.
+
+    The history keeps the last 10000 entries, older ones are dropped. Use
+    "--history-limit" to change this, 0 means unlimited. Operations with
+    lots of operands (e.g. batch functions) only keep the first and last
+    three operands in the history, so that summing up millions of numbers
+    from STDIN doesn't eat up your memory.
+
+    The results of math operations are stored with full precision in the
+    history, they are only rounded when being displayed. So the precision
+    setting doesn't affect the stored values.
+
+    The diff command compares the stack as it was before the last operation
+    (the one undo would restore) with the current stack, position by
+    position starting at the bottom. Unchanged items are indented, removed
+    items are prefixed with "-", added ones with "+". A changed position
+    shows up as a removal followed by an addition:
+
+        1 2 3 +
+        diff
+        --- backup revision 3
+        +++ stack revision 4
+          1
+        - 2
+        + 5
+        - 3
+
+    There are also a number of shortcuts for some commands available:
+
+        d    debug
+        b    batch
+        s    showstack
+        prec precision
+        h    history
+        p    dump (aka print)
+        v    vars
+        c    clear
+        u    undo
+
+INTERACTIVE REPL
+    While you can use rpn in the command-line, the best experience you'll
+    have is the interactive repl (read eval print loop). Just execute "rpn"
+    and you'll be there.
+
+    In interactive mode you can use TAB completion to complete commands,
+    operators and functions. There's also a history, which allows you to
+    repeat complicated calculations (as long as you've entered them in one
+    line).
+
+    There are also a lot of key bindings, here are the most important ones:
+
+    ctrl-d
+        Exit interactive rpn
+
+    ctrl-c
+        Abort the current line, hit it twice in a row to exit interactive
+        rpn
+
+    ctrl-z
+        Send rpn to the backgound.
+
+    ctrl-a
+        Beginning of line.
+
+    ctrl-e
+        End of line.
+
+    ctrl-l
+        Clear the screen.
+
+    ctrl-r
+        Search through history.
+
+    On terminals which don't support these features, that is if "TERM" is
+    unset or set to "dumb" like in an emacs shell, rpn falls back to a plain
+    line reader: there are no colors, no completion and no history, but
+    calculations work just the same, using the same prompt. Use "ctrl-d" to
+    exit there.
+
+COLORS
+    Results are printed in green, errors in red and the stack display (see
+    showstack) dimmed. By default, colors are only used if rpn is talking to
+    a terminal, so scripts reading the output or piping input into rpn don't
+    get escape sequences. Setting the environment variable "NO_COLOR"
+    disables colors as well. Use "--color always" or "--color never" to
+    override this. Interactively, nocolor turns colors off and color toggles
+    them.
+
+TEACH MODE
+    To learn how RPN works, enable teach. Then every input line which
+    changes the stack is followed by a line showing the last operation along
+    with its operands and the resulting stack, instead of the result:
+
+        teach
+        80 20 +
+        80 20 + | stack: 100
+        5
+        5 | stack: 100 5
+        *
+        100 5 * | stack: 500
+
+PROGRAMMER MODE
+    In programmer mode (progmode) integer results are printed in hex and
+    binary as well, e.g.:
+
+        0xf0 0x0f or
+        = 255 (0xff, 0b11111111)
+
+    Other results are printed as usual. The decimal part respects the
+    precision and the notation, hexupper applies to the hex part. The prompt
+    shows "->prog" while the mode is enabled.
+
+PROMPT
+    The prompt is rendered from a template, which can be changed with the
+    prompt command, e.g. "prompt "{stack} {top} » "". The quotes are needed
+    to keep the trailing space. prompt without a template shows the current
+    one, "prompt default" restores the default, which is:
+
+        rpn{modes} [{stack}{checkpoint}{revision}]{arrow}
+
+    These placeholders are supported:
+
+        {stack}              number of items on the stack
+        {top}                the last stack item
+        {rev}                the stack revision
+        {revision}           /rev and the stack revision, in debug mode only
+        {checkpoint}         *, if a checkpoint has been set
+        {modes}              enabled modes, e.g. ->batch->debug->prog
+        {batch}              batch, if batch mode is enabled
+        {money}              money, if money mode is enabled
+        {debug}              debug, if debugging is enabled
+        {prog}               prog, if programmer mode is enabled
+        {arrow}              a red arrow (if colors are enabled)
+
+    A template with unknown placeholders or control characters is rejected,
+    if it has been set in the config the default is used instead.
+
+HINTS
+    If hints is enabled, rpn prints a short hint after each known function
+    or command as soon as you type the following space, e.g.:
+
+        1 atan2 
+        atan2(y, x) — needs 2 args, stack has 1
+
+    Operands are named like on HP calculators, x being the last stack item.
+    Hints are only shown if rpn is talking to a terminal.
+
+RECORDING RESULTS
+    To keep a durable record of a session, use "tee FILE". From then on
+    every printed result is appended to FILE along with the input line which
+    produced it, e.g. "3 4 x = 12". A leading "~" in FILE is expanded to
+    your home directory. notee stops the recording. If the file can't be
+    written, the error is reported once and the recording stops.
+
+CLIPBOARD
+    In interactive mode copy puts the last stack item into the system
+    clipboard, formatted exactly like the result is printed, so it honors
+    the precision, the notation etc. rpn uses the first available of pbcopy
+    (macOS), wl-copy (Wayland), xclip or xsel (X11) and reports an error if
+    none of them can be used.
+
+COMMENTS
+    Lines starting with "#" are being ignored as comments. You can also
+    append comments to rpn input, e.g.:
+
+       # a comment
+       123   # another comment
+
+    In this case only 123 will be added to the stack.
+
+REPEATING CALCULATIONS
+    The repeat command pops a count n from the stack and evaluates the rest
+    of the line n times, e.g. to grow 1000 by 5% ten times:
+
+       1000 10 repeat 1.05 x
+
+    The count must be an integer between 0 and 1000000. Results of the
+    single repetitions are not printed and only one summarized entry is
+    added to the history. If one of the repetitions fails, the stack is
+    restored to the state before repeat. undo reverts all repetitions at
+    once.
+
+    To prevent runaway calculations, e.g. nested repetitions like "0 100
+    repeat 200 repeat 1 +", the number of items evaluated by nested
+    repetitions per input line is limited to 10000. A single repeat isn't
+    affected, it is only limited by its count. Exceeding the limit leads to
+    an error naming the chain of repetitions involved. Use "maxexpand n" to
+    change the limit.
+
+SEPARATING DATA
+    Generated scripts might contain values which happen to look like a
+    command or function name. Everything after a "--" on the same line is
+    treated as data: numbers are pushed onto the stack as usual, everything
+    else is an error:
+
+       1 2 -- 3 4     # pushes 1 2 3 4
+       -- sum         # error: sum is not a number
+
+    The nop command does nothing and can be used as a placeholder.
+
+VARIABLES
+    You can register the last item of the stack into a variable. Variable
+    names must be all caps. Use the ">NAME" command to put a value into
+    variable "NAME". Use "<NAME" to retrieve the value of variable "NAME"
+    and put it onto the stack.
+
+    ">NAME" leaves the value on the stack, use ">>NAME" to remove it from
+    the stack as well, which saves the shift afterwards. undo puts it back
+    onto the stack.
+
+    The command vars can be used to get a list of all variables.
+
+    Like on HP calculators the operands of the last math operation are kept
+    in the registers "LASTX" (the last stack item) and "LASTY" (the one
+    before). This is useful to correct a mistake, e.g. if you wanted to add
+    5 instead of 7:
+
+        100 7 +
+        lastx -
+        5 +
+
+    lastx pushes "LASTX" onto the stack, same as "<LASTX". Stack commands
+    like dup or swap don't change the registers, failed operations neither.
+    clear removes them. The registers are no variables: vars doesn't list
+    them, they are not saved and they can't be overwritten. To apply the
+    same percentage twice:
+
+        100 7 %+
+        lastx %+
+
+EXTENDING RPN USING LUA
+    You can use a lua script with lua functions to extend the calculator. By
+    default the tool looks for "~/.rpn.lua". You can also specify a script
+    using the <kbd>-c</kbd> flag.
+
+    Here's an example of such a script:
+
+        function add(a,b)
+          return a + b
+        end
+    
+        function init()
+          register("add", 2, "addition")
+        end
+
+    Here we created a function "add()" which adds two parameters. All
+    parameters are "FLOAT64" numbers. You don't have to worry about stack
+    management, this is taken care of automatically.
+
+    The function "init()" MUST be defined, it will be called on startup. You
+    can do anything you like in there, but you need to call the "register()"
+    function to register your functions to the calculator. This function
+    takes these parameters:
+
+    *   function name
+
+    *   number of arguments expected (see below)
+
+        Number of expected arguments can be:
+
+            - 0: expect 1 argument but do NOT modify the stack
+            - 1-n: do a singular calculation
+            - -1: batch mode work with all numbers on the stack
+
+    *   help text
+
+    Please refer to the lua language reference:
+    <https://www.lua.org/manual/5.4/> for more details about LUA.
+
+    Please note, that io, networking and system stuff is not allowed though.
+    So you can't open files, execute other programs or open a connection to
+    the outside!
+
+  UNIT CONVERSIONS
+    Most unit conversions are just a factor, so you don't need to write a
+    lua function for them. Call "register_conversion()" in "init()" instead:
+
+        function init()
+          register_conversion("furlong", "meters", 201.168, "furlongs to meters")
+          register_conversion("celsius", "fahrenheit", 1.8, "celsius to fahrenheit", 32)
+        end
+
+    This creates the converters "furlong-to-meters" and "meters-to-furlong",
+    the latter using the inverse factor. The parameters are the names of
+    both units, the factor, an optional help text and an optional offset,
+    which is added after the multiplication. So the second example converts
+    temperatures: "100 celsius-to-fahrenheit" results in 212 and "212
+    fahrenheit-to-celsius" in 100. The factor must not be zero. Conversions
+    replace builtin functions with the same name.
+
+  CUSTOM RESULT FORMAT
+    If the config defines a function "format_result(value)", it is used to
+    format every printed result instead of the precision setting. It must
+    return a string, e.g. to append a currency:
+
+        function format_result(value)
+          return string.format("%.2f EUR", value)
+        end
+
+    If the function fails or doesn't return a string, the default format is
+    used. Files written with "--stack-out" always contain the raw numbers,
+    and showfull prints them as well.
+
+  CUSTOM PROMPT
+    The config may set the prompt template using the global variable
+    "prompt", see PROMPT:
+
+        prompt = "{stack} {top} » "
+
+    If you need logic in your prompt, define a function "prompt(state)"
+    instead. It is called after every input line and returns the prompt as
+    string. "state" is a table containing the "stack" (a list, the first
+    item is the bottom), its "depth", the "top" item (nil if the stack is
+    empty), the "batch" and "debug" modes as booleans and the "precision".
+    E.g. to show the depth in red if the stack grows large:
+
+        function prompt(state)
+          if state.depth > 10 then
+            return "\027[31m" .. state.depth .. "\027[0m> "
+          end
+          return state.depth .. "> "
+        end
+
+    If the function fails or doesn't return a string, the template is used
+    instead and the error is reported once.
+
+  SESSION PERSISTENCE
+    Setting the global variable "persist" to true is the same as using
+    "--persist" all the time, see SESSIONS:
+
+        persist = true
+
+  SOLVING, INTEGRATION AND DIFFERENTIATION
+    Lua functions which expect 1 argument can be solved for a root using
+    solve. It uses Newton's method, the derivative is estimated numerically.
+    The last stack item is the initial guess and will be replaced by the
+    root:
+
+        function poly(x)
+          return x*x*x - 2*x - 5
+        end
+
+        function init()
+          register("poly", 1, "x^3 - 2x - 5")
+        end
+
+        $ rpn -p 6 2 solve poly
+        2.094551
+
+    If no root can be found, an error is printed and the stack remains
+    unchanged. Use tolerance (default 1e-10) and maxiter (default 100) to
+    tune the iteration.
+
+    integrate computes the definite integral of such a function between the
+    last two stack items using the adaptive Simpson rule, which also uses
+    the tolerance setting. It gives up after maxiter times 1000 evaluations
+    of the function. deriv replaces the last stack item by the derivative of
+    the function at this point, estimated using the central difference:
+
+        $ rpn 0 3 integrate poly
+        -3.75
+        $ rpn 2 deriv poly
+        10.00
+
+    In case of an error, e.g. if the function is not defined somewhere in
+    the interval, the stack remains unchanged as well.
+
+CONFIGURATION
+    rpn can be configured via command line flags (see usage above). Most of
+    the flags are also available as interactive commands, such as "--batch"
+    has the same effect as the batch command.
+
+    The floating point number precision can be set with "-p, --precision" on
+    the command line or interactively with precision n (or prec n), where n
+    must be between 0 and 15. Without an argument precision prints the
+    current value. The default precision is 2.
+
+    Commands with an optional argument, like precision, locale, history or
+    usage, only take the next item if it is a valid argument, otherwise it's
+    evaluated as usual: "precision 2.5 2 *" prints the precision and leaves
+    5 on the stack.
+
+SESSIONS
+    With "--persist" rpn continues where you left off: the stack, the
+    variables, the history and the notes are saved on exit to
+    "~/.local/state/rpn/session.json" (or "$XDG_STATE_HOME/rpn/session.json"
+    if XDG_STATE_HOME is set) and restored on the next start. The session is
+    saved regardless of how rpn ends, be it exit, "ctrl-d" or the end of the
+    input.
+
+    A corrupt session file, or one written by an incompatible version of
+    rpn, is reported and ignored, rpn starts with an empty stack then and
+    overwrites it on exit.
+
+  SETTINGS FILE
+    Settings can be saved to "~/.rpnrc" using savesettings, so that you
+    don't have to enable them in every session. Without arguments all
+    settings which differ from the defaults are saved, otherwise only the
+    given ones, e.g.:
+
+        precision 4
+        showstack
+        savesettings precision showstack
+
+    Settings already in the file are kept. settings lists all settings which
+    can be saved along with their current values. The file contains one
+    setting per line, the values have the same form as the arguments of the
+    command of the same name:
+
+        # written by rpn savesettings
+        precision = 4
+        showstack = true
+        prompt = "{stack} » "
+
+    The file is loaded on startup before the commandline flags are
+    evaluated, so flags win over the file and interactive commands win over
+    both. Unknown settings and invalid values are reported and ignored.
+
+    If the home directory can't be determined, e.g. because $HOME is unset
+    in a container, rpn starts anyway without history, config and settings
+    file. Commands which need to write to the home directory, like
+    savesettings or save, report an error then.
+
+PARAGRAPH MODE
+    If you feed many independent calculations via STDIN, separate them by
+    empty lines and use "--paragraph-mode". Then results are only printed at
+    the end of each paragraph, after which the stack is cleared, so every
+    paragraph starts from scratch. An operator given on the commandline is
+    applied to every paragraph in batch mode:
+
+        $ printf "1 2 3\n\n4 5\n6\n" | rpn --paragraph-mode +
+        6
+        15
+
+    If an error occurs in a paragraph, it is reported and no result is
+    printed for this paragraph.
+
+    With "--line" every input line is a calculation of its own, which makes
+    rpn usable as a filter, like awk. Exactly one result is printed per
+    line, erroneous lines print nothing but an error on STDERR. The linemode
+    command toggles this interactively.
+
+        $ printf "2 2 +\n1 2 3\n" | rpn --line
+        4
+        3
+        $ printf "1 2 3\n4 5\n" | rpn --line +
+        6
+        9
+
+    For streams of measurements, "--every N" splits the numbers read into
+    windows of N numbers, regardless of the lines, and applies the operator
+    to each of them, e.g. to compute the mean of every block of 100 values:
+
+        $ seq 1 250 | rpn --every 100 mean
+        50.50
+        150.50
+        225.50
+
+    The last window is computed even if the input ends before it's full, use
+    "--drop-partial" to ignore it instead.
+
+FILE MODE
+    Longer calculations can be stored in a file and evaluated with "-f
+    FILE". It works like feeding the file to STDIN, but every result and
+    error is prefixed with the file name and the line number, like compiler
+    diagnostics, so that your editor can jump to it:
+
+        $ rpn -f invoice.rpn
+        invoice.rpn:3: 1234.56
+        invoice.rpn:7: Error: division by null
+
+    Add "--errors-only" to suppress the results, e.g. to check a file. The
+    exit status is 1 if any line failed.
+
+MONEY MODE
+    Invoices are computed line by line, each line rounded to cents. If you
+    enable money mode ("-M, --money" or the money command), rpn does the
+    same: the result of every operation is rounded to 2 digits, half away
+    from zero, before it is put back onto the stack. Results are always
+    printed with 2 digits, regardless of the precision setting. The prompt
+    shows "->money" while the mode is enabled.
+
+    Note that results differ from exact floating point math, which only
+    rounds the final result for display:
+
+        $ rpn -M 1.02 19 %+ 1.02 19 %+ +
+        2.42
+        $ rpn 1.02 19 %+ 1.02 19 %+ +
+        2.43
+
+USAGE STATISTICS
+    If you are curious which functions and commands you actually use, you
+    can enable the usagestats setting. From then on every invocation will be
+    counted in memory. Use the usage command to display the counters, most
+    used first.
+
+    The statistics are never written anywhere unless you explicitly enter
+    usage save, which writes them to the local file "~/.rpn-usage". They are
+    never transmitted anywhere.
+
+CHAINING INVOCATIONS
+    The final stack can be written to a file using "--stack-out FILE" when
+    rpn exits, one number per line in full precision. Another rpn invocation
+    can pre-load its stack from such a file using "--stack-in FILE" before
+    any input from the commandline or STDIN is processed. Use "-" as
+    filename to write to STDOUT or read from STDIN respectively. When
+    writing the stack to STDOUT no results are printed. When reading the
+    stack from STDIN, the calculation must be given on the commandline,
+    because STDIN is already consumed by the stack:
+
+        $ rpn --stack-out stack.txt 1 2 3
+        $ rpn --stack-in stack.txt + x
+        5
+        $ rpn --stack-out - 2 2 + 3 | rpn --stack-in - 2 x
+        6
+
+    The stack file is only written if the calculation was successful.
+
+    "--print-stack-on-exit" is a shortcut for "--stack-out -", useful in
+    Makefiles or scripts which need the whole stack and not only the last
+    result:
+
+        $ rpn --print-stack-on-exit 1 2 3 dup +
+        1
+        2
+        6
+
+    For scripting, jsondump prints the stack, the variables and the
+    precision as JSON in one line:
+
+        1 2 3 >X jsondump
+        {"stack":[1,2,3],"vars":{"X":3},"precision":2}
+
+    jsonload FILE replaces the stack with the contents of FILE, which may
+    contain such an object, in which case the variables and precision are
+    restored as well, or a plain JSON array like "[1,2,3]". Without a FILE
+    (or with "-") the next input line is loaded. Invalid JSON leads to an
+    error and leaves the stack alone. undo reverts the load, including the
+    variables, precision and notes.
+
+    To keep stacks across sessions, save NAME writes the stack and the
+    variables in the same format to "~/.rpn/stacks/NAME.json", or to
+    "$XDG_DATA_HOME/rpn/stacks/NAME.json" if XDG_DATA_HOME is set. The
+    directory is created when needed. load NAME replaces the stack with a
+    saved one, undo brings back the previous stack, and stacks lists the
+    saved names:
+
+        1 2 3 save budget
+        clear
+        load budget
+        stacks
+
+SUMMARY
+    To get an overview of a column of numbers, use "--summary". After the
+    input has been processed, it prints the count, minimum, maximum, mean,
+    median, sample standard deviation and sum of the numbers on the stack,
+    one per line. The stack itself isn't modified, and no batch mode is
+    needed:
+
+        $ printf "2\n4\n4\n4\n5\n5\n7\n9\n" | rpn --summary
+        count    8
+        min      2
+        max      9
+        mean     5
+        median   4.50
+        stddev   2.14
+        sum      40
+
+    With "--format json" the summary is printed as a JSON object with the
+    same field names instead.
+
+    In interactive mode the stats command prints the same table for the
+    current stack at any time, formatted using the current precision.
+
+FORMATTING NUMBERS
+    Usually rpn only prints something if an operator or function has been
+    executed. If you want to use it to validate or reformat numbers, use
+    "--print-final", which prints the last stack item on exit, unless it has
+    already been printed as the result of the last operation:
+
+        $ echo 42.12345 | rpn --print-final -p 3
+        42.123
+        $ echo 1,000,000 | rpn --print-final
+        1000000
+
+TOKEN PRECEDENCE
+    Every item of the input is interpreted according to the first of these
+    kinds of tokens it matches:
+
+        1. number        any kind of number literal, e.g. 1.5, 0x10, 5%, MCM
+        2. lua function  functions registered in your lua config
+        3. constant      e.g. Pi, case insensitive, see relaxedcase
+        4. function      builtin operators and functions, e.g. + or sqrt
+        5. batch function  e.g. median, only in batch mode
+        6. keep function   e.g. median!, in any mode
+        7. register      >NAME, >>NAME or <NAME
+        8. command       e.g. repeat
+        9. show command  e.g. dump
+        10. stack command e.g. undo
+        11. setting      e.g. debug
+        12. help         ? or help
+
+    So a lua function can replace a builtin function or command, but a
+    number is always a number. Use which to find out how an item would be
+    interpreted, e.g. "which sqrt" prints "sqrt: function". For aliases it
+    reports the canonical name as well, e.g. "which avg" prints "avg: batch
+    function (alias of mean)". help lists the aliases of commands, including
+    single letter ones like "c" for clear.
+
+LISTING FUNCTIONS
+    External tools like editor plugins can retrieve a list of all tokens
+    known to rpn using the "--list-functions" flag. This includes operators,
+    functions, commands, constants and the functions of a loaded Lua config.
+    Each entry consists of the name, the category, the arity (-1 means the
+    whole stack) and the help text. Aliases like "avg" additionally carry
+    the canonical name, e.g. "alias": "mean". The list is sorted by name.
+
+    Use "--format json" to get the list in JSON format, e.g.:
+
+        $ rpn --list-functions --format json
+        [
+          {
+            "name": "%",
+            "category": "operator",
+            "arity": 2,
+            "help": "percent"
+          },
+        ...
+
+GETTING HELP
+    In interactive mode you can enter the help command (or ?) to get a short
+    help along with a list of all supported operators and functions.
+
+    To read the manual you can use the manual command in interactive mode.
+    The commandline option "-m" does the same thing.
+
+    If you have installed rpn as a package or using the distributed tarball,
+    there will also be a manual page you can read using "man rpn".
+
+BUGS
+    In order to report a bug, unexpected behavior, feature requests or to
+    submit a patch, please open an issue on github:
+    <https://github.com/TLINDEN/rpnc/issues>.
+
+LICENSE
+    This software is licensed under the GNU GENERAL PUBLIC LICENSE version
+    3.
+
+    Copyright (c) 2023-2024 by Thomas von Dein
+
+    This software uses the following GO modules:
+
+    readline (github.com/chzyer/readline)
+        Released under the MIT License, Copyright (c) 2016-2023 ChenYe
+
+    pflag (https://github.com/spf13/pflag)
+        Released under the BSD 3 license, Copyright 2013-2023 Steve Francia
+
+    gopher-lua (github.com/yuin/gopher-lua)
+        Released under the MIT License, Copyright (c) 2015-2023 Yusuke
+        Inuzuka
+
+AUTHORS
+    Thomas von Dein tom AT vondein DOT org
+
+`
@@ -0,0 +1,292 @@
+/*
+Copyright © 2025 Thomas von Dein
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package calc
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A setting which can be saved to the settings file using savesettings.
+// Values are strings in the same form as the arguments of the command
+// of the same name, which is used to apply them where there is one.
+type Setting struct {
+	Name string
+	Get  func(c *Calc) string
+	Set  func(c *Calc, value string) error
+}
+
+// all settings known to the settings file, sorted by name
+var Settings = []Setting{
+	boolSetting("allownan", func(c *Calc) *bool { return &c.allownan }),
+	boolSetting("batch", func(c *Calc) *bool { return &c.batch }),
+	argSetting("byteunits", func(c *Calc) string { return c.byteunits }, CommandByteUnits),
+	boolSetting("decimalcomma", func(c *Calc) *bool { return &c.decimalcomma }),
+	boolSetting("groupdigits", func(c *Calc) *bool { return &c.groupdigits }),
+	boolSetting("hexupper", func(c *Calc) *bool { return &c.hexupper }),
+	boolSetting("hints", func(c *Calc) *bool { return &c.hints }),
+	boolSetting("lenientmoney", func(c *Calc) *bool { return &c.lenientmoney }),
+	argSetting("locale", func(c *Calc) string { return c.locale }, CommandLocale),
+	argSetting("maxexpand", func(c *Calc) string { return strconv.Itoa(c.maxexpand) }, CommandMaxExpand),
+	argSetting("maxiter", func(c *Calc) string { return strconv.Itoa(c.maxiter) }, CommandMaxIterations),
+	boolSetting("money", func(c *Calc) *bool { return &c.money }),
+	{
+		Name: "notation",
+		Get:  func(c *Calc) string { return c.notation },
+		Set: func(c *Calc, value string) error {
+			switch value {
+			case NotationFix, NotationSci, NotationEng:
+				c.notation = value
+			default:
+				return fmt.Errorf("unsupported notation %s, use fix, sci or eng", value)
+			}
+
+			return nil
+		},
+	},
+	argSetting("precision", func(c *Calc) string { return strconv.Itoa(c.precision) }, CommandPrecision),
+	boolSetting("progmode", func(c *Calc) *bool { return &c.progmode }),
+	// quoted, so that trailing spaces survive
+	argSetting("prompt", func(c *Calc) string { return `"` + c.PromptTemplate() + `"` }, CommandPrompt),
+	boolSetting("quiet", func(c *Calc) *bool { return &c.quietstack }),
+	argSetting("relaxedcase", func(c *Calc) string { return c.relaxedcase }, CommandRelaxedCase),
+	boolSetting("showstack", func(c *Calc) *bool { return &c.showstack }),
+	{
+		Name: "stacksize",
+		Get:  func(c *Calc) string { return strconv.Itoa(c.showstacklen) },
+		Set: func(c *Calc, value string) error {
+			size, err := strconv.Atoi(value)
+			if err != nil || size < 1 {
+				return fmt.Errorf("invalid stack size %s, must be at least 1", value)
+			}
+
+			c.showstacklen = size
+
+			return nil
+		},
+	},
+	boolSetting("teach", func(c *Calc) *bool { return &c.teach }),
+	argSetting("tolerance", func(c *Calc) string { return fmt.Sprint(c.tolerance) }, CommandTolerance),
+}
+
+func boolSetting(name string, field func(c *Calc) *bool) Setting {
+	return Setting{
+		Name: name,
+		Get:  func(c *Calc) string { return strconv.FormatBool(*field(c)) },
+		Set: func(c *Calc, value string) error {
+			enabled, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("invalid value %s for %s, use true or false", value, name)
+			}
+
+			*field(c) = enabled
+
+			return nil
+		},
+	}
+}
+
+// a setting applied using the command of the same name
+func argSetting(name string, get func(c *Calc) string, command ArgCommandFunction) Setting {
+	return Setting{
+		Name: name,
+		Get:  get,
+		Set: func(c *Calc, value string) error {
+			return command(c, []string{value})
+		},
+	}
+}
+
+func FindSetting(name string) (Setting, bool) {
+	for _, setting := range Settings {
+		if setting.Name == name {
+			return setting, true
+		}
+	}
+
+	return Setting{}, false
+}
+
+func SettingsFile() string {
+	return homeFile(".rpnrc")
+}
+
+// The settings file  consists of lines like "precision  = 4", comments
+// (#) and empty lines are ignored.
+func ReadSettings(reader io.Reader) (map[string]string, []string, error) {
+	values := map[string]string{}
+	order := []string{}
+	scanner := bufio.NewScanner(reader)
+	line := 0
+
+	for scanner.Scan() {
+		line++
+
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		name, value, ok := strings.Cut(text, "=")
+		if !ok {
+			return nil, nil, fmt.Errorf("line %d: expected name = value", line)
+		}
+
+		name = strings.TrimSpace(name)
+
+		if _, seen := values[name]; !seen {
+			order = append(order, name)
+		}
+
+		values[name] = strings.TrimSpace(value)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return values, order, nil
+}
+
+// apply the settings saved in the given file, a missing file is fine.
+// Unknown settings and invalid values are reported and ignored, so that
+// a settings file of a newer version doesn't prevent us from starting.
+func (c *Calc) LoadSettings(filename string) error {
+	if filename == "" {
+		return nil
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+
+		return fmt.Errorf("failed to read settings: %w", err)
+	}
+	defer file.Close()
+
+	values, order, err := ReadSettings(file)
+	if err != nil {
+		return fmt.Errorf("failed to read settings from %s: %w", filename, err)
+	}
+
+	for _, name := range order {
+		setting, ok := FindSetting(name)
+		if !ok {
+			fmt.Fprintf(c.errout, "ignoring unknown setting %s in %s\n", name, filename)
+
+			continue
+		}
+
+		if err := setting.Set(c, values[name]); err != nil {
+			fmt.Fprintf(c.errout, "ignoring setting %s in %s: %s\n", name, filename, err)
+		}
+	}
+
+	return nil
+}
+
+// write the given settings to the settings file, settings already in
+// there are kept. Without names all settings which differ from the
+// defaults are saved.
+func (c *Calc) SaveSettings(filename string, names []string) (int, error) {
+	if filename == "" {
+		return 0, fmt.Errorf("failed to save settings: %w", ErrNoHome)
+	}
+
+	if len(names) == 0 {
+		defaults := NewCalc()
+
+		for _, setting := range Settings {
+			if setting.Get(c) != setting.Get(defaults) {
+				names = append(names, setting.Name)
+			}
+		}
+	}
+
+	values := map[string]string{}
+
+	if file, err := os.Open(filename); err == nil {
+		values, _, err = ReadSettings(file)
+		file.Close()
+
+		if err != nil {
+			return 0, fmt.Errorf("failed to read settings from %s: %w", filename, err)
+		}
+	}
+
+	for _, name := range names {
+		setting, ok := FindSetting(name)
+		if !ok {
+			return 0, fmt.Errorf("unknown setting %s", name)
+		}
+
+		values[name] = setting.Get(c)
+	}
+
+	sorted := []string{}
+	for name := range values {
+		sorted = append(sorted, name)
+	}
+
+	sort.Strings(sorted)
+
+	var out strings.Builder
+
+	out.WriteString("# written by rpn savesettings\n")
+
+	for _, name := range sorted {
+		fmt.Fprintf(&out, "%s = %s\n", name, values[name])
+	}
+
+	if err := os.WriteFile(filename, []byte(out.String()), 0o600); err != nil {
+		return 0, fmt.Errorf("failed to save settings: %w", err)
+	}
+
+	return len(names), nil
+}
+
+// savesettings [NAME...]
+func CommandSaveSettings(c *Calc, args []string) error {
+	count, err := c.SaveSettings(SettingsFile(), args)
+	if err != nil {
+		return err
+	}
+
+	if !c.quiet {
+		fmt.Fprintf(c.out, "saved %d settings to %s\n", count, SettingsFile())
+	}
+
+	return nil
+}
+
+// show the current value of all settings, in the format of the file
+func CommandSettings(c *Calc, _ []string) error {
+	for _, setting := range Settings {
+		fmt.Fprintf(c.out, "%-14s = %s\n", setting.Name, setting.Get(c))
+	}
+
+	return nil
+}
@@ -0,0 +1,341 @@
+/*
+Copyright © 2025 Thomas von Dein
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package calc
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+const (
+	Tolerance           float64 = 1e-10
+	MaxIterations       int     = 100
+	MaxIntegrationDepth int     = 50   // of the adaptive simpson rule
+	EvaluationsPerIter  int     = 1000 // integrate evaluates at most maxiter times this
+)
+
+// estimate the derivative at x using the central difference. The step
+// is scaled with x, cbrt(epsilon) balances truncation and rounding
+// errors.
+func Derivative(function func(float64) (float64, error), x float64) (float64, error) {
+	step := math.Cbrt(2.2e-16) * math.Max(1, math.Abs(x))
+
+	above, err := function(x + step)
+	if err != nil {
+		return 0, err
+	}
+
+	below, err := function(x - step)
+	if err != nil {
+		return 0, err
+	}
+
+	derivative := (above - below) / (2 * step)
+	if math.IsNaN(derivative) || math.IsInf(derivative, 0) {
+		return 0, fmt.Errorf("function is not differentiable at %g", x)
+	}
+
+	return derivative, nil
+}
+
+// integrate function from a to b using the adaptive simpson rule,
+// giving up after maxevals evaluations of the function
+func Integrate(function func(float64) (float64, error), a, b float64,
+	tolerance float64, maxevals int) (float64, error) {
+	// the depth alone doesn't limit the work, each level may double
+	// the number of intervals
+	evaluations := 0
+	evaluate := function
+	function = func(x float64) (float64, error) {
+		if evaluations == maxevals {
+			return 0, fmt.Errorf("integral did not converge after %d function evaluations", maxevals)
+		}
+
+		evaluations++
+
+		return evaluate(x)
+	}
+
+	fa, err := function(a)
+	if err != nil {
+		return 0, err
+	}
+
+	fb, err := function(b)
+	if err != nil {
+		return 0, err
+	}
+
+	fm, err := function((a + b) / 2)
+	if err != nil {
+		return 0, err
+	}
+
+	whole := (b - a) / 6 * (fa + 4*fm + fb)
+
+	return simpson(function, a, b, fa, fm, fb, whole, tolerance, MaxIntegrationDepth)
+}
+
+// split the interval in halves until the estimate doesn't change anymore
+func simpson(function func(float64) (float64, error), a, b, fa, fm, fb, whole,
+	tolerance float64, depth int) (float64, error) {
+	if depth == 0 {
+		return 0, errors.New("integral did not converge")
+	}
+
+	middle := (a + b) / 2
+
+	fleft, err := function((a + middle) / 2)
+	if err != nil {
+		return 0, err
+	}
+
+	fright, err := function((middle + b) / 2)
+	if err != nil {
+		return 0, err
+	}
+
+	left := (middle - a) / 6 * (fa + 4*fleft + fm)
+	right := (b - middle) / 6 * (fm + 4*fright + fb)
+	delta := left + right - whole
+
+	if math.IsNaN(delta) || math.IsInf(delta, 0) {
+		return 0, fmt.Errorf("function is not integrable between %g and %g", a, b)
+	}
+
+	if math.Abs(delta) <= 15*tolerance {
+		return left + right + delta/15, nil
+	}
+
+	leftresult, err := simpson(function, a, middle, fa, fleft, fm, left, tolerance/2, depth-1)
+	if err != nil {
+		return 0, err
+	}
+
+	rightresult, err := simpson(function, middle, b, fm, fright, fb, right, tolerance/2, depth-1)
+	if err != nil {
+		return 0, err
+	}
+
+	return leftresult + rightresult, nil
+}
+
+// find a root of  function using Newton's method, starting at guess.
+// The derivative is estimated numerically using the central difference.
+func Newton(function func(float64) (float64, error), guess float64,
+	tolerance float64, maxiter int) (float64, error) {
+	x := guess
+
+	for range maxiter {
+		fx, err := function(x)
+		if err != nil {
+			return 0, err
+		}
+
+		if math.IsNaN(fx) || math.IsInf(fx, 0) {
+			return 0, fmt.Errorf("function is not defined at %g", x)
+		}
+
+		if math.Abs(fx) <= tolerance {
+			return x, nil
+		}
+
+		derivative, err := Derivative(function, x)
+		if err != nil {
+			return 0, err
+		}
+
+		if derivative == 0 {
+			return 0, fmt.Errorf("derivative vanishes at %g", x)
+		}
+
+		next := x - fx/derivative
+		if math.Abs(next-x) <= tolerance*math.Max(1, math.Abs(x)) {
+			return next, nil
+		}
+
+		x = next
+	}
+
+	return 0, fmt.Errorf("no root found after %d iterations", maxiter)
+}
+
+// return a go function calling the given 1-arg lua function
+func (c *Calc) LuaFunction(args []string, usage string) (string, func(float64) (float64, error), error) {
+	if len(args) == 0 {
+		return "", nil, fmt.Errorf("usage: %s <lua function>", usage)
+	}
+
+	funcname := args[0]
+
+	if c.interpreter == nil || !contains(c.LuaFunctions, funcname) {
+		return "", nil, fmt.Errorf("unknown lua function %s", funcname)
+	}
+
+	if numargs := c.interpreter.FuncNumArgs(funcname); numargs != 0 && numargs != 1 {
+		return "", nil, fmt.Errorf("lua function %s must expect 1 argument", funcname)
+	}
+
+	return funcname, func(x float64) (float64, error) {
+		return c.interpreter.CallLuaFunc(funcname, []float64{x})
+	}, nil
+}
+
+// results of the numeric commands are subject to the same rules as
+// those of DoFuncall(): rounded in money mode and neither NaN nor Inf
+func (c *Calc) RoundResult(result float64) (float64, error) {
+	if err := c.CheckNumber(result); err != nil {
+		return 0, err
+	}
+
+	return c.Round(result), nil
+}
+
+// solve  funcname(x) = 0  using the last  stack item  as initial guess,
+// which is being replaced by the root
+func CommandSolve(c *Calc, args []string) error {
+	funcname, function, err := c.LuaFunction(args, "solve")
+	if err != nil {
+		return err
+	}
+
+	if c.stack.Len() == 0 {
+		return errors.New("solve needs an initial guess on the stack")
+	}
+
+	guess := c.stack.Last()[0]
+
+	root, err := Newton(function, guess, c.tolerance, c.maxiter)
+	if err == nil {
+		root, err = c.RoundResult(root)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	c.stack.Backup()
+	c.stack.Shift()
+	c.stack.Push(root)
+
+	c.ResultHistory(root, "solve %s(x) = 0, x0 = %f", funcname, guess)
+	c.Result()
+
+	return nil
+}
+
+// integrate funcname between the last two stack items
+func CommandIntegrate(c *Calc, args []string) error {
+	funcname, function, err := c.LuaFunction(args, "integrate")
+	if err != nil {
+		return err
+	}
+
+	if c.stack.Len() < 2 {
+		return errors.New("integrate needs the bounds a and b on the stack")
+	}
+
+	bounds := c.stack.Last(2)
+
+	integral, err := Integrate(function, bounds[0], bounds[1], c.tolerance,
+		c.maxiter*EvaluationsPerIter)
+	if err == nil {
+		integral, err = c.RoundResult(integral)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	c.stack.Backup()
+	c.stack.Shift(2)
+	c.stack.Push(integral)
+
+	c.ResultHistory(integral, "integrate %s(x) from %f to %f", funcname, bounds[0], bounds[1])
+	c.Result()
+
+	return nil
+}
+
+// derivative of funcname at the last stack item
+func CommandDerivative(c *Calc, args []string) error {
+	funcname, function, err := c.LuaFunction(args, "deriv")
+	if err != nil {
+		return err
+	}
+
+	if c.stack.Len() == 0 {
+		return errors.New("deriv needs a point x on the stack")
+	}
+
+	x := c.stack.Last()[0]
+
+	derivative, err := Derivative(function, x)
+	if err == nil {
+		derivative, err = c.RoundResult(derivative)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	c.stack.Backup()
+	c.stack.Shift()
+	c.stack.Push(derivative)
+
+	c.ResultHistory(derivative, "deriv %s'(%f)", funcname, x)
+	c.Result()
+
+	return nil
+}
+
+func CommandTolerance(c *Calc, args []string) error {
+	if len(args) == 0 {
+		fmt.Fprintf(c.out, "tolerance: %g\n", c.tolerance)
+
+		return nil
+	}
+
+	tolerance, err := strconv.ParseFloat(args[0], 64)
+	if err != nil || tolerance <= 0 {
+		return fmt.Errorf("invalid tolerance %s", args[0])
+	}
+
+	c.tolerance = tolerance
+
+	return nil
+}
+
+func CommandMaxIterations(c *Calc, args []string) error {
+	if len(args) == 0 {
+		fmt.Fprintf(c.out, "maxiter: %d\n", c.maxiter)
+
+		return nil
+	}
+
+	maxiter, err := strconv.Atoi(args[0])
+	if err != nil || maxiter <= 0 {
+		return fmt.Errorf("invalid number of iterations %s", args[0])
+	}
+
+	c.maxiter = maxiter
+
+	return nil
+}
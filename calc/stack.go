@@ -0,0 +1,538 @@
+/*
+Copyright © 2023 Thomas von Dein
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package calc
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// The stack uses a linked  list provided by container/list as storage
+// and works after the LIFO principle (last in first out). Most of the
+// work is  being done  in the  linked list,  but we  add a  couple of
+// cenvenient functions,  so that the  user doesn't have to  cope with
+// list directly.
+
+type Stack struct {
+	linklist      list.List
+	backup        list.List
+	checkpoint    list.List // only set by the user, see Checkpoint()
+	hascheckpoint bool
+	debug         bool
+	rev           int
+	backuprev     int
+	mutex         sync.Mutex
+	out           io.Writer // debug output
+
+	// optional, restores state beyond the stack which belongs to the
+	// backup, see BackupWith()
+	onrestore func()
+}
+
+// FIXME: maybe use a separate stack  object for backup so that it has
+// its own revision etc
+func NewStack() *Stack {
+	return &Stack{
+		linklist:  list.List{},
+		backup:    list.List{},
+		rev:       0,
+		backuprev: 0,
+		out:       os.Stdout,
+	}
+}
+
+func (s *Stack) Debug(msg string) {
+	if s.debug {
+		fmt.Fprintf(s.out, "DEBUG(%03d): %s\n", s.rev, msg)
+	}
+}
+
+func (s *Stack) ToggleDebug() {
+	s.debug = !s.debug
+}
+
+func (s *Stack) Bump() {
+	s.rev++
+}
+
+// append an item to the stack
+func (s *Stack) Push(item float64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.Debug(fmt.Sprintf("     push to stack: %.2f", item))
+
+	s.Bump()
+	s.linklist.PushBack(item)
+}
+
+// remove and return an item from the stack
+func (s *Stack) Pop() float64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.linklist.Len() == 0 {
+		return 0
+	}
+
+	tail := s.linklist.Back()
+	val := tail.Value
+	s.linklist.Remove(tail)
+
+	s.Debug(fmt.Sprintf(" remove from stack: %.2f", val))
+
+	s.Bump()
+
+	return val.(float64)
+}
+
+// just remove the last item(s), do not return them. Returns the number
+// of items actually removed, which is less than count if the stack is
+// too small.
+func (s *Stack) Shift(num ...int) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	count := 1
+
+	if len(num) > 0 {
+		count = num[0]
+	}
+
+	removed := 0
+
+	for ; removed < count && s.linklist.Len() > 0; removed++ {
+		tail := s.linklist.Back()
+		s.linklist.Remove(tail)
+		s.Debug(fmt.Sprintf("remove from stack: %.2f", tail.Value))
+	}
+
+	return removed
+}
+
+func (s *Stack) Swap() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.linklist.Len() < 2 {
+		return
+	}
+
+	prevA := s.linklist.Back()
+	s.linklist.Remove(prevA)
+
+	prevB := s.linklist.Back()
+	s.linklist.Remove(prevB)
+
+	s.Debug(fmt.Sprintf("swapping %.2f with %.2f", prevB.Value, prevA.Value))
+
+	s.linklist.PushBack(prevA.Value)
+	s.linklist.PushBack(prevB.Value)
+}
+
+// copy the second last item to the top: a b -> a b a
+func (s *Stack) Over() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.linklist.Len() < 2 {
+		return errors.New("stack too small, over needs 2 items")
+	}
+
+	second := s.linklist.Back().Prev()
+	s.Debug(fmt.Sprintf("copying %.2f to the top", second.Value))
+
+	s.Bump()
+	s.linklist.PushBack(second.Value)
+
+	return nil
+}
+
+// duplicate the last count items as a block: a b c, 2 -> a b c b c.
+// The stack remains unchanged if it has less items.
+func (s *Stack) DupN(count int) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if count < 1 || count > s.linklist.Len() {
+		return fmt.Errorf("can't duplicate %d items, stack has %d items", count, s.linklist.Len())
+	}
+
+	first := s.linklist.Back()
+	for index := count; index > 1; index-- {
+		first = first.Prev()
+	}
+
+	last := s.linklist.Back()
+	s.Bump()
+
+	for element := first; ; element = element.Next() {
+		s.Debug(fmt.Sprintf("     push to stack: %.2f", element.Value))
+		s.linklist.PushBack(element.Value)
+
+		if element == last {
+			break
+		}
+	}
+
+	return nil
+}
+
+// remove the second last item: a b -> b
+func (s *Stack) Nip() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.linklist.Len() < 2 {
+		return errors.New("stack too small, nip needs 2 items")
+	}
+
+	second := s.linklist.Back().Prev()
+	s.Debug(fmt.Sprintf("remove from stack: %.2f", second.Value))
+
+	s.linklist.Remove(second)
+
+	return nil
+}
+
+// move the item at the given position counting from the top, which is
+// 1, to the top. The stack remains unchanged if there's no such item.
+func (s *Stack) Roll(index int) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if index < 1 || index > s.linklist.Len() {
+		return fmt.Errorf("no stack item %d, stack has %d items", index, s.linklist.Len())
+	}
+
+	element := s.linklist.Back()
+	for ; index > 1; index-- {
+		element = element.Prev()
+	}
+
+	s.Debug(fmt.Sprintf("rolling %.2f to the top", element.Value))
+
+	s.linklist.MoveToBack(element)
+
+	return nil
+}
+
+// Return the last num items from the stack w/o modifying it.
+func (s *Stack) Last(num ...int) []float64 {
+	items := []float64{}
+	stacklen := s.Len()
+	count := 1
+
+	if len(num) > 0 {
+		count = num[0]
+	}
+
+	for e := s.linklist.Front(); e != nil; e = e.Next() {
+		if stacklen <= count {
+			items = append(items, e.Value.(float64))
+		}
+		stacklen--
+	}
+
+	return items
+}
+
+// Return the item at the given position counting from the top, which is
+// 1, w/o modifying the stack.
+func (s *Stack) PickFrom(index int) (float64, error) {
+	if index < 1 || index > s.linklist.Len() {
+		return 0, fmt.Errorf("no stack item %d, stack has %d items", index, s.linklist.Len())
+	}
+
+	element := s.linklist.Back()
+	for ; index > 1; index-- {
+		element = element.Prev()
+	}
+
+	return element.Value.(float64), nil
+}
+
+// Return all elements of the stack without modifying it.
+func (s *Stack) All() []float64 {
+	items := []float64{}
+
+	for e := s.linklist.Front(); e != nil; e = e.Next() {
+		items = append(items, e.Value.(float64))
+	}
+
+	return items
+}
+
+// dump the stack to out, including backup if debug is enabled. Every
+// item is formatted using the given function and printed along with
+// its position, counting from the top (#1), which is marked as such.
+func (s *Stack) Dump(out io.Writer, format func(float64) string) {
+	fmt.Fprintf(out, "Stack revision %d (%p):\n", s.rev, &s.linklist)
+	dumpItems(out, s.All(), format)
+
+	if s.debug {
+		fmt.Fprintf(out, "Backup stack revision %d (%p):\n", s.backuprev, &s.backup)
+		dumpItems(out, s.BackupItems(), format)
+	}
+}
+
+// print items  bottom up, so that the  top is the last  line, with the
+// numbers right aligned
+func dumpItems(out io.Writer, items []float64, format func(float64) string) {
+	numbers := make([]string, len(items))
+	width := 0
+
+	for idx, item := range items {
+		numbers[idx] = format(item)
+		width = max(width, utf8.RuneCountInString(numbers[idx]))
+	}
+
+	poswidth := len(strconv.Itoa(len(items))) + 1
+
+	for idx, number := range numbers {
+		position := len(items) - idx
+		top := ""
+
+		if position == 1 {
+			top = " <- top"
+		}
+
+		fmt.Fprintf(out, "%*s: %*s%s\n", poswidth, "#"+strconv.Itoa(position), width, number, top)
+	}
+}
+
+// describe the region of the stack which differs between before and
+// after, e.g. "... 5 3 -> ... 3 5". The unchanged items at the bottom
+// are abbreviated with ..., except the last one, if needed to show
+// what has been added or removed. Every item is formatted using the
+// given function, same as Diff(). Returns false if nothing changed.
+func ChangedRegion(before, after []float64, format func(float64) string) (string, bool) {
+	common := 0
+	for common < len(before) && common < len(after) && before[common] == after[common] {
+		common++
+	}
+
+	if common == len(before) && common == len(after) {
+		return "", false
+	}
+
+	if common > 0 && (common == len(before) || common == len(after)) {
+		common--
+	}
+
+	region := func(items []float64) string {
+		parts := []string{}
+
+		if common > 0 {
+			parts = append(parts, "...")
+		}
+
+		for _, item := range items[common:] {
+			parts = append(parts, format(item))
+		}
+
+		if len(parts) == 0 {
+			return "(empty)"
+		}
+
+		return strings.Join(parts, " ")
+	}
+
+	return region(before) + " -> " + region(after), true
+}
+
+// the number of positions, counting from the bottom, which differ
+// between before and after, added and removed items included
+func ChangedItems(before, after []float64) int {
+	changed := 0
+
+	for pos := range max(len(before), len(after)) {
+		if pos >= len(before) || pos >= len(after) || before[pos] != after[pos] {
+			changed++
+		}
+	}
+
+	return changed
+}
+
+// Return all elements of the backup stack without modifying it.
+func (s *Stack) BackupItems() []float64 {
+	items := []float64{}
+
+	for e := s.backup.Front(); e != nil; e = e.Next() {
+		items = append(items, e.Value.(float64))
+	}
+
+	return items
+}
+
+// compare the backup with  the current stack position by position,
+// starting at the bottom. Unchanged items are prefixed with a space,
+// removed ones with - and added ones with +, like in a unified diff.
+// Every item is formatted using the given function, same as Dump().
+func (s *Stack) Diff(format func(float64) string) []string {
+	before := s.BackupItems()
+	after := s.All()
+	lines := []string{}
+
+	for pos := 0; pos < max(len(before), len(after)); pos++ {
+		switch {
+		case pos >= len(before):
+			lines = append(lines, "+ "+format(after[pos]))
+		case pos >= len(after):
+			lines = append(lines, "- "+format(before[pos]))
+		case before[pos] == after[pos]:
+			lines = append(lines, "  "+format(after[pos]))
+		default:
+			lines = append(lines,
+				"- "+format(before[pos]),
+				"+ "+format(after[pos]))
+		}
+	}
+
+	return lines
+}
+
+func (s *Stack) Clear() {
+	s.Debug("clearing stack")
+
+	s.linklist = list.List{}
+}
+
+func (s *Stack) Len() int {
+	return s.linklist.Len()
+}
+
+func (s *Stack) Backup() {
+	// we need clean the list and restore it from scratch each time we
+	// make a backup, because the elements in list.List{} are pointers
+	// and lead to unexpected  results. The methid here works reliably
+	// at least.
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.Debug(fmt.Sprintf("backing up %d items from rev %d",
+		s.linklist.Len(), s.rev))
+
+	s.backup = list.List{}
+	for e := s.linklist.Front(); e != nil; e = e.Next() {
+		s.backup.PushBack(e.Value.(float64))
+	}
+
+	s.backuprev = s.rev
+	s.onrestore = nil
+}
+
+// same as Backup(), restore is called by Restore() as well, so that
+// operations which modify more than the stack can be undone entirely
+func (s *Stack) BackupWith(restore func()) {
+	s.Backup()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.onrestore = restore
+}
+
+func (s *Stack) Restore() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.rev == 0 {
+		return errors.New("stack is empty")
+	}
+
+	s.Debug(fmt.Sprintf("restoring stack to revision %d", s.backuprev))
+
+	s.rev = s.backuprev
+
+	s.linklist = list.List{}
+	for e := s.backup.Front(); e != nil; e = e.Next() {
+		s.linklist.PushBack(e.Value.(float64))
+	}
+
+	if s.onrestore != nil {
+		s.onrestore()
+	}
+
+	return nil
+}
+
+// Unlike  the backup,  the checkpoint  is only  modified on  request, so
+// that the user can go back to a known state after experimenting.
+func (s *Stack) Checkpoint() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.Debug(fmt.Sprintf("checkpoint of %d items at rev %d", s.linklist.Len(), s.rev))
+
+	s.checkpoint = list.List{}
+	for e := s.linklist.Front(); e != nil; e = e.Next() {
+		s.checkpoint.PushBack(e.Value.(float64))
+	}
+
+	s.hascheckpoint = true
+}
+
+// replace the stack with  the checkpoint, which is kept, so that we can
+// roll back multiple times
+func (s *Stack) Rollback() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !s.hascheckpoint {
+		return errors.New("no checkpoint set")
+	}
+
+	s.Debug(fmt.Sprintf("rolling back to checkpoint of %d items", s.checkpoint.Len()))
+
+	s.linklist = list.List{}
+	for e := s.checkpoint.Front(); e != nil; e = e.Next() {
+		s.linklist.PushBack(e.Value.(float64))
+	}
+
+	return nil
+}
+
+func (s *Stack) HasCheckpoint() bool {
+	return s.hascheckpoint
+}
+
+func (s *Stack) Reverse() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	items := []float64{}
+
+	for e := s.linklist.Front(); e != nil; e = e.Next() {
+		tail := s.linklist.Back()
+		items = append(items, tail.Value.(float64))
+		s.linklist.Remove(tail)
+	}
+
+	for i := len(items) - 1; i >= 0; i-- {
+		s.linklist.PushFront(items[i])
+	}
+}
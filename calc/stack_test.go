@@ -0,0 +1,455 @@
+/*
+Copyright © 2023 Thomas von Dein
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package calc
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestPush(t *testing.T) {
+	t.Run("push", func(t *testing.T) {
+		s := NewStack()
+		s.Push(5)
+
+		if s.linklist.Back().Value != 5.0 {
+			t.Errorf("push failed:\n+++  got: %f\n--- want: %f",
+				s.linklist.Back().Value, 5.0)
+		}
+	})
+}
+
+func TestPop(t *testing.T) {
+	t.Run("pop", func(t *testing.T) {
+		stack := NewStack()
+		stack.Push(5)
+		got := stack.Pop()
+
+		if got != 5.0 {
+			t.Errorf("pop failed:\n+++  got: %f\n--- want: %f",
+				got, 5.0)
+		}
+
+		if stack.Len() != 0 {
+			t.Errorf("stack not empty after pop()")
+		}
+	})
+}
+
+func TestPops(t *testing.T) {
+	t.Run("pops", func(t *testing.T) {
+		stack := NewStack()
+		stack.Push(5)
+		stack.Push(5)
+		stack.Push(5)
+		stack.Pop()
+
+		if stack.Len() != 2 {
+			t.Errorf("stack len not correct after pop:\n+++  got: %d\n--- want: %d",
+				stack.Len(), 2)
+		}
+	})
+}
+
+func TestShift(t *testing.T) {
+	t.Run("shift", func(t *testing.T) {
+		stack := NewStack()
+		stack.Shift()
+
+		if stack.Len() != 0 {
+			t.Errorf("stack not empty after shift()")
+		}
+	})
+
+	var tests = []struct {
+		name    string
+		count   int
+		removed int
+		left    int
+	}{
+		{name: "none", count: 0, removed: 0, left: 3},
+		{name: "some", count: 2, removed: 2, left: 1},
+		{name: "all", count: 3, removed: 3, left: 0},
+		{name: "too-many", count: 5, removed: 3, left: 0},
+		{name: "negative", count: -1, removed: 0, left: 3},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stack := NewStack()
+			stack.Push(1)
+			stack.Push(2)
+			stack.Push(3)
+
+			removed := stack.Shift(test.count)
+
+			if removed != test.removed || stack.Len() != test.left {
+				t.Errorf("shift(%d) failed:\n+++  got: %d removed, %d left\n--- want: %d removed, %d left",
+					test.count, removed, stack.Len(), test.removed, test.left)
+			}
+		})
+	}
+}
+
+func TestClear(t *testing.T) {
+	t.Run("clear", func(t *testing.T) {
+		stack := NewStack()
+		stack.Push(5)
+		stack.Push(5)
+		stack.Push(5)
+		stack.Clear()
+
+		if stack.Len() != 0 {
+			t.Errorf("stack not empty after clear()")
+		}
+	})
+}
+
+func TestLast(t *testing.T) {
+	t.Run("last", func(t *testing.T) {
+		stack := NewStack()
+		stack.Push(5)
+		got := stack.Last()
+
+		if len(got) != 1 {
+			t.Errorf("last failed:\n+++  got: %d elements\n--- want: %d elements",
+				len(got), 1)
+		}
+
+		if got[0] != 5.0 {
+			t.Errorf("last failed:\n+++  got: %f\n--- want: %f",
+				got, 5.0)
+		}
+
+		if stack.Len() != 1 {
+			t.Errorf("stack modified after last()")
+		}
+	})
+}
+
+func TestPickFrom(t *testing.T) {
+	var tests = []struct {
+		index int
+		exp   float64
+		err   bool
+	}{
+		{index: 1, exp: 3},
+		{index: 2, exp: 2},
+		{index: 3, exp: 1},
+		{index: 0, err: true},
+		{index: 4, err: true},
+	}
+
+	for _, test := range tests {
+		t.Run("pick-"+strconv.Itoa(test.index), func(t *testing.T) {
+			stack := NewStack()
+			stack.Push(1)
+			stack.Push(2)
+			stack.Push(3)
+
+			got, err := stack.PickFrom(test.index)
+
+			if test.err {
+				if err == nil {
+					t.Errorf("%d accepted, expected error", test.index)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != test.exp {
+				t.Errorf("pick failed:\n+++  got: %f\n--- want: %f", got, test.exp)
+			}
+
+			if stack.Len() != 3 {
+				t.Errorf("stack modified after PickFrom()")
+			}
+		})
+	}
+}
+
+func TestRoll(t *testing.T) {
+	var tests = []struct {
+		index int
+		exp   string
+		err   bool
+	}{
+		{index: 1, exp: "1 2 3 4 5"},
+		{index: 2, exp: "1 2 3 5 4"},
+		{index: 3, exp: "1 2 4 5 3"},
+		{index: 5, exp: "2 3 4 5 1"},
+		{index: 0, err: true},
+		{index: 6, err: true},
+	}
+
+	for _, test := range tests {
+		t.Run("roll-"+strconv.Itoa(test.index), func(t *testing.T) {
+			stack := NewStack()
+			for item := 1; item <= 5; item++ {
+				stack.Push(float64(item))
+			}
+
+			err := stack.Roll(test.index)
+
+			if test.err {
+				if err == nil {
+					t.Errorf("%d accepted, expected error", test.index)
+				}
+
+				if got := list2str(stack.All()); got != "1 2 3 4 5" {
+					t.Errorf("stack modified after failed roll: %s", got)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := list2str(stack.All()); got != test.exp {
+				t.Errorf("roll failed:\n+++  got: %s\n--- want: %s", got, test.exp)
+			}
+		})
+	}
+}
+
+func TestAll(t *testing.T) {
+	t.Run("all", func(t *testing.T) {
+		stack := NewStack()
+		list := []float64{2, 4, 6, 8}
+
+		for _, item := range list {
+			stack.Push(item)
+		}
+
+		got := stack.All()
+
+		if len(got) != len(list) {
+			t.Errorf("all failed:\n+++  got: %d elements\n--- want: %d elements",
+				len(got), len(list))
+		}
+
+		for i := 1; i < len(list); i++ {
+			if got[i] != list[i] {
+				t.Errorf("all failed (element %d):\n+++  got: %f\n--- want: %f",
+					i, got[i], list[i])
+			}
+		}
+
+		if stack.Len() != len(list) {
+			t.Errorf("stack modified after last()")
+		}
+	})
+}
+
+func TestBackupRestore(t *testing.T) {
+	t.Run("shift", func(t *testing.T) {
+		stack := NewStack()
+		stack.Push(5)
+		stack.Backup()
+		stack.Clear()
+		stack.Restore()
+
+		if stack.Len() != 1 {
+			t.Errorf("stack not correctly restored()")
+		}
+
+		value := stack.Pop()
+		if value != 5.0 {
+			t.Errorf("stack not identical to old revision:\n+++  got: %f\n--- want: %f",
+				value, 5.0)
+		}
+	})
+}
+
+func TestReverse(t *testing.T) {
+	t.Run("reverse", func(t *testing.T) {
+		stack := NewStack()
+		list := []float64{2, 4, 6}
+		reverse := []float64{6, 4, 2}
+
+		for _, item := range list {
+			stack.Push(item)
+		}
+
+		stack.Reverse()
+
+		got := stack.All()
+
+		if len(got) != len(list) {
+			t.Errorf("all failed:\n+++  got: %d elements\n--- want: %d elements",
+				len(got), len(list))
+		}
+
+		for i := 1; i < len(reverse); i++ {
+			if got[i] != reverse[i] {
+				t.Errorf("reverse failed (element %d):\n+++  got: %f\n--- want: %f",
+					i, got[i], list[i])
+			}
+		}
+	})
+}
+
+func TestDiff(t *testing.T) {
+	var tests = []struct {
+		name string
+		cmd  string
+		exp  []string
+	}{
+		{
+			name: "push",
+			cmd:  `1 2 3`,
+			exp:  []string{"  1", "  2", "+ 3"},
+		},
+		{
+			name: "operation",
+			cmd:  `1 2 3 +`,
+			exp:  []string{"  1", "- 2", "+ 5", "- 3"},
+		},
+		{
+			name: "clear",
+			cmd:  `1 2 clear`,
+			exp:  []string{"- 1", "- 2"},
+		},
+		{
+			name: "undo",
+			cmd:  `1 2 + undo`,
+			exp:  []string{"  1", "  2"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			calc := NewCalc()
+			calc.quiet = true
+
+			if err := calc.Eval(test.cmd); err != nil {
+				t.Fatal(err)
+			}
+
+			got := calc.stack.Diff(calc.FormatItem)
+			if strings.Join(got, "|") != strings.Join(test.exp, "|") {
+				t.Errorf("diff failed:\n+++  got: %q\n--- want: %q", got, test.exp)
+			}
+		})
+	}
+}
+
+func TestDump(t *testing.T) {
+	format := func(item float64) string {
+		return strconv.FormatFloat(item, 'f', 2, 64)
+	}
+
+	var tests = []struct {
+		name  string
+		items []float64
+		debug bool
+		exp   string
+	}{
+		{
+			name:  "empty",
+			items: []float64{},
+			exp:   "",
+		},
+		{
+			name:  "aligned",
+			items: []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 3.14159},
+			exp: `#11:  1.00
+#10:  2.00
+ #9:  3.00
+ #8:  4.00
+ #7:  5.00
+ #6:  6.00
+ #5:  7.00
+ #4:  8.00
+ #3:  9.00
+ #2: 10.00
+ #1:  3.14 <- top
+`,
+		},
+		{
+			name:  "backup",
+			items: []float64{-1, 100},
+			debug: true,
+			exp: `#2:  -1.00
+#1: 100.00 <- top
+#1: -1.00 <- top
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			stack := NewStack()
+
+			for _, item := range test.items {
+				stack.Backup()
+				stack.Push(item)
+			}
+
+			stack.debug = test.debug
+			stack.Dump(out, format)
+
+			// remove the headers, they contain the address of the stack
+			got := regexp.MustCompile(`(?m)^.*revision.*\n`).ReplaceAllString(out.String(), "")
+
+			if got != test.exp {
+				t.Errorf("dump failed:\n+++  got: %s\n--- want: %s", got, test.exp)
+			}
+		})
+	}
+}
+
+func TestChangedRegion(t *testing.T) {
+	var tests = []struct {
+		name   string
+		before []float64
+		after  []float64
+		exp    string
+		ok     bool
+	}{
+		{name: "swap", before: []float64{1, 5, 3}, after: []float64{1, 3, 5}, exp: "... 5 3 -> ... 3 5", ok: true},
+		{name: "reverse", before: []float64{1, 2, 3}, after: []float64{3, 2, 1}, exp: "1 2 3 -> 3 2 1", ok: true},
+		{name: "dup", before: []float64{1, 2}, after: []float64{1, 2, 2}, exp: "... 2 -> ... 2 2", ok: true},
+		{name: "shift", before: []float64{1, 2, 3}, after: []float64{1, 2}, exp: "... 2 3 -> ... 2", ok: true},
+		{name: "clear", before: []float64{1, 2}, after: []float64{}, exp: "1 2 -> (empty)", ok: true},
+		{name: "unchanged", before: []float64{1, 2}, after: []float64{1, 2}, ok: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, ok := ChangedRegion(test.before, test.after, func(item float64) string {
+				return strconv.FormatFloat(item, 'g', -1, 64)
+			})
+
+			if ok != test.ok || got != test.exp {
+				t.Errorf("changed region failed:\n+++  got: %s %t\n--- want: %s %t",
+					got, ok, test.exp, test.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+/*
+Copyright © 2025 Thomas von Dein
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package calc
+
+import (
+	"sort"
+	"strings"
+)
+
+// converters are named like miles-to-kilometers
+const ConversionSeparator string = "-to-"
+
+// the number of single character edits needed to turn a into b
+func levenshtein(a, b string) int {
+	source, target := []rune(a), []rune(b)
+	previous := make([]int, len(target)+1)
+	current := make([]int, len(target)+1)
+
+	for pos := range previous {
+		previous[pos] = pos
+	}
+
+	for i := range source {
+		current[0] = i + 1
+
+		for j := range target {
+			cost := 1
+			if source[i] == target[j] {
+				cost = 0
+			}
+
+			current[j+1] = min(previous[j+1]+1, current[j]+1, previous[j]+cost)
+		}
+
+		previous, current = current, previous
+	}
+
+	return previous[len(target)]
+}
+
+// find the candidate closest to word, which is at most maxdistance
+// edits away. On a tie the alphabetically first candidate wins.
+func closest(word string, candidates []string, maxdistance int) (string, bool) {
+	sorted := append([]string{}, candidates...)
+	sort.Strings(sorted)
+
+	best, bestdistance := "", maxdistance+1
+
+	for _, candidate := range sorted {
+		if distance := levenshtein(word, candidate); distance < bestdistance {
+			best, bestdistance = candidate, distance
+		}
+	}
+
+	return best, best != ""
+}
+
+// all names the user can enter, used for suggestions
+func (c *Calc) KnownNames() []string {
+	names := append([]string{}, c.Constants...)
+	names = append(names, c.LuaFunctions...)
+
+	for _, funcmap := range []Funcalls{c.Funcalls, c.BatchFuncalls} {
+		for name := range funcmap {
+			names = append(names, name)
+		}
+	}
+
+	for _, commands := range []Commands{c.Commands, c.ShowCommands, c.StackCommands, c.SettingsCommands} {
+		for name := range commands {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// the units of all conversion functions, e.g. miles and kilometers
+func (c *Calc) UnitWords() []string {
+	units := []string{}
+
+	for name := range c.Funcalls {
+		from, to, ok := strings.Cut(name, ConversionSeparator)
+		if !ok || from == "" || to == "" {
+			continue
+		}
+
+		for _, unit := range []string{from, to} {
+			if !contains(units, unit) {
+				units = append(units, unit)
+			}
+		}
+	}
+
+	return units
+}
+
+// suggest what the user might have meant with an unknown item. Names of
+// conversions are matched unit by unit, so that mile-to-kilometer
+// leads to miles-to-kilometers, everything else is matched as a whole.
+func (c *Calc) Suggest(item string) (string, bool) {
+	if from, to, ok := strings.Cut(item, ConversionSeparator); ok && from != "" && to != "" {
+		return c.SuggestConversion(from, to)
+	}
+
+	return closest(item, c.KnownNames(), min(2, len(item)/3))
+}
+
+func (c *Calc) SuggestConversion(from, to string) (string, bool) {
+	units := c.UnitWords()
+
+	unit := func(word string) (string, bool) {
+		return closest(word, units, max(1, len(word)/3))
+	}
+
+	fromunit, ok := unit(from)
+	if !ok {
+		return "", false
+	}
+
+	tounit, ok := unit(to)
+	if !ok {
+		return "", false
+	}
+
+	name := fromunit + ConversionSeparator + tounit
+	if !exists(c.Funcalls, name) {
+		return "", false
+	}
+
+	return name, true
+}
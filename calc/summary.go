@@ -0,0 +1,119 @@
+/*
+Copyright © 2025 Thomas von Dein
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package calc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// The report printed by --summary, describing the numbers on the
+// stack. The field names are part of the json interface.
+type Summary struct {
+	Count  int     `json:"count"`
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+	Mean   float64 `json:"mean"`
+	Median float64 `json:"median"`
+	Stddev float64 `json:"stddev"`
+	Sum    float64 `json:"sum"`
+}
+
+// compute the summary of the stack using the batch functions, the
+// stack itself is left alone
+func (c *Calc) Summarize() (Summary, error) {
+	items := c.stack.All()
+
+	if len(items) == 0 {
+		return Summary{}, errors.New("no numbers to summarize")
+	}
+
+	batch := func(name string) float64 {
+		return c.BatchFuncalls[name].Func(items).Res
+	}
+
+	return Summary{
+		Count:  len(items),
+		Min:    batch("min"),
+		Max:    batch("max"),
+		Mean:   batch("mean"),
+		Median: batch("median"),
+		Stddev: standardDeviation(items),
+		Sum:    batch("sum"),
+	}, nil
+}
+
+// called on exit if --summary has been given
+func (c *Calc) PrintSummary() error {
+	if c.summary == "" {
+		return nil
+	}
+
+	summary, err := c.Summarize()
+	if err != nil {
+		return err
+	}
+
+	switch c.summary {
+	case "json":
+		out, err := json.Marshal(summary)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintln(c.out, string(out))
+	case "text":
+		c.PrintSummaryTable(summary)
+	default:
+		return fmt.Errorf("unsupported format %s", c.summary)
+	}
+
+	return nil
+}
+
+// one line per value, formatted like results
+func (c *Calc) PrintSummaryTable(summary Summary) {
+	fmt.Fprintf(c.out, "%-8s %d\n", "count", summary.Count)
+
+	for _, field := range []struct {
+		label string
+		value float64
+	}{
+		{"min", summary.Min},
+		{"max", summary.Max},
+		{"mean", summary.Mean},
+		{"median", summary.Median},
+		{"stddev", summary.Stddev},
+		{"sum", summary.Sum},
+	} {
+		fmt.Fprintf(c.out, "%-8s %s\n", field.label, c.FormatResult(field.value))
+	}
+}
+
+// the stats command, the summary of the stack on demand
+func CommandStats(c *Calc, _ []string) error {
+	summary, err := c.Summarize()
+	if err != nil {
+		return err
+	}
+
+	c.PrintSummaryTable(summary)
+
+	return nil
+}
@@ -0,0 +1,70 @@
+/*
+Copyright © 2025 Thomas von Dein
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package calc
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// tee FILE appends every printed result along with the input line
+// which produced it to FILE
+func CommandTee(c *Calc, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: tee <filename>")
+	}
+
+	filename := expandHome(args[0])
+
+	file, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open tee file: %w", err)
+	}
+
+	c.StopTee()
+	c.tee = file
+
+	return nil
+}
+
+// close the tee file, if any
+func (c *Calc) StopTee() {
+	if c.tee == nil {
+		return
+	}
+
+	if err := c.tee.Close(); err != nil {
+		c.PrintError(fmt.Errorf("failed to close tee file: %w", err))
+	}
+
+	c.tee = nil
+}
+
+// record a result, called from Result(). In case of an error the tee
+// is disabled, so that it's only reported once.
+func (c *Calc) Tee(result float64) {
+	if c.tee == nil {
+		return
+	}
+
+	if _, err := fmt.Fprintf(c.tee, "%s = %s\n", c.line, c.FormatResult(result)); err != nil {
+		c.PrintError(fmt.Errorf("failed to write tee file, tee disabled: %w", err))
+		c.StopTee()
+	}
+}
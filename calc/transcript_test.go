@@ -0,0 +1,119 @@
+/*
+Copyright © 2025 Thomas von Dein
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package calc
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rogpeppe/go-internal/txtar"
+)
+
+// regenerate the expected output using: go test -run TestTranscripts -update
+var update = flag.Bool("update", false, "update the transcripts in testdata/")
+
+// Every transcript in testdata/transcripts consists of the input lines
+// and the complete output of an interactive session, just like in the
+// repl, errors included:
+//
+//	comment describing the session
+//	-- input --
+//	2 2 +
+//	-- output --
+//	= 4
+func TestTranscripts(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "transcripts", "*.txtar"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(files) == 0 {
+		t.Fatal("no transcripts found")
+	}
+
+	for _, file := range files {
+		t.Run(strings.TrimSuffix(filepath.Base(file), ".txtar"), func(t *testing.T) {
+			archive, err := txtar.ParseFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			input, output := transcriptSections(t, archive)
+
+			got := RunTranscript(string(input.Data))
+
+			if *update {
+				output.Data = []byte(got)
+
+				if err := os.WriteFile(file, txtar.Format(archive), 0o644); err != nil {
+					t.Fatal(err)
+				}
+
+				return
+			}
+
+			if got != string(output.Data) {
+				t.Errorf("transcript %s failed:\n+++  got: %s\n--- want: %s",
+					file, got, output.Data)
+			}
+		})
+	}
+}
+
+func transcriptSections(t *testing.T, archive *txtar.Archive) (*txtar.File, *txtar.File) {
+	t.Helper()
+
+	var input, output *txtar.File
+
+	for pos := range archive.Files {
+		switch archive.Files[pos].Name {
+		case "input":
+			input = &archive.Files[pos]
+		case "output":
+			output = &archive.Files[pos]
+		}
+	}
+
+	if input == nil || output == nil {
+		t.Fatal("transcript needs an input and an output section")
+	}
+
+	return input, output
+}
+
+// feed the lines one by one, like the repl does
+func RunTranscript(input string) string {
+	out := &bytes.Buffer{}
+	calc := NewCalcWriter(out)
+
+	for _, line := range strings.Split(strings.TrimSuffix(input, "\n"), "\n") {
+		if err := calc.Eval(line); err != nil {
+			calc.PrintError(err)
+		}
+
+		if err := calc.EndLine(); err != nil {
+			calc.PrintError(err)
+		}
+	}
+
+	return out.String()
+}
@@ -0,0 +1,100 @@
+/*
+Copyright © 2025 Thomas von Dein
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package calc
+
+import (
+	"fmt"
+	"os"
+	"sort"
+)
+
+// Usage statistics are  opt-in and only kept in  memory. They are only
+// written to a local file if the user explicitly saves them, they are
+// never transmitted anywhere.
+
+type UsageCount struct {
+	Name  string
+	Count int
+}
+
+func UsageFile() string {
+	return homeFile(".rpn-usage")
+}
+
+// count an invocation of a function or command, if enabled
+func (c *Calc) CountUsage(name string) {
+	if c.usagestats {
+		c.Usage[name]++
+	}
+}
+
+// return the usage counters, most used first
+func (c *Calc) SortedUsage() []UsageCount {
+	counts := make([]UsageCount, 0, len(c.Usage))
+
+	for name, count := range c.Usage {
+		counts = append(counts, UsageCount{Name: name, Count: count})
+	}
+
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count == counts[j].Count {
+			return counts[i].Name < counts[j].Name
+		}
+
+		return counts[i].Count > counts[j].Count
+	})
+
+	return counts
+}
+
+func (c *Calc) PrintUsage() {
+	if len(c.Usage) == 0 {
+		fmt.Fprintln(c.out, "nothing used yet")
+
+		return
+	}
+
+	fmt.Fprintf(c.out, "%-20s     %s\n", "NAME", "COUNT")
+
+	for _, usage := range c.SortedUsage() {
+		fmt.Fprintf(c.out, "%-20s  -> %d\n", usage.Name, usage.Count)
+	}
+}
+
+// write the usage counters to a local file, one "name count" per line
+func (c *Calc) SaveUsage(filename string) error {
+	if filename == "" {
+		return fmt.Errorf("failed to save usage statistics: %w", ErrNoHome)
+	}
+
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("failed to save usage statistics: %w", err)
+	}
+	defer file.Close()
+
+	for _, usage := range c.SortedUsage() {
+		if _, err := fmt.Fprintf(file, "%s %d\n", usage.Name, usage.Count); err != nil {
+			return fmt.Errorf("failed to save usage statistics: %w", err)
+		}
+	}
+
+	fmt.Fprintf(c.out, "usage statistics saved to %s\n", filename)
+
+	return nil
+}
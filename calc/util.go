@@ -0,0 +1,846 @@
+/*
+Copyright © 2023 Thomas von Dein
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package calc
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeLiteral = regexp.MustCompile(`^([0-9]+):([0-9]+)(?::([0-9]+))?$`)
+	dmsLiteral  = regexp.MustCompile(
+		`^(-?)([0-9]+(?:\.[0-9]+)?)d(?:([0-9]+(?:\.[0-9]+)?)m)?(?:([0-9]+(?:\.[0-9]+)?)s)?$`)
+	dateLiteral     = regexp.MustCompile(`^[0-9]{4}-[0-9]{2}-[0-9]{2}$`)
+	fractionLiteral = regexp.MustCompile(`^(-?[0-9]+)/([0-9]+)$`)
+	romanLiteral    = regexp.MustCompile(`^(?:r:[IVXLCDM]+|[IVXLCDM]{2,})$`)
+	ipLiteral       = regexp.MustCompile(`^[0-9]+\.[0-9]+\.[0-9]+\.[0-9]+$`)
+	multiSign       = regexp.MustCompile(`^[-+]{2,}\.?[0-9]`) // like --5, malformed
+	moneyLiteral    = regexp.MustCompile(`^\(?[-+]?[$€£]?[0-9.,]*[0-9][0-9.,]*[$€£]?\)?$`)
+)
+
+// find an item in a list, generic variant
+func contains[E comparable](s []E, v E) bool {
+	for _, vs := range s {
+		if v == vs {
+			return true
+		}
+	}
+
+	return false
+}
+
+// In containers and CI $HOME might be unset, then there's no place for
+// the config, history, settings etc.
+var ErrNoHome = errors.New("no home directory")
+
+// the home directory of the user, empty if it can't be determined
+func homeDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	return home
+}
+
+// the path of a file in the home directory of the user, empty if
+// there is no home directory
+func homeFile(elements ...string) string {
+	home := homeDir()
+	if home == "" {
+		return ""
+	}
+
+	return filepath.Join(append([]string{home}, elements...)...)
+}
+
+// expand a leading ~ to the home directory of the user
+func expandHome(path string) string {
+	if home := homeDir(); home != "" && (path == "~" || strings.HasPrefix(path, "~/")) {
+		return home + path[1:]
+	}
+
+	return path
+}
+
+// look if a key in a map exists, generic variant
+func exists[K comparable, V any](m map[K]V, v K) bool {
+	if _, ok := m[v]; ok {
+		return true
+	}
+
+	return false
+}
+
+// an alias refers to the same function or command as its canonical
+// name, so that both behave exactly the same
+func addAliases[V any](table map[string]V, aliases map[string]string) {
+	for alias, canonical := range aliases {
+		if item, ok := table[canonical]; ok {
+			table[alias] = item
+		}
+	}
+}
+
+func const2num(name string) float64 {
+	switch name {
+	case "E":
+		return math.E
+	case "Pi":
+		return math.Pi
+	case "Phi":
+		return math.Phi
+	case "Sqrt2":
+		return math.Sqrt2
+	case "SqrtE":
+		return math.SqrtE
+	case "SqrtPi":
+		return math.SqrtPi
+	case "SqrtPhi":
+		return math.SqrtPhi
+	case "Ln2":
+		return math.Ln2
+	case "Log2E":
+		return math.Log2E
+	case "Ln10":
+		return math.Ln10
+	case "Log10E":
+		return math.Log10E
+	default:
+		return 0
+	}
+}
+
+func list2str(list Numbers) string {
+	return strings.Trim(strings.Join(strings.Fields(fmt.Sprint(list)), " "), "[]")
+}
+
+// same as list2str(), but long lists are abbreviated to the first and
+// last n items plus the number of items in between
+func sample2str(list Numbers, n int) string {
+	if len(list) <= 2*n+1 {
+		return list2str(list)
+	}
+
+	return fmt.Sprintf("%s ... (%d more) ... %s",
+		list2str(list[:n]), len(list)-2*n, list2str(list[len(list)-n:]))
+}
+
+func Error(m string) error {
+	return fmt.Errorf("Error: %s", m)
+}
+
+// remove digit separators (_ or ,) from a numeric literal. They are
+// only allowed between two (hex) digits, so 1__000 or 1_ are errors.
+// In decimal numbers the comma separates thousands, so it has to be
+// followed by exactly three digits, otherwise 5,5 would become 55.
+func stripSeparators(item string) (string, error) {
+	if !strings.ContainsAny(item, "_,") {
+		return item, nil
+	}
+
+	decimal := !strings.ContainsAny(item, "xX")
+
+	for pos := 0; pos < len(item); pos++ {
+		if item[pos] != '_' && item[pos] != ',' {
+			continue
+		}
+
+		if pos == 0 || pos == len(item)-1 ||
+			!isHexDigit(item[pos-1]) || !isHexDigit(item[pos+1]) {
+			return item, errors.New("misplaced digit separator")
+		}
+
+		if item[pos] == ',' && decimal && !isThousands(item[pos+1:]) {
+			return item, errors.New("misplaced thousands separator")
+		}
+	}
+
+	return strings.NewReplacer("_", "", ",", "").Replace(item), nil
+}
+
+// expand bracketed groups like [1, 2, 3] into single items, separators
+// contains the characters separating the items of a group besides
+// whitespace.
+func expandGroups(line string, separators string) (string, error) {
+	if !strings.ContainsAny(line, "[]") {
+		return line, nil
+	}
+
+	expanded := strings.Builder{}
+	ingroup := false
+
+	for _, char := range line {
+		switch {
+		case char == '[':
+			if ingroup {
+				return line, errors.New("nested brackets are not supported")
+			}
+
+			ingroup = true
+
+			expanded.WriteRune(' ')
+		case char == ']':
+			if !ingroup {
+				return line, errors.New("closing bracket without opening bracket")
+			}
+
+			ingroup = false
+
+			expanded.WriteRune(' ')
+		case ingroup && strings.ContainsRune(separators, char):
+			expanded.WriteRune(' ')
+		default:
+			expanded.WriteRune(char)
+		}
+	}
+
+	if ingroup {
+		return line, errors.New("unterminated bracket")
+	}
+
+	return expanded.String(), nil
+}
+
+// remove a leading or trailing currency symbol from pasted amounts like
+// $1,234.56, -$5 or 1.234,56€
+func stripCurrency(item string) string {
+	sign := ""
+	amount := item
+
+	if strings.HasPrefix(amount, "-") || strings.HasPrefix(amount, "+") {
+		sign = amount[:1]
+		amount = amount[1:]
+	}
+
+	for _, symbol := range Currencies {
+		if strings.HasPrefix(amount, symbol) {
+			return sign + strings.TrimPrefix(amount, symbol)
+		}
+
+		if strings.HasSuffix(amount, symbol) {
+			return sign + strings.TrimSuffix(amount, symbol)
+		}
+	}
+
+	return item
+}
+
+// parse an amount pasted from an  invoice, no matter if it's written
+// like 1.234,56 € or $1,234.56: the last separator is the decimal point,
+// unless it occurs more than once. Negative amounts may be enclosed in
+// parentheses,  like  (1,234.56).  A  single separator  followed  by
+// exactly three digits could be either, so 1,234 is rejected.
+func parseMoney(item string) (float64, error) {
+	amount := item
+	negative := false
+
+	if strings.HasPrefix(amount, "(") != strings.HasSuffix(amount, ")") {
+		return 0, errors.New("unbalanced parentheses")
+	}
+
+	if strings.HasPrefix(amount, "(") {
+		negative = true
+		amount = amount[1 : len(amount)-1]
+	}
+
+	amount = stripCurrency(amount)
+
+	if strings.HasPrefix(amount, "-") || strings.HasPrefix(amount, "+") {
+		if negative {
+			return 0, errors.New("sign inside parentheses")
+		}
+
+		negative = amount[0] == '-'
+		amount = amount[1:]
+	}
+
+	integer, fraction := amount, ""
+	group := ""
+
+	if last := strings.LastIndexAny(amount, ".,"); last >= 0 {
+		separator := amount[last : last+1]
+		before, after := amount[:last], amount[last+1:]
+
+		switch {
+		case strings.Contains(before, separator):
+			// 1.234.567, the separator groups digits
+			group = separator
+		case strings.ContainsAny(before, ".,"):
+			// 1.234,56, the other separator groups digits
+			integer, fraction = before, after
+			group = strings.Trim(".,", separator)
+		case len(after) == 3 && len(before) > 0 && len(before) <= 3 &&
+			strings.Trim(before, "0") != "":
+			return 0, errors.New("ambiguous separator, could be a decimal point or group digits")
+		default:
+			integer, fraction = before, after
+		}
+	}
+
+	digits := integer
+
+	if group != "" {
+		groups := strings.Split(integer, group)
+
+		for pos, digitgroup := range groups {
+			if (pos == 0 && (len(digitgroup) == 0 || len(digitgroup) > 3)) ||
+				(pos > 0 && len(digitgroup) != 3) {
+				return 0, errors.New("misplaced thousands separator")
+			}
+		}
+
+		digits = strings.Join(groups, "")
+	}
+
+	if strings.Trim(digits+fraction, "0123456789") != "" || digits+fraction == "" {
+		return 0, errors.New("not an amount")
+	}
+
+	if digits == "" {
+		digits = "0"
+	}
+
+	if fraction != "" {
+		digits += "." + fraction
+	}
+
+	value, err := strconv.ParseFloat(digits, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	if negative {
+		value = -value
+	}
+
+	return value, nil
+}
+
+// in german notation the  roles of . and , are swapped: 1.234,56. We
+// swap them back, so that the comma becomes a digit separator again.
+func delocalize(item string, locale string) string {
+	if locale != LocaleDE {
+		return item
+	}
+
+	return strings.Map(func(char rune) rune {
+		switch char {
+		case '.':
+			return ','
+		case ',':
+			return '.'
+		}
+
+		return char
+	}, item)
+}
+
+// render value in  engineering notation, where the exponent is always
+// a multiple of 3, e.g. 12.5e3. The precision is the number of digits
+// after the decimal point in scientific notation, so that the number of
+// significant digits is the same in both.
+func formatEngineering(value float64, precision int) string {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+
+	mantissa, exp, _ := strings.Cut(strconv.FormatFloat(value, 'e', precision, 64), "e")
+	exponent, _ := strconv.Atoi(exp) // always valid, produced by strconv
+
+	sign := ""
+	if strings.HasPrefix(mantissa, "-") {
+		sign, mantissa = "-", mantissa[1:]
+	}
+
+	// move the decimal point to the right until the exponent fits
+	shift := ((exponent % 3) + 3) % 3
+	digits := strings.Replace(mantissa, ".", "", 1)
+
+	for len(digits) < shift+1 {
+		digits += "0"
+	}
+
+	number := digits[:shift+1]
+	if fraction := digits[shift+1:]; fraction != "" {
+		number += "." + fraction
+	}
+
+	return fmt.Sprintf("%s%se%d", sign, number, exponent-shift)
+}
+
+// insert separator between every  three digits of the integer part of
+// a formatted number like -1234567.89 and replace its decimal point. In
+// sci and eng notation only the mantissa is affected.
+func groupDigits(number, separator, decimal string) string {
+	mantissa, exponent, hasexponent := strings.Cut(number, "e")
+	if hasexponent {
+		return groupDigits(mantissa, separator, decimal) + "e" + exponent
+	}
+
+	integer, fraction, hasfraction := strings.Cut(number, ".")
+	sign := ""
+
+	if strings.HasPrefix(integer, "-") {
+		sign, integer = "-", integer[1:]
+	}
+
+	var grouped strings.Builder
+
+	for pos, digit := range integer {
+		if pos > 0 && (len(integer)-pos)%3 == 0 {
+			grouped.WriteString(separator)
+		}
+
+		grouped.WriteRune(digit)
+	}
+
+	if hasfraction {
+		return sign + grouped.String() + decimal + fraction
+	}
+
+	return sign + grouped.String()
+}
+
+// true if rest starts with a group of exactly three digits
+func isThousands(rest string) bool {
+	if len(rest) < 3 {
+		return false
+	}
+
+	for pos := range 3 {
+		if rest[pos] < '0' || rest[pos] > '9' {
+			return false
+		}
+	}
+
+	return len(rest) == 3 || rest[3] < '0' || rest[3] > '9'
+}
+
+func isHexDigit(char byte) bool {
+	return (char >= '0' && char <= '9') ||
+		(char >= 'a' && char <= 'f') ||
+		(char >= 'A' && char <= 'F')
+}
+
+// parse a time literal like 7:30 or 7:30:15 into fractional hours
+func parseTime(item string) (float64, error) {
+	parts := timeLiteral.FindStringSubmatch(item)
+	if parts == nil {
+		return 0, fmt.Errorf("invalid time literal %s", item)
+	}
+
+	// the regexp ensures we only have digits here
+	hours, _ := strconv.Atoi(parts[1])
+	minutes, _ := strconv.Atoi(parts[2])
+	seconds := 0
+
+	if parts[3] != "" {
+		seconds, _ = strconv.Atoi(parts[3])
+	}
+
+	if minutes > 59 || seconds > 59 {
+		return 0, fmt.Errorf("invalid time literal %s", item)
+	}
+
+	return float64(hours) + float64(minutes)/60 + float64(seconds)/3600, nil
+}
+
+// render fractional hours as h:mm:ss, rounded to full seconds
+func formatTime(hours float64) string {
+	sign := ""
+	if hours < 0 {
+		sign = "-"
+	}
+
+	seconds := int64(math.Round(math.Abs(hours) * 3600))
+
+	return fmt.Sprintf("%s%d:%02d:%02d", sign,
+		seconds/3600, (seconds%3600)/60, seconds%60)
+}
+
+// render seconds as a go duration string like 1h30m0s
+func formatDuration(seconds float64) (string, error) {
+	if math.Abs(seconds) > float64(math.MaxInt64)/float64(time.Second) {
+		return "", errors.New("value out of duration range")
+	}
+
+	return time.Duration(seconds * float64(time.Second)).String(), nil
+}
+
+// round to cents, half away from zero. We round to a couple of more
+// digits first, otherwise 2.675 (which is actually 2.67499999...) would
+// be rounded down.
+func roundMoney(value float64) float64 {
+	return math.Round(math.Round(value*1e8)/1e6) / 100
+}
+
+// parse a fraction like 3/4 into its quotient
+func parseFraction(item string) (float64, error) {
+	parts := fractionLiteral.FindStringSubmatch(item)
+	if parts == nil {
+		return 0, fmt.Errorf("invalid fraction %s", item)
+	}
+
+	numerator, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid fraction %s", item)
+	}
+
+	denominator, err := strconv.ParseFloat(parts[2], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid fraction %s", item)
+	}
+
+	if denominator == 0 {
+		return 0, errors.New("division by null")
+	}
+
+	return numerator / denominator, nil
+}
+
+// parse an ISO date like 2024-06-01 into the unix timestamp of midnight
+// UTC of that day
+func parseDate(item string) (float64, error) {
+	date, err := time.Parse(time.DateOnly, item)
+	if err != nil {
+		return 0, fmt.Errorf("invalid date %s", item)
+	}
+
+	return float64(date.Unix()), nil
+}
+
+// render a unix timestamp as RFC3339 date in the given timezone
+func formatDate(timestamp float64, location *time.Location) string {
+	seconds, fraction := math.Modf(timestamp)
+
+	return time.Unix(int64(seconds), int64(fraction*1e9)).In(location).Format(time.RFC3339)
+}
+
+var romanNumerals = []struct {
+	value   int
+	numeral string
+}{
+	{1000, "M"}, {900, "CM"}, {500, "D"}, {400, "CD"},
+	{100, "C"}, {90, "XC"}, {50, "L"}, {40, "XL"},
+	{10, "X"}, {9, "IX"}, {5, "V"}, {4, "IV"}, {1, "I"},
+}
+
+// parse a roman numeral like MCMLXXXIV or r:X. Only the canonical form
+// is accepted, so IIII or IC are errors.
+func parseRoman(item string) (float64, error) {
+	numeral := strings.TrimPrefix(item, "r:")
+	rest := numeral
+	value := 0
+
+	for _, roman := range romanNumerals {
+		for strings.HasPrefix(rest, roman.numeral) {
+			value += roman.value
+			rest = rest[len(roman.numeral):]
+		}
+	}
+
+	if rest != "" || value == 0 {
+		return 0, fmt.Errorf("invalid roman numeral %s", item)
+	}
+
+	if canonical, _ := formatRoman(float64(value)); canonical != numeral {
+		return 0, fmt.Errorf("invalid roman numeral %s", item)
+	}
+
+	return float64(value), nil
+}
+
+// render a number as roman numeral, only integers from 1 to 3999 work
+func formatRoman(value float64) (string, error) {
+	if value < 1 || value >= 4000 || value != math.Trunc(value) {
+		return "", fmt.Errorf("%g can't be represented as roman numeral", value)
+	}
+
+	number := int(value)
+	numeral := strings.Builder{}
+
+	for _, roman := range romanNumerals {
+		for number >= roman.value {
+			numeral.WriteString(roman.numeral)
+			number -= roman.value
+		}
+	}
+
+	return numeral.String(), nil
+}
+
+// parse an IPv4 address like 192.168.1.1 into its integer value
+func parseIP(item string) (float64, error) {
+	addr, err := netip.ParseAddr(item)
+	if err != nil || !addr.Is4() {
+		return 0, fmt.Errorf("invalid ip address %s", item)
+	}
+
+	octets := addr.As4()
+
+	return float64(binary.BigEndian.Uint32(octets[:])), nil
+}
+
+// render an integer as IPv4 address, it must fit into 32 bits
+func formatIP(value float64) (string, error) {
+	if value < 0 || value > math.MaxUint32 || value != math.Trunc(value) {
+		return "", fmt.Errorf("%g is not a valid ip address", value)
+	}
+
+	var octets [4]byte
+
+	binary.BigEndian.PutUint32(octets[:], uint32(value))
+
+	return netip.AddrFrom4(octets).String(), nil
+}
+
+// parse a hex literal like 0xff or -0x10. Values which can't be
+// represented exactly as float64 are rejected, that is everything above
+// 2^53 which is not a multiple of the according power of 2.
+func parseHex(item string) (float64, error) {
+	negative := strings.HasPrefix(item, "-")
+	digits := strings.TrimPrefix(item, "-")
+
+	if !strings.HasPrefix(strings.ToLower(digits), "0x") {
+		return 0, fmt.Errorf("malformed hex literal %s", item)
+	}
+
+	num, err := strconv.ParseUint(digits[2:], 16, 64)
+	if err != nil {
+		if errors.Is(err, strconv.ErrRange) {
+			return 0, errors.New("hex literal out of range")
+		}
+
+		return 0, fmt.Errorf("malformed hex literal %s", item)
+	}
+
+	value := float64(num)
+	if value >= math.Exp2(64) || uint64(value) != num {
+		return 0, errors.New("hex literal out of range")
+	}
+
+	if negative {
+		value = -value
+	}
+
+	return value, nil
+}
+
+// render the integer part of a number in hex, so that it can be read
+// back by parseHex(). If width is  given, the number is zero padded to
+// width hex digits and negative numbers are printed in two's complement.
+func formatHex(value float64, width int, upper bool) (string, error) {
+	digits := "%x"
+	if upper {
+		digits = "%X"
+	}
+
+	if width > 0 {
+		bits := 4 * width
+		value = math.Trunc(value)
+
+		if value >= math.Exp2(float64(bits)) || value < -math.Exp2(float64(bits-1)) {
+			return "", fmt.Errorf("value doesn't fit into %d hex digits", width)
+		}
+
+		number := uint64(value)
+		if value < 0 {
+			number = uint64(int64(value))
+		}
+
+		if bits < 64 {
+			number &= 1<<bits - 1
+		}
+
+		return fmt.Sprintf("0x%0*"+digits[1:], width, number), nil
+	}
+
+	sign := ""
+
+	if value < 0 {
+		sign = "-"
+		value = -value
+	}
+
+	if value >= math.Exp2(64) {
+		return "", errors.New("value out of hex range")
+	}
+
+	return fmt.Sprintf("%s0x"+digits, sign, uint64(value)), nil
+}
+
+var (
+	iecUnits = []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB", "EiB", "ZiB", "YiB"}
+	siUnits  = []string{"B", "kB", "MB", "GB", "TB", "PB", "EB", "ZB", "YB"}
+)
+
+// render a number of bytes with the largest unit which keeps the value
+// above 1, e.g. 1.46 GiB. Units are powers of 1024 (IEC) or 1000 (SI).
+func formatBytes(value float64, precision int, si bool) string {
+	base, units := 1024.0, iecUnits
+	if si {
+		base, units = 1000.0, siUnits
+	}
+
+	unit := 0
+	for math.Abs(value) >= base && unit < len(units)-1 {
+		value /= base
+		unit++
+	}
+
+	if unit == 0 {
+		// there are no fractional bytes
+		return fmt.Sprintf("%.0f %s", value, units[unit])
+	}
+
+	// rounding might lead to 1024.00 KiB, which is 1.00 MiB
+	number := strconv.FormatFloat(value, 'f', precision, 64)
+	if rounded, _ := strconv.ParseFloat(number, 64); math.Abs(rounded) >= base && unit < len(units)-1 {
+		value /= base
+		unit++
+		number = strconv.FormatFloat(value, 'f', precision, 64)
+	}
+
+	return number + " " + units[unit]
+}
+
+// find the fraction closest to value whose denominator doesn't exceed
+// maxden using continued fractions, see Python's limit_denominator()
+func approximateFraction(value float64, maxden int64) (int64, int64, error) {
+	if math.IsNaN(value) || math.Abs(value) >= 1<<53 {
+		return 0, 0, fmt.Errorf("%g can't be represented as fraction", value)
+	}
+
+	sign := int64(1)
+	if value < 0 {
+		sign, value = -1, -value
+	}
+
+	// the last two convergents
+	p0, q0, p1, q1 := int64(0), int64(1), int64(1), int64(0)
+	rest := value
+
+	for {
+		whole := math.Floor(rest)
+		if q0+int64(whole)*q1 > maxden || whole > 1<<53 {
+			break
+		}
+
+		p0, q0, p1, q1 = p1, q1, p0+int64(whole)*p1, q0+int64(whole)*q1
+
+		if rest == whole {
+			break
+		}
+
+		rest = 1 / (rest - whole)
+	}
+
+	// the best semiconvergent might be closer than the last convergent
+	steps := (maxden - q0) / q1
+	semip, semiq := p0+steps*p1, q0+steps*q1
+
+	if math.Abs(value-float64(semip)/float64(semiq)) < math.Abs(value-float64(p1)/float64(q1)) {
+		return sign * semip, semiq, nil
+	}
+
+	return sign * p1, q1, nil
+}
+
+// render an integer in the given base using the digits 0-9 and a-z
+func formatBase(value float64, base int) (string, error) {
+	if base < 2 || base > 36 {
+		return "", fmt.Errorf("invalid base %d, use 2-36", base)
+	}
+
+	if value != math.Trunc(value) {
+		return "", fmt.Errorf("%g is not an integer", value)
+	}
+
+	if value < math.MinInt64 || value >= math.MaxInt64 {
+		return "", fmt.Errorf("%g is out of range", value)
+	}
+
+	return strconv.FormatInt(int64(value), base), nil
+}
+
+// parse degrees, minutes and seconds like 52d31m12s into decimal degrees
+func parseDMS(item string) (float64, error) {
+	parts := dmsLiteral.FindStringSubmatch(item)
+	if parts == nil {
+		return 0, fmt.Errorf("invalid degrees literal %s", item)
+	}
+
+	// the regexp ensures we only have numbers here
+	degrees, _ := strconv.ParseFloat(parts[2], 64)
+	minutes, seconds := 0.0, 0.0
+
+	if parts[3] != "" {
+		minutes, _ = strconv.ParseFloat(parts[3], 64)
+	}
+
+	if parts[4] != "" {
+		seconds, _ = strconv.ParseFloat(parts[4], 64)
+	}
+
+	if minutes >= 60 || seconds >= 60 {
+		return 0, fmt.Errorf("invalid degrees literal %s", item)
+	}
+
+	degrees += minutes/60 + seconds/3600
+
+	if parts[1] == "-" {
+		degrees = -degrees
+	}
+
+	return degrees, nil
+}
+
+// render decimal degrees as 52d31m12s, seconds are rounded to the
+// given precision
+func formatDMS(degrees float64, precision int) string {
+	sign := ""
+	if degrees < 0 {
+		sign = "-"
+		degrees = -degrees
+	}
+
+	scale := math.Pow(10, float64(precision))
+	total := math.Round(degrees*3600*scale) / scale
+
+	whole := math.Floor(total / 3600)
+	minutes := math.Floor((total - whole*3600) / 60)
+	seconds := total - whole*3600 - minutes*60
+
+	second := strconv.FormatFloat(seconds, 'f', precision, 64)
+	if strings.Contains(second, ".") {
+		second = strings.TrimRight(strings.TrimRight(second, "0"), ".")
+	}
+
+	return fmt.Sprintf("%s%.0fd%.0fm%ss", sign, whole, minutes, second)
+}
@@ -0,0 +1,822 @@
+/*
+Copyright © 2023 Thomas von Dein
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package calc
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestContains(t *testing.T) {
+	list := []string{"a", "b", "c"}
+
+	t.Run("contains", func(t *testing.T) {
+		if !contains(list, "a") {
+			t.Errorf("a in [a,b,c] not found")
+		}
+	})
+}
+
+func TestSample2str(t *testing.T) {
+	var tests = []struct {
+		list Numbers
+		exp  string
+	}{
+		{list: Numbers{1, 2, 3}, exp: "1 2 3"},
+		{list: Numbers{1, 2, 3, 4, 5, 6, 7}, exp: "1 2 3 4 5 6 7"},
+		{list: Numbers{1, 2, 3, 4, 5, 6, 7, 8, 9}, exp: "1 2 3 ... (3 more) ... 7 8 9"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.exp, func(t *testing.T) {
+			got := sample2str(test.list, 3)
+			if got != test.exp {
+				t.Errorf("sample2str failed:\n+++  got: %s\n--- want: %s", got, test.exp)
+			}
+		})
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	var tests = []struct {
+		item string
+		exp  float64
+		err  bool
+	}{
+		{item: "7:30", exp: 7.5},
+		{item: "0:45", exp: 0.75},
+		{item: "7:30:36", exp: 7.51},
+		{item: "7:99", err: true},
+		{item: "7:30:60", err: true},
+		{item: "7:-30", err: true},
+		{item: "7:", err: true},
+		{item: "7:30:15:1", err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.item, func(t *testing.T) {
+			got, err := parseTime(test.item)
+
+			if test.err {
+				if err == nil {
+					t.Errorf("%s accepted, expected error", test.item)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Error(err.Error())
+			}
+
+			if got != test.exp {
+				t.Errorf("parse time failed:\n+++  got: %f\n--- want: %f",
+					got, test.exp)
+			}
+		})
+	}
+}
+
+func TestFormatTime(t *testing.T) {
+	var tests = []struct {
+		hours float64
+		exp   string
+	}{
+		{hours: 7.5, exp: "7:30:00"},
+		{hours: 4.2583333333, exp: "4:15:30"},
+		{hours: -1.25, exp: "-1:15:00"},
+		{hours: 0, exp: "0:00:00"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.exp, func(t *testing.T) {
+			if got := formatTime(test.hours); got != test.exp {
+				t.Errorf("format time failed:\n+++  got: %s\n--- want: %s",
+					got, test.exp)
+			}
+		})
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	var tests = []struct {
+		seconds float64
+		exp     string
+		err     bool
+	}{
+		{seconds: 5400, exp: "1h30m0s"},
+		{seconds: 45.5, exp: "45.5s"},
+		{seconds: -60, exp: "-1m0s"},
+		{seconds: 1e20, err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.exp, func(t *testing.T) {
+			got, err := formatDuration(test.seconds)
+
+			if test.err {
+				if err == nil {
+					t.Errorf("%f accepted, expected error", test.seconds)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Error(err.Error())
+			}
+
+			if got != test.exp {
+				t.Errorf("format duration failed:\n+++  got: %s\n--- want: %s",
+					got, test.exp)
+			}
+		})
+	}
+}
+
+func TestParseHex(t *testing.T) {
+	var tests = []struct {
+		item string
+		exp  float64
+		err  bool
+	}{
+		{item: "0xff", exp: 255},
+		{item: "0XFF", exp: 255},
+		{item: "-0x10", exp: -16},
+		{item: "0x20000000000000", exp: 1 << 53},
+		{item: "0x20000000000001", err: true},      // 2^53+1
+		{item: "0x7fffffffffffffff", err: true},    // 2^63-1
+		{item: "0x8000000000000000", exp: 1 << 63}, // 2^63
+		{item: "0xffffffffffffffff", err: true},    // 2^64-1
+		{item: "0x10000000000000000", err: true},   // 2^64
+		{item: "0x10zz", err: true},
+		{item: "0x", err: true},
+		{item: "ff", err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.item, func(t *testing.T) {
+			got, err := parseHex(test.item)
+
+			if test.err {
+				if err == nil {
+					t.Errorf("%s accepted, expected error", test.item)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Error(err.Error())
+			}
+
+			if got != test.exp {
+				t.Errorf("parse hex failed:\n+++  got: %f\n--- want: %f",
+					got, test.exp)
+			}
+
+			// must survive a round trip
+			hex, err := formatHex(got, 0, false)
+			if err != nil {
+				t.Error(err.Error())
+			}
+
+			back, err := parseHex(hex)
+			if err != nil || back != got {
+				t.Errorf("hex round trip failed:\n+++  got: %s\n--- want: %s",
+					hex, test.item)
+			}
+		})
+	}
+}
+
+func TestFormatHex(t *testing.T) {
+	var tests = []struct {
+		value float64
+		width int
+		upper bool
+		exp   string
+		err   bool
+	}{
+		{value: 48879, exp: "0xbeef"},
+		{value: 48879, upper: true, exp: "0xBEEF"},
+		{value: 48879, width: 8, exp: "0x0000beef"},
+		{value: 48879, width: 8, upper: true, exp: "0x0000BEEF"},
+		{value: 255.9, width: 2, exp: "0xff"},
+		{value: -16, exp: "-0x10"},
+		{value: -1, width: 4, exp: "0xffff"},
+		{value: -2, width: 2, upper: true, exp: "0xFE"},
+		{value: -128, width: 2, exp: "0x80"},
+		{value: -1, width: 16, exp: "0xffffffffffffffff"},
+		{value: 1 << 63, width: 16, exp: "0x8000000000000000"},
+		{value: 256, width: 2, err: true},
+		{value: -129, width: 2, err: true},
+		{value: 1 << 64, err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%.0f-%d", test.value, test.width), func(t *testing.T) {
+			got, err := formatHex(test.value, test.width, test.upper)
+
+			if test.err {
+				if err == nil {
+					t.Errorf("%f accepted, expected error", test.value)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != test.exp {
+				t.Errorf("format hex failed:\n+++  got: %s\n--- want: %s", got, test.exp)
+			}
+		})
+	}
+}
+
+func TestFormatBase(t *testing.T) {
+	var tests = []struct {
+		value float64
+		base  int
+		exp   string
+		err   bool
+	}{
+		{value: 10, base: 2, exp: "1010"},
+		{value: -10, base: 2, exp: "-1010"},
+		{value: 35, base: 36, exp: "z"},
+		{value: 1295, base: 36, exp: "zz"},
+		{value: 49, base: 7, exp: "100"},
+		{value: 1.5, base: 2, err: true},
+		{value: 10, base: 1, err: true},
+		{value: 10, base: 37, err: true},
+		{value: 1e19, base: 10, err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%g-%d", test.value, test.base), func(t *testing.T) {
+			got, err := formatBase(test.value, test.base)
+
+			if test.err {
+				if err == nil {
+					t.Errorf("%g in base %d accepted, expected error", test.value, test.base)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != test.exp {
+				t.Errorf("format base failed:\n+++  got: %s\n--- want: %s", got, test.exp)
+			}
+		})
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	var tests = []struct {
+		value float64
+		si    bool
+		exp   string
+	}{
+		{value: 0, exp: "0 B"},
+		{value: 1023, exp: "1023 B"},
+		{value: 999, si: true, exp: "999 B"},
+		{value: 1024, exp: "1.00 KiB"},
+		{value: 1500, si: true, exp: "1.50 kB"},
+		{value: 1.5e9, exp: "1.40 GiB"},
+		{value: 1.5e9, si: true, exp: "1.50 GB"},
+		{value: 1048575, exp: "1.00 MiB"},
+		{value: 999999, si: true, exp: "1.00 MB"},
+		{value: -2048, exp: "-2.00 KiB"},
+		{value: 2.5e18, si: true, exp: "2.50 EB"},
+		{value: 1e30, si: true, exp: "1000000.00 YB"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.exp, func(t *testing.T) {
+			if got := formatBytes(test.value, 2, test.si); got != test.exp {
+				t.Errorf("format bytes failed:\n+++  got: %s\n--- want: %s", got, test.exp)
+			}
+		})
+	}
+}
+
+func TestApproximateFraction(t *testing.T) {
+	var tests = []struct {
+		value  float64
+		maxden int64
+		num    int64
+		den    int64
+		err    bool
+	}{
+		{value: 0.5, maxden: 10000, num: 1, den: 2},
+		{value: -0.75, maxden: 10000, num: -3, den: 4},
+		{value: 0.333333, maxden: 10000, num: 1, den: 3},
+		{value: math.Pi, maxden: 10000, num: 355, den: 113},
+		{value: math.Pi, maxden: 100, num: 311, den: 99},
+		{value: math.Pi, maxden: 1, num: 3, den: 1},
+		{value: 3, maxden: 10000, num: 3, den: 1},
+		{value: -42, maxden: 10000, num: -42, den: 1},
+		{value: 0, maxden: 10000, num: 0, den: 1},
+		{value: 1e-9, maxden: 10000, num: 0, den: 1},
+		{value: 1e300, maxden: 10000, err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%g-%d", test.value, test.maxden), func(t *testing.T) {
+			num, den, err := approximateFraction(test.value, test.maxden)
+
+			if test.err {
+				if err == nil {
+					t.Errorf("%g accepted, expected error", test.value)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if num != test.num || den != test.den {
+				t.Errorf("approximate fraction failed:\n+++  got: %d/%d\n--- want: %d/%d",
+					num, den, test.num, test.den)
+			}
+		})
+	}
+}
+
+func TestParseDMS(t *testing.T) {
+	var tests = []struct {
+		item string
+		exp  float64
+		err  bool
+	}{
+		{item: "52d31m12s", exp: 52.52},
+		{item: "52d30m", exp: 52.5},
+		{item: "13d", exp: 13},
+		{item: "-13d15m", exp: -13.25},
+		{item: "0d0m36s", exp: 0.01},
+		{item: "52d60m", err: true},
+		{item: "52d31m60s", err: true},
+		{item: "52d31m12", err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.item, func(t *testing.T) {
+			got, err := parseDMS(test.item)
+
+			if test.err {
+				if err == nil {
+					t.Errorf("%s accepted, expected error", test.item)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Error(err.Error())
+			}
+
+			if math.Abs(got-test.exp) > 1e-12 {
+				t.Errorf("parse dms failed:\n+++  got: %f\n--- want: %f",
+					got, test.exp)
+			}
+		})
+	}
+}
+
+func TestFormatDMS(t *testing.T) {
+	var tests = []struct {
+		degrees float64
+		exp     string
+	}{
+		{degrees: 52.52, exp: "52d31m12s"},
+		{degrees: -13.25, exp: "-13d15m0s"},
+		{degrees: 1.999999999, exp: "2d0m0s"},
+		{degrees: 10.123456, exp: "10d7m24.44s"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.exp, func(t *testing.T) {
+			if got := formatDMS(test.degrees, 2); got != test.exp {
+				t.Errorf("format dms failed:\n+++  got: %s\n--- want: %s",
+					got, test.exp)
+			}
+		})
+	}
+}
+
+func TestRoundMoney(t *testing.T) {
+	var tests = []struct {
+		value float64
+		exp   float64
+	}{
+		{value: 2.675, exp: 2.68},
+		{value: 1.005, exp: 1.01},
+		{value: -1.005, exp: -1.01},
+		{value: 1.2138, exp: 1.21},
+		{value: 0.125, exp: 0.13},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%f", test.value), func(t *testing.T) {
+			if got := roundMoney(test.value); got != test.exp {
+				t.Errorf("round money failed:\n+++  got: %f\n--- want: %f",
+					got, test.exp)
+			}
+		})
+	}
+}
+
+func TestGroupDigits(t *testing.T) {
+	var tests = []struct {
+		number    string
+		separator string
+		decimal   string
+		exp       string
+	}{
+		{number: "1234567.89", separator: ",", decimal: ".", exp: "1,234,567.89"},
+		{number: "1234567.89", separator: ".", decimal: ",", exp: "1.234.567,89"},
+		{number: "-123456", separator: ",", decimal: ".", exp: "-123,456"},
+		{number: "-999.5", separator: ",", decimal: ".", exp: "-999.5"},
+		{number: "1000", separator: "", decimal: ",", exp: "1000"},
+		{number: "0.001", separator: ",", decimal: ",", exp: "0,001"},
+		{number: "123e-6", separator: ",", decimal: ".", exp: "123e-6"},
+		{number: "-1.25e+04", separator: ".", decimal: ",", exp: "-1,25e+04"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.exp, func(t *testing.T) {
+			got := groupDigits(test.number, test.separator, test.decimal)
+			if got != test.exp {
+				t.Errorf("group digits failed:\n+++  got: %s\n--- want: %s",
+					got, test.exp)
+			}
+		})
+	}
+}
+
+func TestFormatEngineering(t *testing.T) {
+	var tests = []struct {
+		value     float64
+		precision int
+		exp       string
+	}{
+		{value: 12500, precision: 2, exp: "12.5e3"},
+		{value: 0.000123, precision: 2, exp: "123e-6"},
+		{value: 1.23e9, precision: 2, exp: "1.23e9"},
+		{value: -0.5, precision: 3, exp: "-500.0e-3"},
+		{value: 999999, precision: 2, exp: "1.00e6"},
+		{value: 100, precision: 0, exp: "100e0"},
+		{value: 0, precision: 1, exp: "0.0e0"},
+		{value: 4200, precision: -1, exp: "4.2e3"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.exp, func(t *testing.T) {
+			got := formatEngineering(test.value, test.precision)
+			if got != test.exp {
+				t.Errorf("format engineering failed:\n+++  got: %s\n--- want: %s",
+					got, test.exp)
+			}
+		})
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	var tests = []struct {
+		item string
+		exp  float64
+		err  bool
+	}{
+		{item: "2024-06-01", exp: 1717200000},
+		{item: "1970-01-01", exp: 0},
+		{item: "2024-02-30", err: true},
+		{item: "2024-13-01", err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.item, func(t *testing.T) {
+			got, err := parseDate(test.item)
+
+			if test.err {
+				if err == nil {
+					t.Errorf("%s accepted, expected error", test.item)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Error(err.Error())
+
+				return
+			}
+
+			if got != test.exp {
+				t.Errorf("parse date failed:\n+++  got: %f\n--- want: %f",
+					got, test.exp)
+			}
+		})
+	}
+}
+
+func TestFormatDate(t *testing.T) {
+	berlin := time.FixedZone("CEST", 2*3600)
+
+	var tests = []struct {
+		timestamp float64
+		location  *time.Location
+		exp       string
+	}{
+		{timestamp: 1717200000, location: time.UTC, exp: "2024-06-01T00:00:00Z"},
+		{timestamp: 1717200000, location: berlin, exp: "2024-06-01T02:00:00+02:00"},
+		{timestamp: -86400, location: time.UTC, exp: "1969-12-31T00:00:00Z"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.exp, func(t *testing.T) {
+			if got := formatDate(test.timestamp, test.location); got != test.exp {
+				t.Errorf("format date failed:\n+++  got: %s\n--- want: %s",
+					got, test.exp)
+			}
+		})
+	}
+}
+
+func TestParseRoman(t *testing.T) {
+	var tests = []struct {
+		item string
+		exp  float64
+		err  bool
+	}{
+		{item: "MCMLXXXIV", exp: 1984},
+		{item: "MMMCMXCIX", exp: 3999},
+		{item: "XL", exp: 40},
+		{item: "r:V", exp: 5},
+		{item: "IIII", err: true},
+		{item: "IC", err: true},
+		{item: "VV", err: true},
+		{item: "MMMM", err: true},
+		{item: "r:", err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.item, func(t *testing.T) {
+			got, err := parseRoman(test.item)
+
+			if test.err {
+				if err == nil {
+					t.Errorf("%s accepted, expected error", test.item)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Error(err.Error())
+
+				return
+			}
+
+			if got != test.exp {
+				t.Errorf("parse roman failed:\n+++  got: %f\n--- want: %f",
+					got, test.exp)
+			}
+		})
+	}
+}
+
+func TestFormatRoman(t *testing.T) {
+	var tests = []struct {
+		value float64
+		exp   string
+		err   bool
+	}{
+		{value: 1984, exp: "MCMLXXXIV"},
+		{value: 4, exp: "IV"},
+		{value: 3999, exp: "MMMCMXCIX"},
+		{value: 0, err: true},
+		{value: -5, err: true},
+		{value: 4000, err: true},
+		{value: 2.5, err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.exp, func(t *testing.T) {
+			got, err := formatRoman(test.value)
+
+			if test.err {
+				if err == nil {
+					t.Errorf("%f accepted, expected error", test.value)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Error(err.Error())
+
+				return
+			}
+
+			if got != test.exp {
+				t.Errorf("format roman failed:\n+++  got: %s\n--- want: %s",
+					got, test.exp)
+			}
+		})
+	}
+}
+
+func TestParseIP(t *testing.T) {
+	var tests = []struct {
+		item string
+		exp  float64
+		err  bool
+	}{
+		{item: "192.168.1.1", exp: 3232235777},
+		{item: "0.0.0.0", exp: 0},
+		{item: "255.255.255.255", exp: 4294967295},
+		{item: "256.1.1.1", err: true},
+		{item: "192.168.01.1", err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.item, func(t *testing.T) {
+			got, err := parseIP(test.item)
+
+			if test.err {
+				if err == nil {
+					t.Errorf("%s accepted, expected error", test.item)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Error(err.Error())
+
+				return
+			}
+
+			if got != test.exp {
+				t.Errorf("parse ip failed:\n+++  got: %f\n--- want: %f",
+					got, test.exp)
+			}
+		})
+	}
+}
+
+func TestParseMoney(t *testing.T) {
+	var tests = []struct {
+		item string
+		exp  float64
+		err  bool
+	}{
+		// us
+		{item: "$1,234.56", exp: 1234.56},
+		{item: "1,234,567.89", exp: 1234567.89},
+		{item: "1,234,567", exp: 1234567},
+		{item: "12.5", exp: 12.5},
+		{item: "-$5.99", exp: -5.99},
+		// eu
+		{item: "1.234,56€", exp: 1234.56},
+		{item: "1.234,56", exp: 1234.56},
+		{item: "€1.234.567,89", exp: 1234567.89},
+		{item: "1.234.567", exp: 1234567},
+		{item: "12,5", exp: 12.5},
+		{item: "-12,50£", exp: -12.5},
+		// negative amounts in parentheses
+		{item: "(1,234.56)", exp: -1234.56},
+		{item: "($5.00)", exp: -5},
+		{item: "(1.234,56€)", exp: -1234.56},
+		// no grouping possible
+		{item: "0,123", exp: 0.123},
+		{item: ".123", exp: 0.123},
+		{item: "1234,567", exp: 1234.567},
+		{item: "42", exp: 42},
+		// ambiguous or malformed
+		{item: "1,234", err: true},
+		{item: "1.234", err: true},
+		{item: "$999.000", err: true},
+		{item: "12,34,567", err: true},
+		{item: "1,234.567,89", err: true},
+		{item: "1.2345.678", err: true},
+		{item: ",123,456", err: true},
+		{item: "(1,234.56", err: true},
+		{item: "(-5)", err: true},
+		{item: "1..5", err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.item, func(t *testing.T) {
+			got, err := parseMoney(test.item)
+
+			if test.err {
+				if err == nil {
+					t.Errorf("%s accepted, expected error", test.item)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Error(err.Error())
+
+				return
+			}
+
+			if got != test.exp {
+				t.Errorf("parse money failed:\n+++  got: %f\n--- want: %f",
+					got, test.exp)
+			}
+		})
+	}
+}
+
+func TestFormatIP(t *testing.T) {
+	var tests = []struct {
+		value float64
+		exp   string
+		err   bool
+	}{
+		{value: 3232235777, exp: "192.168.1.1"},
+		{value: 0, exp: "0.0.0.0"},
+		{value: 4294967295, exp: "255.255.255.255"},
+		{value: 4294967296, err: true},
+		{value: -1, err: true},
+		{value: 1.5, err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.exp, func(t *testing.T) {
+			got, err := formatIP(test.value)
+
+			if test.err {
+				if err == nil {
+					t.Errorf("%f accepted, expected error", test.value)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Error(err.Error())
+
+				return
+			}
+
+			if got != test.exp {
+				t.Errorf("format ip failed:\n+++  got: %s\n--- want: %s",
+					got, test.exp)
+			}
+		})
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	var tests = []struct {
+		a, b string
+		exp  int
+	}{
+		{a: "mile", b: "miles", exp: 1},
+		{a: "metres", b: "meters", exp: 2},
+		{a: "", b: "abc", exp: 3},
+		{a: "dump", b: "dump", exp: 0},
+		{a: "kitten", b: "sitting", exp: 3},
+	}
+
+	for _, test := range tests {
+		testname := fmt.Sprintf("levenshtein-%s-%s", test.a, test.b)
+
+		t.Run(testname, func(t *testing.T) {
+			if got := levenshtein(test.a, test.b); got != test.exp {
+				t.Errorf("levenshtein failed:\n+++  got: %d\n--- want: %d", got, test.exp)
+			}
+		})
+	}
+}
@@ -4,12 +4,14 @@ import (
 	"os"
 	"testing"
 
+	"rpn/calc"
+
 	"github.com/rogpeppe/go-internal/testscript"
 )
 
 func TestMain(m *testing.M) {
 	os.Exit(testscript.RunMain(m, map[string]func() int{
-		"testrpn": Main,
+		"testrpn": calc.Main,
 	}))
 }
 
@@ -1,32 +0,0 @@
-/*
-Copyright © 2023 Thomas von Dein
-
-This program is free software: you can redistribute it and/or modify
-it under the terms of the GNU General Public License as published by
-the Free Software Foundation, either version 3 of the License, or
-(at your option) any later version.
-
-This program is distributed in the hope that it will be useful,
-but WITHOUT ANY WARRANTY; without even the implied warranty of
-MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
-GNU General Public License for more details.
-
-You should have received a copy of the GNU General Public License
-along with this program. If not, see <http://www.gnu.org/licenses/>.
-*/
-
-package main
-
-import (
-	"testing"
-)
-
-func TestContains(t *testing.T) {
-	list := []string{"a", "b", "c"}
-
-	t.Run("contains", func(t *testing.T) {
-		if !contains(list, "a") {
-			t.Errorf("a in [a,b,c] not found")
-		}
-	})
-}